@@ -0,0 +1,215 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/liveness"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/urfave/cli/v3"
+)
+
+var checkCmd = &cli.Command{
+	Name:      "check",
+	Usage:     "verify reachability, ssh banner and authentication of hosts",
+	UsageText: "ssm check [query]\nexample: ssm check tag:prod\nexample: ssm check --json --skip-auth",
+	Description: "check runs three layers in parallel for every matching host: " +
+		"TCP reachability, the ssh identification banner and non-interactive " +
+		"authentication (ssh -o BatchMode=yes host true). " +
+		"Layers that can't run, like a HostName with %C, show ?. " +
+		"It exits with status 1 when any host fails.",
+	Action: checkAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "query",
+			UsageText: "filter query, same syntax as the [tag] argument",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print results as JSON",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-auth",
+			Usage: "only check TCP and banner",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "timeout of every layer",
+			Value: 5 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "hosts checked in parallel",
+			Value: 16,
+		},
+	},
+}
+
+// layer is the outcome of a single check layer.
+type layer struct {
+	OK      bool `json:"ok"`
+	Skipped bool `json:"skipped,omitempty"`
+	// Unchecked layers couldn't run, Error tells why.
+	Unchecked bool   `json:"unchecked,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (l layer) String() string {
+	switch {
+	case l.Skipped:
+		return "-"
+	case l.Unchecked:
+		return "?"
+	case l.OK:
+		return "ok"
+	default:
+		return "FAIL"
+	}
+}
+
+// failed reports a layer that ran and failed.
+func (l layer) failed() bool {
+	return !l.OK && !l.Skipped && !l.Unchecked
+}
+
+type checkResult struct {
+	Host    string        `json:"host"`
+	Addr    string        `json:"addr"`
+	Jump    string        `json:"jump,omitempty"`
+	TCP     layer         `json:"tcp"`
+	Banner  layer         `json:"banner"`
+	Auth    layer         `json:"auth"`
+	Version string        `json:"version,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+func (r checkResult) failed() bool {
+	return r.TCP.failed() || r.Banner.failed() || r.Auth.failed()
+}
+
+// firstError returns the error of the first failed layer.
+func (r checkResult) firstError() string {
+	for _, l := range []layer{r.TCP, r.Banner, r.Auth} {
+		if l.Error != "" {
+			return l.Error
+		}
+	}
+	return ""
+}
+
+var checkAction = func(ctx context.Context, cmd *cli.Command) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	hosts := filterHosts(config, cmd.StringArg("query"))
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts match %q", cmd.StringArg("query"))
+	}
+
+	checker := liveness.New(config.GetPath())
+	checker.Timeout = cmd.Duration("timeout")
+	workers := max(1, int(cmd.Int("workers")))
+
+	results := make([]checkResult, len(hosts))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = checkHost(ctx, checker, config, h, cmd.Bool("skip-auth"))
+		}()
+	}
+	wg.Wait()
+
+	var failed int
+	for _, r := range results {
+		if r.failed() {
+			failed++
+		}
+	}
+
+	if cmd.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tADDR\tTCP\tBANNER\tAUTH\tLATENCY\tERROR")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%v\t%s\n",
+				r.Host, r.Addr, r.TCP, r.Banner, r.Auth,
+				r.Latency.Round(time.Millisecond), r.firstError())
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hosts failed", failed, len(results))
+	}
+	return nil
+}
+
+func checkHost(ctx context.Context, checker *liveness.Checker, config *sshconf.Config, h sshconf.Host, skipAuth bool) checkResult {
+	res := checker.Check(ctx, h)
+	out := checkResult{
+		Host:    res.Host,
+		Addr:    res.Addr,
+		Jump:    res.Jump,
+		Version: res.Banner,
+		Latency: res.Latency,
+	}
+	errString := func(err error) string {
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	}
+	switch res.Status {
+	case liveness.Down:
+		out.TCP = layer{Error: errString(res.Err)}
+		out.Banner = layer{Skipped: true}
+		out.Auth = layer{Skipped: true}
+		return out
+	case liveness.Unknown:
+		if !res.Connected {
+			// nothing dialed: ssh may still get through
+			out.TCP = layer{Unchecked: true, Error: errString(res.Err)}
+			out.Banner = layer{Unchecked: true}
+			break
+		}
+		out.TCP = layer{OK: true}
+		out.Banner = layer{Error: errString(res.Err)}
+		out.Auth = layer{Skipped: true}
+		return out
+	default:
+		out.TCP = layer{OK: true}
+		out.Banner = layer{OK: true}
+	}
+	if skipAuth {
+		out.Auth = layer{Skipped: true}
+		return out
+	}
+	if err := liveness.CheckAuth(ctx, config.GetPath(), h.Name, checker.Timeout); err != nil {
+		out.Auth = layer{Error: err.Error()}
+		return out
+	}
+	out.Auth = layer{OK: true}
+	return out
+}
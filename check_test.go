@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/liveness"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"github.com/urfave/cli/v3"
+)
+
+// listen serves banner to every connection.
+func listen(t *testing.T, banner string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(c, banner)
+			c.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// checkConfig returns hosts answering every layer but auth
+// of denied, no banner, nothing listening on down and token
+// left unchecked.
+// The ssh in PATH authenticates every host but denied.
+func checkConfig(t *testing.T) *sshconf.Config {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *denied*) echo 'denied: Permission denied (publickey).' >&2; exit 255;; esac\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	ssh := listen(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	var b strings.Builder
+	for _, h := range []struct{ name, addr string }{
+		{"up", ssh},
+		{"denied", ssh},
+		{"nobanner", listen(t, "HTTP/1.1 400 Bad Request\r\n")},
+		{"down", down},
+	} {
+		ip, port, _ := net.SplitHostPort(h.addr)
+		b.WriteString("Host " + h.name + "\n  HostName " + ip + "\n  Port " + port + "\n")
+	}
+	b.WriteString("Host token\n  HostName %C.example.com\n")
+	return sshconftest.Parse(t, b.String())
+}
+
+func TestCheckHost(t *testing.T) {
+	config := checkConfig(t)
+	checker := liveness.New(config.GetPath())
+	checker.Timeout = 2 * time.Second
+	ctx := context.Background()
+
+	ok, skipped, unchecked := layer{OK: true}, layer{Skipped: true}, layer{Unchecked: true}
+	tests := []struct {
+		host     string
+		skipAuth bool
+		tcp      layer
+		banner   layer
+		auth     layer
+		failed   bool
+	}{
+		{host: "up", tcp: ok, banner: ok, auth: ok},
+		{host: "up", skipAuth: true, tcp: ok, banner: ok, auth: skipped},
+		{host: "denied", tcp: ok, banner: ok, auth: layer{Error: "denied: Permission denied (publickey)."}, failed: true},
+		{host: "nobanner", tcp: ok, auth: skipped, failed: true},
+		{host: "down", banner: skipped, auth: skipped, failed: true},
+		{host: "token", tcp: unchecked, banner: unchecked, auth: ok},
+	}
+	for _, tt := range tests {
+		r := checkHost(ctx, checker, config, config.GetHost(tt.host), tt.skipAuth)
+		// the errors of the network layers depend on the platform
+		tcp, banner := r.TCP, r.Banner
+		if !tt.tcp.OK && !tt.tcp.Skipped {
+			tcp.Error = ""
+		}
+		if !tt.banner.OK && !tt.banner.Skipped {
+			banner.Error = ""
+		}
+		if tcp != tt.tcp || banner != tt.banner || r.Auth != tt.auth {
+			t.Errorf("%s: tcp %+v, banner %+v, auth %+v", tt.host, r.TCP, r.Banner, r.Auth)
+		}
+		if r.failed() != tt.failed {
+			t.Errorf("%s: failed %v", tt.host, r.failed())
+		}
+		if tt.failed && r.firstError() == "" {
+			t.Errorf("%s: no error", tt.host)
+		}
+	}
+}
+
+func TestFailed(t *testing.T) {
+	ok, skipped, fail := layer{OK: true}, layer{Skipped: true}, layer{Error: "refused"}
+	unchecked := layer{Unchecked: true, Error: "unsupported token"}
+	tests := []struct {
+		r    checkResult
+		want bool
+	}{
+		{checkResult{TCP: ok, Banner: ok, Auth: ok}, false},
+		{checkResult{TCP: ok, Banner: ok, Auth: skipped}, false},
+		{checkResult{TCP: ok, Banner: ok, Auth: fail}, true},
+		{checkResult{TCP: ok, Banner: fail, Auth: skipped}, true},
+		{checkResult{TCP: fail, Banner: skipped, Auth: skipped}, true},
+		{checkResult{TCP: unchecked, Banner: unchecked, Auth: ok}, false},
+		{checkResult{TCP: unchecked, Banner: unchecked, Auth: fail}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.r.failed(); got != tt.want {
+			t.Errorf("%+v: failed %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
+
+// runCheck runs `ssm --config path check args...` and returns its output.
+func runCheck(t *testing.T, config *sshconf.Config, args ...string) (string, error) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	app := &cli.Command{
+		Name: "ssm",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "config"},
+			&cli.BoolFlag{Name: "order"},
+		},
+		Commands: []*cli.Command{checkCmd},
+	}
+	runErr := app.Run(context.Background(), append([]string{"ssm", "--config", config.GetPath(), "check"}, args...))
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), runErr
+}
+
+func TestCheckOutput(t *testing.T) {
+	config := checkConfig(t)
+
+	out, err := runCheck(t, config, "--json", "--timeout", "2s")
+	// the exit status is 1 when any host fails
+	if err == nil || err.Error() != "3 of 5 hosts failed" {
+		t.Errorf("error: %v", err)
+	}
+	var results []checkResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(results) != 5 || results[0].Host != "up" || results[0].failed() || results[0].Version != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("results: %+v", results)
+	}
+
+	out, err = runCheck(t, config, "--skip-auth", "--timeout", "2s", "host:up")
+	if err != nil {
+		t.Errorf("error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "HOST") || strings.Join(strings.Fields(lines[1])[2:5], " ") != "ok ok -" {
+		t.Errorf("table:\n%s", out)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/facts"
+	"github.com/lfaoro/ssm/pkg/prefs"
+	"github.com/urfave/cli/v3"
+)
+
+// setting is a preference, flags take precedence over
+// the environment variable and then over config.toml.
+type setting struct {
+	flag string
+	env  string
+	key  string
+	// value is the default of settings without a flag.
+	value func(p *prefs.Prefs) string
+}
+
+var settings = []setting{
+	{flag: "connector", env: "SSM_CONNECTOR", key: "connector"},
+	{flag: "sort", env: "SSM_SORT", key: "sort"},
+	{flag: "theme", env: "SSM_THEME", key: "theme"},
+	{flag: "config", env: "SSM_SSH_CONFIG_PATH", key: "ssh-config"},
+	{flag: "show", env: "SSM_SHOW", key: "show"},
+	{flag: "exit", env: "SSM_EXIT", key: "exit"},
+	{flag: "order", env: "SSM_ORDER", key: "order"},
+	{flag: "ping", env: "SSM_PING", key: "ping"},
+	{flag: "ping-interval", env: "SSM_PING_INTERVAL", key: "ping-interval"},
+	{flag: "editor", env: "SSM_EDITOR", key: "editor"},
+	{flag: "debug", env: "SSM_DEBUG", key: "debug"},
+	{flag: "debug-log", env: "SSM_DEBUG_LOG", key: "debug-log"},
+	{flag: "launch", env: "SSM_LAUNCH", key: "launch"},
+	{key: "terminal", value: func(p *prefs.Prefs) string { return strings.Join(p.Terminal, " ") }},
+	{key: "synchronize-panes", value: func(p *prefs.Prefs) string { return fmt.Sprint(p.SyncPanes) }},
+	{key: "record.hosts", value: func(p *prefs.Prefs) string { return strings.Join(p.Record.Hosts, " ") }},
+	{key: "record.tags", value: func(p *prefs.Prefs) string { return strings.Join(p.Record.Tags, " ") }},
+	{key: "hooks.pre", value: func(p *prefs.Prefs) string { return strings.Join(p.Hooks.Pre, "; ") }},
+	{key: "hooks.post", value: func(p *prefs.Prefs) string { return strings.Join(p.Hooks.Post, "; ") }},
+	{key: "facts.ttl", value: func(p *prefs.Prefs) string {
+		if p.Facts.TTL <= 0 {
+			return facts.DefaultTTL.String()
+		}
+		return p.Facts.TTL.String()
+	}},
+	{key: "facts.background", value: func(p *prefs.Prefs) string { return fmt.Sprint(p.Facts.Background) }},
+	{key: "health.interval", value: func(p *prefs.Prefs) string { return p.Health.WithDefaults().Interval.String() }},
+	{key: "health.timeout", value: func(p *prefs.Prefs) string { return p.Health.WithDefaults().Timeout.String() }},
+	{key: "health.probes", value: func(p *prefs.Prefs) string {
+		var names []string
+		for _, probe := range p.Health.WithDefaults().Probes {
+			names = append(names, probe.Name)
+		}
+		return strings.Join(names, " ")
+	}},
+	{key: "filter.start", value: func(p *prefs.Prefs) string { return fmt.Sprint(p.Filter.Start) }},
+	{key: "filter.query", value: func(p *prefs.Prefs) string { return p.Filter.Query }},
+}
+
+// userPrefs loads config.toml once, flags read it while parsing.
+var userPrefs = sync.OnceValues(prefs.Load)
+
+// connectors returns the built-in connectors merged with config.toml.
+func connectors() ([]connector.Connector, error) {
+	p, err := userPrefs()
+	if err != nil {
+		return nil, err
+	}
+	list, err := connector.Merge(connector.Builtin(), p.Connectors)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", p.File(), err)
+	}
+	return list, nil
+}
+
+// prefSource looks a key up in config.toml.
+type prefSource string
+
+func (s prefSource) Lookup() (string, bool) {
+	p, err := userPrefs()
+	if err != nil {
+		return "", false
+	}
+	return p.Lookup(string(s))
+}
+
+func (s prefSource) String() string {
+	return fmt.Sprintf("config.toml key %q", string(s))
+}
+
+func (s prefSource) GoString() string {
+	return fmt.Sprintf("prefSource(%q)", string(s))
+}
+
+// sources returns the environment variable and config.toml key of flag.
+func sources(flag string) cli.ValueSourceChain {
+	for _, s := range settings {
+		if s.flag == flag {
+			return cli.NewValueSourceChain(cli.EnvVar(s.env), prefSource(s.key))
+		}
+	}
+	panic("no setting for flag " + flag)
+}
+
+var configCmd = &cli.Command{
+	Name:      "config",
+	Usage:     "print the effective settings and where they come from",
+	UsageText: "ssm config\nexample: ssm --sort alpha config\nexample: ssm config --json",
+	Description: "settings come from flags, then SSM_* environment variables, " +
+		"then $XDG_CONFIG_HOME/ssm/config.toml, then the defaults.",
+	Action: configAction,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print settings as JSON",
+		},
+	},
+}
+
+type effective struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+var configAction = func(_ context.Context, cmd *cli.Command) error {
+	p, err := userPrefs()
+	if err != nil {
+		return err
+	}
+	set := commandLineFlags(cmd.Root(), os.Args[1:])
+	var out []effective
+	for _, s := range settings {
+		e := effective{Key: s.key, Source: "default"}
+		if s.flag != "" {
+			e.Value = fmt.Sprint(cmd.Value(s.flag))
+		} else {
+			e.Value = s.value(p)
+		}
+		_, inEnv := os.LookupEnv(s.env)
+		_, inFile := p.Lookup(s.key)
+		switch {
+		case set[s.flag]:
+			e.Source = "flag --" + s.flag
+		case s.env != "" && inEnv:
+			e.Source = "env " + s.env
+		case inFile:
+			e.Source = p.File()
+		}
+		out = append(out, e)
+	}
+
+	if cmd.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, e := range out {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Key, e.Value, e.Source)
+	}
+	return w.Flush()
+}
+
+// commandLineFlags returns the root flags present in args,
+// by name, short options may be combined: -seo.
+func commandLineFlags(root *cli.Command, args []string) map[string]bool {
+	name := func(alias string) string {
+		for _, f := range root.Flags {
+			if names := f.Names(); slices.Contains(names, alias) {
+				return names[0]
+			}
+		}
+		return ""
+	}
+	set := map[string]bool{}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			n, _, _ := strings.Cut(arg[2:], "=")
+			set[name(n)] = true
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			n, _, _ := strings.Cut(arg[1:], "=")
+			if full := name(n); full != "" {
+				set[full] = true
+				continue
+			}
+			for _, r := range n {
+				set[name(string(r))] = true
+			}
+		}
+	}
+	delete(set, "")
+	return set
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/facts"
+	"github.com/urfave/cli/v3"
+)
+
+var factsCmd = &cli.Command{
+	Name:  "facts",
+	Usage: "print the cached facts of hosts as JSON",
+	UsageText: "ssm facts [query]\n" +
+		"example: ssm facts 'tag:prod' --collect\n" +
+		"example: ssm facts 'os:debian'",
+	Description: "facts prints the OS release, kernel, architecture, CPUs, memory and boot time " +
+		"of the matching hosts, cached in $XDG_CACHE_HOME/ssm/facts.json. --collect gathers " +
+		"the facts missing or older than the ttl of config.toml first, running plain shell " +
+		"commands over `ssh -T` in BatchMode.",
+	Action: factsAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "query",
+			UsageText: "filter query, same syntax as the [tag] argument",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "collect",
+			Usage: "collect missing and stale facts first",
+		},
+		&cli.BoolFlag{
+			Name:  "refresh",
+			Usage: "collect the facts of every matching host first",
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "hosts collected in parallel",
+			Value: facts.DefaultWorkers,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "timeout of the collection on every host",
+			Value: facts.DefaultTimeout,
+		},
+	},
+}
+
+// openFacts opens the facts cache with the ttl of config.toml.
+func openFacts() (*facts.Cache, error) {
+	pf, err := userPrefs()
+	if err != nil {
+		return facts.New("", 0), err
+	}
+	return facts.Open(pf.Facts.TTL)
+}
+
+var factsAction = func(ctx context.Context, cmd *cli.Command) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	cache, err := openFacts()
+	if err != nil {
+		return err
+	}
+	hosts := filterHosts(config, cmd.StringArg("query"))
+
+	var collectErr error
+	if cmd.Bool("collect") || cmd.Bool("refresh") {
+		var names []string
+		now := time.Now()
+		for _, h := range hosts {
+			if cmd.Bool("refresh") || cache.Stale(h.Name, now) {
+				names = append(names, h.Name)
+			}
+		}
+		_, collectErr = cache.CollectAll(ctx, config.GetPath(), names,
+			int(cmd.Int("workers")), cmd.Duration("timeout"))
+	}
+
+	out := []facts.Facts{}
+	for _, h := range hosts {
+		if f, ok := cache.Get(h.Name); ok {
+			out = append(out, f)
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	if collectErr != nil {
+		return fmt.Errorf("collecting facts: %w", collectErr)
+	}
+	return nil
+}
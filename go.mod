@@ -3,13 +3,19 @@ module github.com/lfaoro/ssm
 go 1.24
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/aymanbagabas/go-udiff v0.2.0
 	github.com/charmbracelet/bubbles/v2 v2.0.0-beta.1
 	github.com/charmbracelet/bubbletea/v2 v2.0.0-beta1
 	github.com/charmbracelet/lipgloss/v2 v2.0.0-beta1
+	github.com/creack/pty v1.1.24
 	github.com/google/go-github v17.0.0+incompatible
+	github.com/muesli/cancelreader v0.2.2
+	github.com/sahilm/fuzzy v0.1.1
 	github.com/thalesfsp/go-common-types v0.2.4
 	github.com/urfave/cli/v3 v3.3.2
-	golang.org/x/term v0.31.0
+	golang.org/x/crypto v0.38.0
+	golang.org/x/term v0.32.0
 )
 
 require (
@@ -24,9 +30,7 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
-	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/sahilm/fuzzy v0.1.1 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/sync v0.14.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
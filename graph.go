@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lfaoro/ssm/pkg/jumpgraph"
+	"github.com/urfave/cli/v3"
+)
+
+var graphCmd = &cli.Command{
+	Name:  "graph",
+	Usage: "export the ProxyJump topology as Graphviz DOT or Mermaid",
+	UsageText: "ssm graph [query] [--format dot|mermaid]\n" +
+		"example: ssm graph | dot -Tsvg > hosts.svg\n" +
+		"example: ssm graph 'tag:prod' --format mermaid",
+	Description: "graph draws an edge from every jump host to the host it leads to, " +
+		"from ProxyJump and `ProxyCommand ssh -W`. Jump hosts missing from the config " +
+		"are dashed, hosts on a jump cycle are red. A query keeps the matching hosts " +
+		"and their routes. Broken routes are reported on stderr, it exits with status 1 on cycles.",
+	Action: graphAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "query",
+			UsageText: "filter query, same syntax as the [tag] argument",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: dot or mermaid",
+			Value: "dot",
+			Validator: func(s string) error {
+				if s != "dot" && s != "mermaid" {
+					return fmt.Errorf("unknown format %q, want dot or mermaid", s)
+				}
+				return nil
+			},
+		},
+	},
+}
+
+var graphAction = func(_ context.Context, cmd *cli.Command) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	g := jumpgraph.New(config.Hosts)
+	if q := cmd.StringArg("query"); q != "" {
+		var names []string
+		for _, h := range filterHosts(config, q) {
+			names = append(names, h.Name)
+		}
+		g = g.Only(names)
+	}
+	switch cmd.String("format") {
+	case "mermaid":
+		fmt.Print(g.Mermaid())
+	default:
+		fmt.Print(g.DOT())
+	}
+
+	// jump hosts missing from the config may still resolve
+	// through DNS, only cycles are errors
+	var cycles int
+	for _, h := range config.Hosts {
+		c := g.Chain(h.Name)
+		if !g.Has(h.Name) || (!c.Cycle && len(c.Missing) == 0) {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %s", h.Name, c)
+		if c.Cycle {
+			cycles++
+			msg += ", jump cycle"
+		}
+		if len(c.Missing) > 0 {
+			msg += ", not in config: " + strings.Join(c.Missing, ", ")
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	if cycles > 0 {
+		return fmt.Errorf("%d hosts are on a jump cycle", cycles)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/urfave/cli/v3"
+)
+
+var historyCmd = &cli.Command{
+	Name:      "history",
+	Usage:     "print recorded connection sessions",
+	UsageText: "ssm history [host]\nexample: ssm history --limit 5\nexample: ssm history web --json",
+	Action:    historyAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "host",
+			UsageText: "only sessions to hosts containing this text",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print sessions as JSON",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of sessions, 0 prints all",
+			Value: 20,
+		},
+	},
+}
+
+var historyAction = func(_ context.Context, cmd *cli.Command) error {
+	log, err := history.Open()
+	if err != nil {
+		return err
+	}
+	entries, err := log.Entries()
+	if err != nil {
+		return err
+	}
+
+	filter := cmd.StringArg("host")
+	limit := cmd.Int("limit")
+	var out []history.Entry
+	for _, e := range entries {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		if filter != "" && !strings.Contains(e.Host, filter) {
+			continue
+		}
+		out = append(out, e)
+	}
+
+	if cmd.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if out == nil {
+			out = []history.Entry{}
+		}
+		return enc.Encode(out)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tHOST\tCONNECTOR\tDURATION\tEXIT\tLAST STDERR")
+	for _, e := range out {
+		var last string
+		if len(e.Stderr) > 0 {
+			last = e.Stderr[len(e.Stderr)-1]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			e.Start.Format("2006-01-02 15:04:05"), e.Host, e.Connector, e.Duration, e.ExitCode, last)
+	}
+	return w.Flush()
+}
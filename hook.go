@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/urfave/cli/v3"
+)
+
+// hookCmd wraps connections running outside of the TUI:
+// tmux windows and panes, terminals and --exit, to run
+// their post-connect hooks and record them in the history.
+var hookCmd = &cli.Command{
+	Name:      "hook",
+	Usage:     "run a command then its post-connect hooks and record it",
+	UsageText: "ssm hook --spec '{\"post\": [...], \"env\": [...]}' -- command [args]",
+	Hidden:    true,
+	Action:    hookAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "spec",
+			Usage:    "hooks run once the command ends and their variables, as JSON",
+			Required: true,
+		},
+	},
+}
+
+var hookAction = func(_ context.Context, cmd *cli.Command) error {
+	argv := cmd.Args().Slice()
+	if len(argv) == 0 {
+		return fmt.Errorf("hook: missing command")
+	}
+	var spec hook.Deferred
+	if err := json.Unmarshal([]byte(cmd.String("spec")), &spec); err != nil {
+		return fmt.Errorf("hook: --spec: %w", err)
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	// ctrl+c belongs to the connection, not to its wrapper
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt, syscall.SIGQUIT)
+
+	c := exec.Command(path, argv[1:]...)
+	tail := history.NewTail(history.TailLines)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, io.MultiWriter(os.Stderr, tail)
+	start := time.Now()
+	runErr := c.Run()
+	code := history.ExitCode(runErr)
+	duration := time.Since(start)
+
+	if spec.History != "" {
+		entry := history.Entry{
+			Host:      spec.Host,
+			Connector: spec.Connector,
+			Start:     start,
+			Duration:  duration.Round(time.Second),
+			ExitCode:  code,
+			Stderr:    tail.Lines(),
+		}
+		if runErr != nil {
+			entry.Error = runErr.Error()
+		}
+		if err := history.OpenPath(spec.History).Append(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		}
+	}
+
+	var timeout time.Duration
+	if pf, err := userPrefs(); err == nil {
+		timeout = pf.Hooks.Timeout
+	}
+	env := append(spec.Env, hook.ResultEnv(code, duration)...)
+	out, err := hook.Run(context.Background(), spec.Post, env, timeout)
+	os.Stderr.Write(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "post-connect %v\n", err)
+	}
+
+	var exit *exec.ExitError
+	if errors.As(runErr, &exit) {
+		// keep the exit status of the connection
+		os.Exit(exit.ExitCode())
+	}
+	return runErr
+}
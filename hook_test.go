@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/hook"
+)
+
+func TestHookHistory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	spec, err := json.Marshal(hook.Deferred{
+		History:   path,
+		Host:      "web",
+		Connector: "ssh",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"hook", "--spec", string(spec), "--", "sh", "-c", "echo refused >&2"}
+	if err := hookCmd.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := history.OpenPath(path).Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries: %+v", entries)
+	}
+	e := entries[0]
+	if e.Host != "web" || e.Connector != "ssh" || e.ExitCode != 0 || len(e.Stderr) != 1 || e.Stderr[0] != "refused" {
+		t.Errorf("entry: %+v", e)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/tui"
+	"github.com/urfave/cli/v3"
+)
+
+var keysCmd = &cli.Command{
+	Name:      "keys",
+	Usage:     "print the key bindings in effect",
+	UsageText: "ssm keys",
+	Description: "keys lists every action with its bindings, then the actions of the screens " +
+		"as screen.action, defaults merged with $XDG_CONFIG_HOME/ssm/keymap.toml. " +
+		"It exits with status 1 when bindings of the host list or of a screen conflict.",
+	Action: keysAction,
+}
+
+var keysAction = func(_ context.Context, _ *cli.Command) error {
+	if _, err := loadThemes(); err != nil {
+		return err
+	}
+	user, err := keymap.Load()
+	if err != nil {
+		return err
+	}
+	keys, err := tui.DefaultKeymap().Merge(user)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKEYS\tDESCRIPTION")
+	row := func(id, title string) {
+		bound := strings.Join(keys[id], " ")
+		if bound == "" {
+			bound = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", id, bound, title)
+	}
+	for _, a := range tui.Actions() {
+		row(a.ID, a.Title)
+	}
+	for _, k := range tui.ScreenKeys() {
+		row(k.ID, k.Title)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return keys.Validate()
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lfaoro/ssm/pkg/query"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/urfave/cli/v3"
+)
+
+var listCmd = &cli.Command{
+	Name:      "list",
+	Aliases:   []string{"ls"},
+	Usage:     "print hosts matching a filter query",
+	UsageText: "ssm list [query]\nexample: ssm list 'tag:prod -user:root'\nexample: ssm list web,db",
+	Action:    listAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "query",
+			UsageText: "filter query, same syntax as the [tag] argument",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print hosts as JSON",
+		},
+	},
+}
+
+type listEntry struct {
+	Name     string   `json:"name"`
+	HostName string   `json:"hostname"`
+	User     string   `json:"user,omitempty"`
+	Port     string   `json:"port"`
+	Jump     string   `json:"jump,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+var listAction = func(_ context.Context, cmd *cli.Command) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	hosts := filterHosts(config, cmd.StringArg("query"))
+
+	entries := make([]listEntry, 0, len(hosts))
+	for _, h := range hosts {
+		entries = append(entries, listEntry{
+			Name:     h.Name,
+			HostName: h.HostName(),
+			User:     h.User(),
+			Port:     h.Port(),
+			Jump:     h.ProxyJump(),
+			Tags:     h.Tags(),
+		})
+	}
+	if cmd.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tHOSTNAME\tUSER\tPORT\tJUMP\tTAGS")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Name, e.HostName, e.User, e.Port, e.Jump, strings.Join(e.Tags, ","))
+	}
+	return w.Flush()
+}
+
+// filterHosts returns the config hosts matching arg ranked by score,
+// an empty arg returns every host in config order. Cached facts
+// are matched too: `os:debian`.
+func filterHosts(config *sshconf.Config, arg string) []sshconf.Host {
+	q := query.Parse(query.FromTagArg(arg))
+	cache, _ := openFacts()
+	records := make([]query.Record, 0, len(config.Hosts))
+	for _, h := range config.Hosts {
+		r := query.FromHost(h)
+		if f, ok := cache.Get(h.Name); ok {
+			maps.Copy(r.Fields, f.Fields())
+		}
+		records = append(records, r)
+	}
+	var hosts []sshconf.Host
+	for _, r := range q.Filter(records) {
+		hosts = append(hosts, config.Hosts[r.Index])
+	}
+	return hosts
+}
@@ -9,12 +9,27 @@ import (
 	"net/mail"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/google/go-github/github"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/facts"
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/launch"
+	"github.com/lfaoro/ssm/pkg/query"
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/lfaoro/ssm/pkg/secret"
+	"github.com/lfaoro/ssm/pkg/snapshot"
+	"github.com/lfaoro/ssm/pkg/snippet"
 	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/state"
+	"github.com/lfaoro/ssm/pkg/theme"
 	"github.com/lfaoro/ssm/pkg/tui"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/term"
@@ -30,6 +45,10 @@ var (
 )
 
 func main() {
+	if secret.IsAskpass(os.Args[1:]) {
+		// ssh asks the password of a #secret: host
+		os.Exit(secret.Askpass(os.Args[1:], os.Stdout))
+	}
 	appcmd := &cli.Command{
 		Name: "ssm",
 		Authors: []any{
@@ -57,15 +76,16 @@ func main() {
 		},
 
 		Before: func(c context.Context, cmd *cli.Command) (context.Context, error) {
-			_ = cmd
-			return c, nil
+			// flags read config.toml silently, report its errors here
+			_, err := userPrefs()
+			return c, err
 		},
 
 		Action: mainCmd,
 		Arguments: []cli.Argument{
 			&cli.StringArg{
 				Name:        "tag",
-				UsageText:   "comma separated arguments for filtering #tag: hosts",
+				UsageText:   "comma separated #tag: values or a filter query e.g. 'user:root -tag:legacy'",
 				Destination: &filterTag,
 			},
 		},
@@ -76,56 +96,124 @@ func main() {
 				Aliases: []string{"s"},
 				Usage:   "always show config params",
 				Value:   false,
-				Sources: cli.EnvVars("SSM_SHOW"),
+				Sources: sources("show"),
 			},
 			&cli.BoolFlag{
 				Name:    "exit",
 				Aliases: []string{"e"},
 				Usage:   "exit after connection",
 				Value:   false,
-				Sources: cli.EnvVars("SSM_EXIT"),
+				Sources: sources("exit"),
 			},
 			&cli.BoolFlag{
 				Name:    "order",
 				Aliases: []string{"o"},
 				Usage:   "show hosts with a tag first",
 				Value:   false,
-				Sources: cli.EnvVars("SSM_ORDER"),
+				Sources: sources("order"),
+			},
+			&cli.StringFlag{
+				Name:        "sort",
+				Usage:       "host sort order",
+				DefaultText: "config|alpha|favorites|recent|frecency",
+				Value:       "config",
+				Sources:     sources("sort"),
+				Validator: func(s string) error {
+					_, err := state.ParseSortMode(s)
+					return err
+				},
 			},
 			&cli.StringFlag{
 				Name:      "config",
 				TakesFile: true,
 				Aliases:   []string{"c"},
 				Usage:     "custom ssh config file path",
-				Sources:   cli.EnvVars("SSM_SSH_CONFIG_PATH"),
+				Sources:   sources("config"),
+			},
+			&cli.StringFlag{
+				Name:        "connector",
+				Usage:       "default connector, hosts may pin theirs with #connector:",
+				DefaultText: "ssh|mosh|autossh|et|kitty|sshpass",
+				Value:       "ssh",
+				Sources:     sources("connector"),
+				Validator: func(s string) error {
+					list, err := connectors()
+					if err != nil {
+						return err
+					}
+					if _, ok := connector.Find(list, s); !ok {
+						return fmt.Errorf("unknown connector %q, available: %s",
+							s, strings.Join(connector.Names(list), ", "))
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:        "launch",
+				Usage:       "where connections open, space selects hosts for a tiled tmux window",
+				DefaultText: "replace|tmux-window|tmux-pane|terminal",
+				Value:       string(launch.Replace),
+				Sources:     sources("launch"),
+				Validator: func(s string) error {
+					_, err := launch.Parse(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:    "editor",
+				Usage:   "editor of the ssh config, defaults to $EDITOR",
+				Sources: sources("editor"),
 			},
 			&cli.StringFlag{
 				Name:        "theme",
 				TakesFile:   false,
 				Aliases:     []string{"t"},
-				Usage:       "define a color theme",
-				DefaultText: "sky|matrix",
+				Usage:       "color theme, 'list' prints the available themes",
+				DefaultText: "matrix",
 				Value:       "matrix",
-				Sources:     cli.EnvVars("SSM_THEME"),
+				Sources:     sources("theme"),
 			},
 			&cli.BoolFlag{
-				// TODO: not implemented
 				Name:    "ping",
 				Aliases: []string{"p"},
 				Usage:   "ping all hosts and show liveness",
 				Value:   false,
-				Hidden:  true,
+				Sources: sources("ping"),
+			},
+			&cli.DurationFlag{
+				Name:    "ping-interval",
+				Usage:   "how often --ping refreshes liveness",
+				Value:   30 * time.Second,
+				Sources: sources("ping-interval"),
 			},
 			&cli.BoolFlag{
 				Name:    "debug",
 				Aliases: []string{"d"},
 				Usage:   "enable debug mode with verbose logging",
 				Value:   false,
-				Sources: cli.EnvVars("SSM_DEBUG"),
+				Sources: sources("debug"),
+			},
+			&cli.StringFlag{
+				Name:      "debug-log",
+				TakesFile: true,
+				Usage:     "append log and error lines to this file",
+				Sources:   sources("debug-log"),
 			},
 		},
 
 		Commands: []*cli.Command{
+			listCmd,
+			historyCmd,
+			factsCmd,
+			checkCmd,
+			runCmd,
+			keysCmd,
+			configCmd,
+			replayCmd,
+			graphCmd,
+			secretCmd,
+			recordCmd,
+			hookCmd,
 			generateCmd,
 			testCmd,
 		},
@@ -138,6 +226,48 @@ func main() {
 	}
 }
 
+// loadThemes makes the theme files available to the interface.
+func loadThemes() ([]theme.Theme, error) {
+	themes, err := theme.Load()
+	if err != nil {
+		return nil, err
+	}
+	tui.RegisterThemes(themes...)
+	return themes, nil
+}
+
+// printThemes lists the themes and where they come from.
+func printThemes(themes []theme.Theme) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "THEME\tSOURCE")
+	for _, t := range themes {
+		source := t.Path
+		if source == "" {
+			source = "built-in"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", t.Name, source)
+	}
+	return w.Flush()
+}
+
+// loadKeymap merges keymap.toml into the default bindings,
+// a keymap with conflicts doesn't start.
+func loadKeymap() (keymap.Keymap, error) {
+	user, err := keymap.Load()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := tui.DefaultKeymap().Merge(user)
+	if err == nil {
+		err = keys.Validate()
+	}
+	if err != nil {
+		path, _ := keymap.Path()
+		return nil, fmt.Errorf("%w\nfix %s, actions: ssm keys", err, path)
+	}
+	return keys, nil
+}
+
 func mainCmd(_ context.Context, cmd *cli.Command) error {
 	debug := cmd.Bool("debug")
 	if debug {
@@ -146,29 +276,93 @@ func mainCmd(_ context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	themes, err := loadThemes()
+	if err != nil {
+		return err
+	}
+	switch name := cmd.String("theme"); {
+	case name == "list":
+		return printThemes(themes)
+	case name != "":
+		if _, ok := theme.Find(themes, name); !ok {
+			return fmt.Errorf("unknown theme %q, available: %s",
+				name, strings.Join(theme.Names(themes), ", "))
+		}
+	}
+
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
 		return fmt.Errorf("not an interactive terminal :(")
 	}
 
-	var err error
-	var config = sshconf.New()
-	if cmd.Bool("order") {
-		config.SetOrder(sshconf.TagOrder)
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
 	}
-	configFlag := cmd.String("config")
-	if configFlag != "" {
-		err = config.ParsePath(configFlag)
-		if err != nil {
-			return err
-		}
-	} else {
-		err = config.Parse()
+
+	st, err := state.Load()
+	if err != nil {
+		fmt.Println(err)
+	}
+	hist, err := history.Open()
+	if err != nil {
+		fmt.Println(err)
+	}
+	snippets, err := snippet.Load()
+	if err != nil {
+		fmt.Println(err)
+	}
+	keys, err := loadKeymap()
+	if err != nil {
+		return err
+	}
+	conns, err := connectors()
+	if err != nil {
+		return err
+	}
+	pf, _ := userPrefs()
+	recordDir, err := recording.Dir()
+	if err != nil {
+		fmt.Println(err)
+	}
+	var secrets *secret.File
+	if path, err := secret.FilePath(); err == nil {
+		// the passphrase is asked in the TUI when needed
+		secrets = &secret.File{Path: path}
+	}
+	opts := []tui.ModelOption{
+		tui.WithConnectors(conns),
+		tui.WithState(st),
+		tui.WithHistory(hist),
+		tui.WithPingInterval(cmd.Duration("ping-interval")),
+		tui.WithSnippets(snippets),
+		tui.WithKeymap(keys),
+		tui.WithConnector(tui.SysCmd(cmd.String("connector"))),
+		tui.WithEditor(cmd.String("editor")),
+		tui.WithRecording(recordDir, pf.Record),
+		tui.WithSecrets(secrets),
+		tui.WithHooks(pf.Hooks),
+		tui.WithHealth(pf.Health),
+		tui.WithLauncher(launch.Launcher{
+			Target:   launch.Target(cmd.String("launch")),
+			Terminal: pf.Terminal,
+			Sync:     pf.SyncPanes,
+		}),
+	}
+	if cache, err := facts.Open(pf.Facts.TTL); err == nil {
+		opts = append(opts, tui.WithFacts(cache))
+	}
+	if dir, err := snapshot.Dir(); err == nil {
+		opts = append(opts, tui.WithSnapshots(snapshot.Store{Dir: dir}))
+	}
+	if path := cmd.String("debug-log"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
 		if err != nil {
 			return err
 		}
+		defer f.Close()
+		opts = append(opts, tui.WithDebugLog(f))
 	}
-
-	m := tui.NewModel(config, debug)
+	m := tui.NewModel(config, debug, opts...)
 	p := tea.NewProgram(
 		m,
 		tea.WithOutput(os.Stderr))
@@ -187,13 +381,17 @@ func mainCmd(_ context.Context, cmd *cli.Command) error {
 			fmt.Println("you found bug#1: open an issue")
 			os.Exit(1)
 		}
-		if m.ExitOnCmd && m.ExitHost != "" {
-			sshPath, err := exec.LookPath(m.Cmd.String())
+		if m.ExitOnCmd && len(m.ExitArgv) > 0 {
+			path, err := exec.LookPath(m.ExitArgv[0])
 			if err != nil {
-				fmt.Printf("can't find `%s` cmd in your path: %v\n", m.Cmd, err)
+				fmt.Printf("can't find `%s` cmd in your path: %v\n", m.ExitArgv[0], err)
 				os.Exit(1)
 			}
-			err = syscall.Exec(sshPath, []string{"ssh", "-F", config.GetPath(), m.ExitHost}, os.Environ())
+			env := os.Environ()
+			if m.ExitEnv != nil {
+				env = m.ExitEnv
+			}
+			err = syscall.Exec(path, m.ExitArgv, env)
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
@@ -203,24 +401,36 @@ func mainCmd(_ context.Context, cmd *cli.Command) error {
 
 	if filterTag != "" {
 		p.Send(tui.FilterTagMsg{
-			Arg: fmt.Sprintf("#%s", filterTag),
+			Arg: query.FromTagArg(filterTag),
+		})
+	} else if pf.Filter.Query != "" {
+		p.Send(tui.FilterTagMsg{
+			Arg: pf.Filter.Query,
 		})
 	}
+	if pf.Filter.Start {
+		p.Send(tui.StartFilterMsg{})
+	}
 	if cmd.Bool("exit") {
 		p.Send(tui.ExitOnConnMsg{})
 	}
 	if cmd.Bool("show") {
 		p.Send(tui.ShowConfigMsg{})
 	}
-	theme := cmd.String("theme")
-	if theme != "" {
+	if name := cmd.String("theme"); name != "" {
 		p.Send(tui.SetThemeMsg{
-			Theme: theme,
+			Theme: name,
 		})
 	}
+	if mode, ok := startSort(cmd, st, os.Args[1:]); ok {
+		p.Send(tui.SetSortMsg{Mode: mode})
+	}
 	if cmd.Bool("ping") {
 		p.Send(tui.LivenessCheckMsg{})
 	}
+	if pf.Facts.Background {
+		p.Send(tui.CollectFactsMsg{})
+	}
 
 	// inform user when new version is available
 	go func() {
@@ -241,6 +451,31 @@ func mainCmd(_ context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// loadConfig parses the ssh config selected by the `--config` flag
+// or the default one.
+// startSort returns the sort the list starts with, ok is false to
+// keep the one saved in st. --sort in args wins over the saved sort,
+// which wins over SSM_SORT and config.toml.
+func startSort(cmd *cli.Command, st *state.State, args []string) (state.SortMode, bool) {
+	if _, saved := st.LastSort(); saved && !commandLineFlags(cmd.Root(), args)["sort"] {
+		return 0, false
+	}
+	mode, err := state.ParseSortMode(cmd.String("sort"))
+	return mode, err == nil
+}
+
+func loadConfig(cmd *cli.Command) (*sshconf.Config, error) {
+	var config = sshconf.New()
+	if cmd.Bool("order") {
+		config.SetOrder(sshconf.TagOrder)
+	}
+	configFlag := cmd.String("config")
+	if configFlag != "" {
+		return config, config.ParsePath(configFlag)
+	}
+	return config, config.Parse()
+}
+
 var testCmd = &cli.Command{
 	Name:   "test",
 	Action: testAction,
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/state"
+	"github.com/urfave/cli/v3"
+)
+
+func TestStartSort(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	saved := state.New("")
+	saved.SetSort(state.SortFrecency)
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		st   *state.State
+		want state.SortMode
+		ok   bool
+	}{
+		{name: "default", st: state.New(""), want: state.SortConfig, ok: true},
+		{name: "saved", st: saved},
+		{name: "env below saved", env: "alpha", st: saved},
+		{name: "env", env: "alpha", st: state.New(""), want: state.SortAlpha, ok: true},
+		{name: "flag", args: []string{"--sort", "recent"}, env: "alpha", st: saved, want: state.SortRecent, ok: true},
+		{name: "flag=", args: []string{"--sort=recent"}, st: saved, want: state.SortRecent, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("SSM_SORT", tt.env)
+			}
+			var mode state.SortMode
+			var ok bool
+			app := &cli.Command{
+				Name:  "ssm",
+				Flags: []cli.Flag{&cli.StringFlag{Name: "sort", Value: "config", Sources: sources("sort")}},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					mode, ok = startSort(cmd, tt.st, tt.args)
+					return nil
+				},
+			}
+			if err := app.Run(context.Background(), append([]string{"ssm"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if mode != tt.want || ok != tt.ok {
+				t.Errorf("got %s %v, want %s %v", mode, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
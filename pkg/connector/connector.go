@@ -0,0 +1,167 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package connector builds the commands connecting to a host.
+// A connector is an argv of Go templates over the host fields,
+// config.toml adds connectors or replaces the built-in ones:
+//
+//	[[connectors]]
+//	name = "ssh-tmux"
+//	command = ["ssh", "-t", "-F", "{{.Config}}", "{{.Name}}", "tmux new -A -s ssm"]
+//
+// A host picks its own connector with a `#connector: mosh` comment.
+package connector
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+// Default is the connector used when none is chosen.
+const Default = "ssh"
+
+// Connector builds the argv connecting to a host.
+type Connector struct {
+	Name string `toml:"name"`
+	// Command is the argv, every element is a template over Data,
+	// elements rendering empty are dropped.
+	Command []string `toml:"command"`
+}
+
+// Data is what connector templates are rendered with.
+type Data struct {
+	Name     string
+	HostName string
+	User     string
+	Port     string
+	// Config is the ssh config the host was read from.
+	Config string
+	Meta   map[string]string
+}
+
+// DataOf returns the template data of h read from config.
+func DataOf(h sshconf.Host, config string) Data {
+	return Data{
+		Name:     h.Name,
+		HostName: h.HostName(),
+		User:     h.User(),
+		Port:     h.Port(),
+		Config:   config,
+		Meta:     h.Meta(),
+	}
+}
+
+// Builtin returns the connectors shipped with ssm.
+func Builtin() []Connector {
+	return []Connector{
+		{Name: "ssh", Command: []string{"ssh", "{{.Name}}", "-F", "{{.Config}}"}},
+		{Name: "mosh", Command: []string{"mosh", "--ssh=ssh -F {{.Config}}", "{{.Name}}"}},
+		{Name: "autossh", Command: []string{"autossh", "-M", "0", "-F", "{{.Config}}", "{{.Name}}"}},
+		// et reads ~/.ssh/config, it has no option for another file
+		{Name: "et", Command: []string{"et", "{{.Name}}"}},
+		{Name: "kitty", Command: []string{"kitty", "+kitten", "ssh", "-F", "{{.Config}}", "{{.Name}}"}},
+		// the password is read from $SSHPASS
+		{Name: "sshpass", Command: []string{"sshpass", "-e", "ssh", "-F", "{{.Config}}", "{{.Name}}"}},
+	}
+}
+
+// Merge returns base with extra appended, connectors of extra
+// replace the ones of base with the same name.
+func Merge(base, extra []Connector) ([]Connector, error) {
+	out := slices.Clone(base)
+	for _, c := range extra {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if i := slices.IndexFunc(out, func(b Connector) bool { return b.Name == c.Name }); i >= 0 {
+			out[i] = c
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (c Connector) validate() error {
+	if c.Name == "" || len(c.Command) == 0 || c.Command[0] == "" {
+		return fmt.Errorf("connector %q needs a name and a command", c.Name)
+	}
+	for _, arg := range c.Command {
+		if _, err := parse(arg); err != nil {
+			return fmt.Errorf("connector %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func parse(arg string) (*template.Template, error) {
+	return template.New("arg").Option("missingkey=error").Parse(arg)
+}
+
+// Argv renders the command for d.
+func (c Connector) Argv(d Data) ([]string, error) {
+	argv := make([]string, 0, len(c.Command))
+	for _, arg := range c.Command {
+		t, err := parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("connector %s: %w", c.Name, err)
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, d); err != nil {
+			return nil, fmt.Errorf("connector %s on %s: %w", c.Name, d.Name, err)
+		}
+		if b.Len() > 0 {
+			argv = append(argv, b.String())
+		}
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("connector %s: empty command", c.Name)
+	}
+	return argv, nil
+}
+
+// Installed reports whether the program of c is in PATH.
+func (c Connector) Installed() bool {
+	if len(c.Command) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(c.Command[0])
+	return err == nil
+}
+
+// Find returns the connector called name.
+func Find(list []Connector, name string) (Connector, bool) {
+	i := slices.IndexFunc(list, func(c Connector) bool { return c.Name == name })
+	if i < 0 {
+		return Connector{}, false
+	}
+	return list[i], true
+}
+
+// Names returns the names of list.
+func Names(list []Connector) []string {
+	names := make([]string, 0, len(list))
+	for _, c := range list {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// For returns the connector of h: its `#connector:` comment
+// when set, name otherwise.
+func For(list []Connector, h sshconf.Host, name string) (Connector, error) {
+	if pinned := h.Meta()["connector"]; pinned != "" {
+		name = pinned
+	}
+	c, ok := Find(list, name)
+	if !ok {
+		return c, fmt.Errorf("unknown connector %q for %s, available: %s",
+			name, h.Name, strings.Join(Names(list), ", "))
+	}
+	return c, nil
+}
@@ -0,0 +1,88 @@
+package connector_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+const config = `
+Host web1
+#connector: mosh
+    User deploy
+    HostName 10.0.0.1
+
+Host db1
+    HostName 10.0.0.2
+    Port 2222
+`
+
+func hosts(t *testing.T) []sshconf.Host {
+	t.Helper()
+	return sshconftest.Parse(t, config).Hosts
+}
+
+func TestArgv(t *testing.T) {
+	hs := hosts(t)
+	list, err := connector.Merge(connector.Builtin(), []connector.Connector{
+		{Name: "tmux", Command: []string{"ssh", "-t", "{{.Name}}", "{{with .Port}}-p{{.}}{{end}}", "tmux new -A"}},
+		{Name: "ssh", Command: []string{"ssh", "-F", "{{.Config}}", "{{.User}}@{{.HostName}}"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(connector.Builtin())+1 {
+		t.Fatalf("merged %d connectors", len(list))
+	}
+
+	web, db := hs[0], hs[1]
+	for _, tc := range []struct {
+		name string
+		host sshconf.Host
+		want []string
+	}{
+		{"mosh", web, []string{"mosh", "--ssh=ssh -F /cfg", "web1"}},
+		{"ssh", web, []string{"ssh", "-F", "/cfg", "deploy@10.0.0.1"}},
+		{"tmux", db, []string{"ssh", "-t", "db1", "-p2222", "tmux new -A"}},
+		{"tmux", web, []string{"ssh", "-t", "web1", "-p22", "tmux new -A"}},
+	} {
+		c, ok := connector.Find(list, tc.name)
+		if !ok {
+			t.Fatalf("%s not found", tc.name)
+		}
+		got, err := c.Argv(connector.DataOf(tc.host, "/cfg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s on %s: got %q, want %q", tc.name, tc.host.Name, got, tc.want)
+		}
+	}
+
+	c, err := connector.For(list, web, "ssh")
+	if err != nil || c.Name != "mosh" {
+		t.Errorf("web1 pins mosh, got %s %v", c.Name, err)
+	}
+	c, err = connector.For(list, db, "kitty")
+	if err != nil || c.Name != "kitty" {
+		t.Errorf("db1 uses the default, got %s %v", c.Name, err)
+	}
+	if _, err := connector.For(list, db, "telnet"); err == nil {
+		t.Error("expected unknown connector error")
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	for _, c := range []connector.Connector{
+		{Name: "", Command: []string{"ssh"}},
+		{Name: "empty"},
+		{Name: "template", Command: []string{"ssh", "{{.Name"}},
+	} {
+		if _, err := connector.Merge(connector.Builtin(), []connector.Connector{c}); err == nil {
+			t.Errorf("%q: expected error", c.Name)
+		}
+	}
+}
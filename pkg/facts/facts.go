@@ -0,0 +1,300 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package facts collects the OS release, kernel, architecture,
+// CPUs, memory and boot time of hosts with plain shell commands
+// over ssh, nothing is installed remotely. Facts are cached in
+// $XDG_CACHE_HOME/ssm/facts.json and collected again past a TTL.
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshexec"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const (
+	fileName = "facts.json"
+	// DefaultTTL is how long facts are fresh.
+	DefaultTTL     = 24 * time.Hour
+	DefaultTimeout = 10 * time.Second
+	DefaultWorkers = 8
+)
+
+// Config is the [facts] table of config.toml.
+type Config struct {
+	// TTL is how long facts are fresh, DefaultTTL when zero.
+	TTL time.Duration `toml:"ttl"`
+	// Background collects the stale facts of every host at startup.
+	Background bool `toml:"background"`
+}
+
+// Query fields of the facts, see Fields.
+const (
+	FieldOS     = "os"
+	FieldKernel = "kernel"
+	FieldArch   = "arch"
+	FieldCPUs   = "cpus"
+)
+
+// Facts describe a host.
+type Facts struct {
+	Host string `json:"host"`
+	// OS is the ID of os-release: debian, ubuntu, alpine,
+	// or the lowercased kernel name without one: darwin.
+	OS        string `json:"os"`
+	OSName    string `json:"os_name,omitempty"`
+	OSVersion string `json:"os_version,omitempty"`
+	Kernel    string `json:"kernel"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus,omitempty"`
+	// Memory is the total memory in bytes.
+	Memory int64 `json:"memory,omitempty"`
+	// Boot is when the host booted, the uptime follows.
+	Boot      time.Time `json:"boot,omitzero"`
+	Collected time.Time `json:"collected"`
+}
+
+// Uptime returns how long the host has been up at now.
+func (f Facts) Uptime(now time.Time) time.Duration {
+	if f.Boot.IsZero() {
+		return 0
+	}
+	return now.Sub(f.Boot)
+}
+
+// Fields returns the facts as query fields: `os:debian`
+// matches the ID, name and version of the release.
+func (f Facts) Fields() map[string][]string {
+	fields := map[string][]string{
+		FieldOS:     {f.OS, f.OSName, f.OSVersion},
+		FieldKernel: {f.Kernel},
+		FieldArch:   {f.Arch},
+	}
+	if f.CPUs > 0 {
+		fields[FieldCPUs] = []string{strconv.Itoa(f.CPUs)}
+	}
+	return fields
+}
+
+// script prints key=value lines, the commands of each
+// fact fall back from Linux to BSD and macOS.
+const script = `echo "kernel=$(uname -r)"
+echo "system=$(uname -s)"
+echo "arch=$(uname -m)"
+echo "cpus=$(getconf _NPROCESSORS_ONLN 2>/dev/null || sysctl -n hw.ncpu 2>/dev/null)"
+echo "mem_kb=$(awk '/^MemTotal:/ {print $2}' /proc/meminfo 2>/dev/null)"
+echo "mem_bytes=$(sysctl -n hw.memsize 2>/dev/null || sysctl -n hw.physmem 2>/dev/null)"
+echo "uptime=$(cut -d' ' -f1 /proc/uptime 2>/dev/null)"
+echo "boottime=$(sysctl -n kern.boottime 2>/dev/null)"
+grep -E '^(ID|NAME|PRETTY_NAME|VERSION_ID)=' /etc/os-release 2>/dev/null
+command -v sw_vers >/dev/null 2>&1 && echo "ID=macos" && echo "PRETTY_NAME=macOS $(sw_vers -productVersion)" && echo "VERSION_ID=$(sw_vers -productVersion)"
+true
+`
+
+// Parse reads the output of the collection script run at now.
+func Parse(host string, out []byte, now time.Time) Facts {
+	f := Facts{Host: host, Collected: now}
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		values[k] = strings.Trim(v, `"'`)
+	}
+	f.Kernel = values["kernel"]
+	f.Arch = values["arch"]
+	f.CPUs, _ = strconv.Atoi(values["cpus"])
+	if kb, err := strconv.ParseInt(values["mem_kb"], 10, 64); err == nil {
+		f.Memory = kb * 1024
+	} else if b, err := strconv.ParseInt(values["mem_bytes"], 10, 64); err == nil {
+		f.Memory = b
+	}
+	if up, err := strconv.ParseFloat(values["uptime"], 64); err == nil {
+		f.Boot = now.Add(-time.Duration(up * float64(time.Second))).Round(time.Second)
+	} else if _, rest, ok := strings.Cut(values["boottime"], "sec = "); ok {
+		// { sec = 1690000000, usec = 0 } Sat Jul 22 ...
+		sec, _, _ := strings.Cut(rest, ",")
+		if s, err := strconv.ParseInt(sec, 10, 64); err == nil {
+			f.Boot = time.Unix(s, 0)
+		}
+	}
+	f.OS = strings.ToLower(values["ID"])
+	if f.OS == "" {
+		f.OS = strings.ToLower(values["system"])
+	}
+	f.OSName = values["PRETTY_NAME"]
+	if f.OSName == "" {
+		f.OSName = values["NAME"]
+	}
+	f.OSVersion = values["VERSION_ID"]
+	return f
+}
+
+// Collect runs the collection script on host with sshexec.Batch.
+func Collect(ctx context.Context, configPath, host string, timeout time.Duration) (Facts, error) {
+	out, err := sshexec.Batch(ctx, configPath, host, script, timeout)
+	if err != nil {
+		return Facts{}, fmt.Errorf("%s: %w", host, err)
+	}
+	f := Parse(host, out, time.Now())
+	if f.Kernel == "" {
+		return Facts{}, fmt.Errorf("%s: no facts in the output, is the shell POSIX?", host)
+	}
+	return f, nil
+}
+
+// Cache keeps the facts of hosts by Host alias.
+type Cache struct {
+	// TTL is how long facts are fresh.
+	TTL time.Duration
+
+	// protects hosts
+	mu    sync.Mutex
+	hosts map[string]Facts
+	path  string
+}
+
+// Open reads the cache from $XDG_CACHE_HOME/ssm/facts.json,
+// a missing file yields an empty cache.
+func Open(ttl time.Duration) (*Cache, error) {
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return New("", ttl), err
+	}
+	return OpenPath(filepath.Join(dir, fileName), ttl)
+}
+
+// OpenPath reads the cache from path.
+func OpenPath(path string, ttl time.Duration) (*Cache, error) {
+	c := New(path, ttl)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c.hosts); err != nil {
+		c.hosts = map[string]Facts{}
+		return c, fmt.Errorf("facts %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// New returns an empty cache saved to path, an empty path keeps
+// the cache in memory. A zero ttl is DefaultTTL.
+func New(path string, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{TTL: ttl, hosts: map[string]Facts{}, path: path}
+}
+
+// Get returns the facts of host, stale ones too.
+func (c *Cache) Get(host string) (Facts, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.hosts[host]
+	return f, ok
+}
+
+// Stale reports whether the facts of host are missing
+// or older than the TTL at now.
+func (c *Cache) Stale(host string, now time.Time) bool {
+	f, ok := c.Get(host)
+	return !ok || now.Sub(f.Collected) > c.TTL
+}
+
+// Put stores f and saves the cache.
+func (c *Cache) Put(f ...Facts) error {
+	c.mu.Lock()
+	for _, f := range f {
+		c.hosts[f.Host] = f
+	}
+	c.mu.Unlock()
+	return c.save()
+}
+
+// All returns the cached facts sorted by host.
+func (c *Cache) All() []Facts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Facts, 0, len(c.hosts))
+	for _, f := range c.hosts {
+		out = append(out, f)
+	}
+	slices.SortFunc(out, func(a, b Facts) int { return strings.Compare(a.Host, b.Host) })
+	return out
+}
+
+// save writes the cache atomically.
+func (c *Cache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(c.hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// CollectAll collects the facts of hosts in parallel and caches
+// them, the errors of the hosts that failed are joined.
+func (c *Cache) CollectAll(ctx context.Context, configPath string, hosts []string, workers int, timeout time.Duration) ([]Facts, error) {
+	var (
+		mu        sync.Mutex
+		collected []Facts
+		errs      []error
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, max(1, workers))
+	for _, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			f, err := Collect(ctx, configPath, h, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			collected = append(collected, f)
+		}()
+	}
+	wg.Wait()
+	if len(collected) > 0 {
+		if err := c.Put(collected...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return collected, errors.Join(errs...)
+}
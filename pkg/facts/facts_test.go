@@ -0,0 +1,102 @@
+package facts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/facts"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	linux := facts.Parse("web", []byte(`kernel=6.1.0-18-amd64
+system=Linux
+arch=x86_64
+cpus=4
+mem_kb=8048576
+mem_bytes=
+uptime=86400.12
+boottime=
+PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+NAME="Debian GNU/Linux"
+VERSION_ID="12"
+ID=debian
+`), now)
+	if linux.OS != "debian" || linux.OSVersion != "12" || linux.OSName != "Debian GNU/Linux 12 (bookworm)" ||
+		linux.Kernel != "6.1.0-18-amd64" || linux.Arch != "x86_64" || linux.CPUs != 4 ||
+		linux.Memory != 8048576*1024 || linux.Uptime(now) != 24*time.Hour {
+		t.Errorf("linux: %+v", linux)
+	}
+
+	mac := facts.Parse("mac", []byte(`kernel=23.5.0
+system=Darwin
+arch=arm64
+cpus=8
+mem_kb=
+mem_bytes=17179869184
+uptime=
+boottime={ sec = 1722470400, usec = 0 } Thu Aug  1 00:00:00 2024
+ID=macos
+PRETTY_NAME=macOS 14.5
+VERSION_ID=14.5
+`), now)
+	if mac.OS != "macos" || mac.Memory != 17179869184 || !mac.Boot.Equal(time.Unix(1722470400, 0)) {
+		t.Errorf("mac: %+v", mac)
+	}
+
+	bsd := facts.Parse("bsd", []byte("kernel=14.0-RELEASE\nsystem=FreeBSD\n"), now)
+	if bsd.OS != "freebsd" {
+		t.Errorf("bsd: %+v", bsd)
+	}
+	if fields := linux.Fields(); fields[facts.FieldOS][0] != "debian" || fields[facts.FieldCPUs][0] != "4" {
+		t.Errorf("fields: %v", fields)
+	}
+}
+
+// fakeSSH puts an ssh in PATH running the command locally,
+// hosts named down fail.
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\nfor last; do :; done\ncase \"$*\" in *down*) echo 'ssh: connect to host down port 22: Connection refused' >&2; exit 255;; esac\nexec sh -c \"$last\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCollectAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	fakeSSH(t)
+	path := filepath.Join(t.TempDir(), "facts.json")
+	cache, err := facts.OpenPath(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected, err := cache.CollectAll(context.Background(), "", []string{"web", "down"}, 2, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "down: ssh: connect to host down") {
+		t.Errorf("error: %v", err)
+	}
+	if len(collected) != 1 || collected[0].Kernel == "" || collected[0].Arch == "" || collected[0].OS == "" {
+		t.Fatalf("collected: %+v", collected)
+	}
+
+	reopened, err := facts.OpenPath(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, ok := reopened.Get("web"); !ok || reopened.Stale("web", now) {
+		t.Errorf("web not cached: %+v", reopened.All())
+	}
+	if !reopened.Stale("web", now.Add(2*time.Hour)) || !reopened.Stale("down", now) {
+		t.Error("stale facts")
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package health probes hosts with read-only commands: load,
+// memory, disk usage and failed systemd units. The probes of a
+// host run in a single `ssh -T` connection in BatchMode.
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshexec"
+)
+
+const (
+	DefaultInterval = 30 * time.Second
+	DefaultTimeout  = 10 * time.Second
+	DefaultWorkers  = 8
+)
+
+// Level is the state of a value against the thresholds of its probe.
+type Level int
+
+const (
+	Unknown Level = iota
+	OK
+	Warn
+	Crit
+)
+
+func (l Level) String() string {
+	switch l {
+	case OK:
+		return "ok"
+	case Warn:
+		return "warn"
+	case Crit:
+		return "crit"
+	}
+	return "unknown"
+}
+
+// Probe is a command printing a number, values at or above
+// Warn and Crit are flagged, zero thresholds are unset.
+type Probe struct {
+	Name    string  `toml:"name"`
+	Command string  `toml:"command"`
+	Unit    string  `toml:"unit"`
+	Warn    float64 `toml:"warn"`
+	Crit    float64 `toml:"crit"`
+}
+
+// Thresholds reports whether p flags its values.
+func (p Probe) Thresholds() bool {
+	return p.Warn > 0 || p.Crit > 0
+}
+
+// Level returns the level of v.
+func (p Probe) Level(v float64) Level {
+	switch {
+	case p.Crit > 0 && v >= p.Crit:
+		return Crit
+	case p.Warn > 0 && v >= p.Warn:
+		return Warn
+	}
+	return OK
+}
+
+// DefaultProbes read /proc and systemctl, they're meant for Linux.
+var DefaultProbes = []Probe{
+	{
+		Name:    "up",
+		Command: `awk '{printf "%.1f\n", $1/86400}' /proc/uptime`,
+		Unit:    "d",
+	},
+	{
+		// load average of the last minute by CPU
+		Name:    "load",
+		Command: `awk -v n="$(nproc 2>/dev/null || echo 1)" '{printf "%.2f\n", $1/n}' /proc/loadavg`,
+		Warn:    1,
+		Crit:    2,
+	},
+	{
+		Name:    "mem",
+		Command: `awk '/^MemTotal:/ {t=$2} /^MemAvailable:/ {a=$2} END {if (t) printf "%.0f\n", 100*(t-a)/t}' /proc/meminfo`,
+		Unit:    "%",
+		Warn:    80,
+		Crit:    95,
+	},
+	{
+		// the fullest filesystem backed by a device
+		Name:    "disk",
+		Command: `df -P | awk 'NR>1 && $1 ~ /^\/dev\// {u=$5+0; if (u>m) m=u} END {print m+0}'`,
+		Unit:    "%",
+		Warn:    80,
+		Crit:    90,
+	},
+	{
+		Name:    "failed",
+		Command: `command -v systemctl >/dev/null && systemctl list-units --state=failed --no-legend --plain | wc -l`,
+		Warn:    1,
+	},
+}
+
+// Config is the [health] table of config.toml.
+type Config struct {
+	Interval time.Duration `toml:"interval"`
+	Timeout  time.Duration `toml:"timeout"`
+	Workers  int           `toml:"workers"`
+	// Probes add to DefaultProbes, replacing those of the same
+	// name: an empty command removes a default probe.
+	Probes []Probe `toml:"probes"`
+}
+
+// WithDefaults returns c with the unset fields defaulted.
+func (c Config) WithDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Workers <= 0 {
+		c.Workers = DefaultWorkers
+	}
+	c.Probes = merge(DefaultProbes, c.Probes)
+	return c
+}
+
+func merge(defaults, probes []Probe) []Probe {
+	out := slices.Clone(defaults)
+	for _, p := range probes {
+		i := slices.IndexFunc(out, func(d Probe) bool { return d.Name == p.Name })
+		switch {
+		case i < 0:
+			out = append(out, p)
+		case p.Command == "":
+			out = slices.Delete(out, i, i+1)
+		default:
+			out[i] = p
+		}
+	}
+	return slices.DeleteFunc(out, func(p Probe) bool { return p.Command == "" })
+}
+
+// Value is the output of a probe.
+type Value struct {
+	Probe  string
+	Text   string
+	Number float64
+	Level  Level
+}
+
+// Report holds the values of the probes run on a host,
+// in the order of the probes.
+type Report struct {
+	Host    string
+	Values  []Value
+	Err     error
+	Time    time.Time
+	Elapsed time.Duration
+}
+
+// Worst returns the highest level of r, Crit when it failed.
+func (r Report) Worst() Level {
+	if r.Err != nil {
+		return Crit
+	}
+	worst := Unknown
+	for _, v := range r.Values {
+		worst = max(worst, v.Level)
+	}
+	return worst
+}
+
+// marker separates the output of the probes.
+const marker = "@@ssm-probe "
+
+// Script returns the shell script running probes, their output
+// follows a marker line.
+func Script(probes []Probe) string {
+	var b strings.Builder
+	for _, p := range probes {
+		fmt.Fprintf(&b, "echo '%s%s'; { %s ; } 2>/dev/null\n", marker, p.Name, p.Command)
+	}
+	return b.String()
+}
+
+// Parse returns the values of probes in the output of Script, probes
+// with no numeric output are Unknown.
+func Parse(out []byte, probes []Probe) []Value {
+	outputs := map[string]string{}
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if name, ok := strings.CutPrefix(line, marker); ok {
+			current = name
+			continue
+		}
+		// the first line printed is the value
+		if current != "" && line != "" && outputs[current] == "" {
+			outputs[current] = line
+		}
+	}
+	values := make([]Value, 0, len(probes))
+	for _, p := range probes {
+		v := Value{Probe: p.Name, Text: outputs[p.Name]}
+		fields := strings.Fields(v.Text)
+		if len(fields) > 0 {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+			if err == nil {
+				v.Number, v.Level = n, p.Level(n)
+			}
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// Run probes host in one non-interactive ssh session
+// with sshexec.Batch.
+func Run(ctx context.Context, configPath, host string, probes []Probe, timeout time.Duration) Report {
+	start := time.Now()
+	out, err := sshexec.Batch(ctx, configPath, host, Script(probes), timeout)
+	r := Report{Host: host, Time: start, Elapsed: time.Since(start), Err: err}
+	if err == nil {
+		r.Values = Parse(out, probes)
+	}
+	return r
+}
+
+// Sort orders reports by column: 0 sorts by host, 1 by the worst
+// level and the following ones by the value of probe column-2.
+// Hosts without a value sort last either way.
+func Sort(reports []Report, column int, reverse bool) {
+	slices.SortStableFunc(reports, func(a, b Report) int {
+		var c int
+		switch {
+		case column <= 0:
+			c = strings.Compare(a.Host, b.Host)
+		case column == 1:
+			c = cmp.Compare(a.Worst(), b.Worst())
+		default:
+			av, aok := a.number(column - 2)
+			bv, bok := b.number(column - 2)
+			switch {
+			case !aok || !bok:
+				// missing values last, even reversed
+				if aok != bok {
+					if aok {
+						return -1
+					}
+					return 1
+				}
+			default:
+				c = cmp.Compare(av, bv)
+			}
+		}
+		if reverse {
+			c = -c
+		}
+		return cmp.Or(c, strings.Compare(a.Host, b.Host))
+	})
+}
+
+func (r Report) number(i int) (float64, bool) {
+	if i >= len(r.Values) || r.Values[i].Level == Unknown {
+		return 0, false
+	}
+	return r.Values[i].Number, true
+}
@@ -0,0 +1,110 @@
+package health_test
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/health"
+)
+
+func TestScript(t *testing.T) {
+	probes := []health.Probe{
+		{Name: "disk", Command: "echo 91%", Unit: "%", Warn: 80, Crit: 90},
+		{Name: "load", Command: "printf '0.50\\n1.00\\n'", Warn: 1},
+		{Name: "quoted", Command: `echo "it's 3"`},
+		{Name: "missing", Command: "ssm-no-such-command", Warn: 1},
+		{Name: "text", Command: "echo n/a"},
+	}
+	out, err := exec.Command("sh", "-c", health.Script(probes)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := health.Parse(out, probes)
+	want := []struct {
+		text  string
+		n     float64
+		level health.Level
+	}{
+		{"91%", 91, health.Crit},
+		{"0.50", 0.5, health.OK},
+		{"it's 3", 0, health.Unknown},
+		{"", 0, health.Unknown},
+		{"n/a", 0, health.Unknown},
+	}
+	for i, w := range want {
+		v := values[i]
+		if v.Text != w.text || v.Number != w.n || v.Level != w.level {
+			t.Errorf("%s: got %q %v %v, want %q %v %v", v.Probe, v.Text, v.Number, v.Level, w.text, w.n, w.level)
+		}
+	}
+}
+
+func TestDefaultProbes(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the default probes read /proc")
+	}
+	probes := health.DefaultProbes[:3]
+	out, err := exec.Command("sh", "-c", health.Script(probes)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range health.Parse(out, probes) {
+		if v.Level == health.Unknown {
+			t.Errorf("%s: no value in %q", v.Probe, out)
+		}
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	c := health.Config{Probes: []health.Probe{
+		{Name: "disk", Command: "df -P /", Warn: 70},
+		{Name: "failed"},
+		{Name: "nginx", Command: "pgrep -c nginx"},
+	}}.WithDefaults()
+	var names string
+	for _, p := range c.Probes {
+		names += p.Name + " "
+	}
+	if names != "up load mem disk nginx " || c.Probes[3].Warn != 70 {
+		t.Errorf("probes: %s%+v", names, c.Probes[3])
+	}
+	if c.Interval != health.DefaultInterval || c.Workers != health.DefaultWorkers {
+		t.Errorf("defaults: %+v", c)
+	}
+}
+
+func TestSort(t *testing.T) {
+	report := func(host string, load float64, level health.Level) health.Report {
+		return health.Report{Host: host, Values: []health.Value{{Probe: "load", Number: load, Level: level}}}
+	}
+	reports := []health.Report{
+		report("c", 0.2, health.OK),
+		report("a", 3, health.Crit),
+		{Host: "b", Err: errors.New("timed out")},
+		report("d", 1.5, health.Warn),
+	}
+	hosts := func() string {
+		var s string
+		for _, r := range reports {
+			s += r.Host
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		column  int
+		reverse bool
+		want    string
+	}{
+		{0, false, "abcd"},
+		{1, true, "abdc"},
+		{2, false, "cdab"},
+		{2, true, "adcb"},
+	} {
+		health.Sort(reports, tt.column, tt.reverse)
+		if got := hosts(); got != tt.want {
+			t.Errorf("column %d reverse %v: got %s, want %s", tt.column, tt.reverse, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package history records every connection session
+// as JSON lines under $XDG_STATE_HOME/ssm/history.jsonl.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const fileName = "history.jsonl"
+
+// TailLines is the number of stderr lines kept per session.
+const TailLines = 10
+
+// Entry is a single connection session.
+type Entry struct {
+	Host      string        `json:"host"`
+	Connector string        `json:"connector"`
+	Start     time.Time     `json:"start"`
+	Duration  time.Duration `json:"duration"`
+	ExitCode  int           `json:"exit_code"`
+	Error     string        `json:"error,omitempty"`
+	Stderr    []string      `json:"stderr,omitempty"`
+}
+
+// Log is an append-only session history file.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open returns the history log in $XDG_STATE_HOME/ssm.
+func Open() (*Log, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return &Log{}, err
+	}
+	return OpenPath(filepath.Join(dir, fileName)), nil
+}
+
+// OpenPath returns the history log stored at path,
+// an empty path discards every entry.
+func OpenPath(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the history file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append adds e at the end of the history.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Entries returns every recorded session, most recent first.
+// Malformed lines are skipped.
+func (l *Log) Entries() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, scanner.Err()
+}
+
+// Tail is an io.Writer keeping the last n lines written to it.
+type Tail struct {
+	mu    sync.Mutex
+	n     int
+	lines []string
+	part  []byte
+}
+
+// NewTail returns a Tail keeping n lines.
+func NewTail(n int) *Tail {
+	return &Tail{n: n}
+}
+
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.part = append(t.part, p...)
+	for {
+		i := bytes.IndexByte(t.part, '\n')
+		if i < 0 {
+			break
+		}
+		t.push(string(t.part[:i]))
+		t.part = t.part[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, including an unterminated last one.
+func (t *Tail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string{}, t.lines...)
+	if len(t.part) > 0 {
+		lines = append(lines, string(t.part))
+	}
+	if len(lines) > t.n {
+		lines = lines[len(lines)-t.n:]
+	}
+	return lines
+}
+
+func (t *Tail) push(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.n {
+		t.lines = t.lines[len(t.lines)-t.n:]
+	}
+}
+
+// ExitCode returns the process exit code carried by err,
+// -1 when the process didn't start or was killed.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,42 @@
+package history_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/history"
+)
+
+func TestLog(t *testing.T) {
+	log := history.OpenPath(filepath.Join(t.TempDir(), "history.jsonl"))
+	for i := range 3 {
+		err := log.Append(history.Entry{
+			Host:      fmt.Sprintf("host%d", i),
+			Connector: "ssh",
+			Start:     time.Now(),
+			ExitCode:  i,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := log.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Host != "host2" || entries[0].ExitCode != 2 {
+		t.Fatalf("want most recent first, got %+v", entries)
+	}
+}
+
+func TestTail(t *testing.T) {
+	tail := history.NewTail(2)
+	fmt.Fprint(tail, "one\ntwo\n\nthree\nfo")
+	fmt.Fprint(tail, "ur")
+	got := tail.Lines()
+	if len(got) != 2 || got[0] != "three" || got[1] != "four" {
+		t.Fatalf("got %q", got)
+	}
+}
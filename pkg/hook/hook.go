@@ -0,0 +1,175 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package hook runs the shell commands set to run before and after
+// a connection: bringing a VPN up, a port knock, Wake-on-LAN or
+// kinit, and their cleanup. Hooks are set in config.toml, globally,
+// per tag and per host:
+//
+//	[hooks]
+//	pre = ["wg-quick up office"]
+//	post = ["wg-quick down office"]
+//
+//	[hooks.tags.lab]
+//	pre = ["wakeonlan 00:11:22:33:44:55", "sleep 20"]
+//
+//	[hooks.hosts.db]
+//	pre = ["kinit -R"]
+//
+// and with `#pre-connect:` and `#post-connect:` host comments.
+// Commands run with sh -c, the host fields in SSM_* variables.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/secret"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+const (
+	// PreMeta and PostMeta are the host comments
+	// adding a hook to the host.
+	PreMeta  = "pre-connect"
+	PostMeta = "post-connect"
+	// DefaultTimeout bounds each hook command.
+	DefaultTimeout = 2 * time.Minute
+)
+
+// Hooks are the commands run before and after connecting.
+type Hooks struct {
+	Pre  []string `toml:"pre"`
+	Post []string `toml:"post"`
+}
+
+// Empty reports whether no command is set.
+func (h Hooks) Empty() bool {
+	return len(h.Pre) == 0 && len(h.Post) == 0
+}
+
+func (h Hooks) add(o Hooks) Hooks {
+	return Hooks{
+		Pre:  append(h.Pre, o.Pre...),
+		Post: append(h.Post, o.Post...),
+	}
+}
+
+// Config are the hooks of config.toml.
+type Config struct {
+	Hooks
+	Tags  map[string]Hooks `toml:"tags"`
+	Hosts map[string]Hooks `toml:"hosts"`
+	// Timeout bounds each command, DefaultTimeout when zero.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// For returns the hooks of h in run order: global ones,
+// then those of its tags, of its name and of its comments.
+func (c Config) For(h sshconf.Host) Hooks {
+	hooks := Hooks{}.add(c.Hooks)
+	for _, t := range h.Tags() {
+		hooks = hooks.add(c.Tags[t])
+	}
+	hooks = hooks.add(c.Hosts[h.Name])
+	meta := h.Meta()
+	if v := strings.TrimSpace(meta[PreMeta]); v != "" {
+		hooks.Pre = append(hooks.Pre, v)
+	}
+	if v := strings.TrimSpace(meta[PostMeta]); v != "" {
+		hooks.Post = append(hooks.Post, v)
+	}
+	return hooks
+}
+
+// Env returns the variables describing h to its hooks.
+func Env(h sshconf.Host, connector string) []string {
+	return []string{
+		"SSM_HOST=" + h.Name,
+		"SSM_HOSTNAME=" + h.HostName(),
+		"SSM_USER=" + h.User(),
+		"SSM_PORT=" + h.Port(),
+		"SSM_TAGS=" + strings.Join(h.Tags(), ","),
+		"SSM_PROXYJUMP=" + h.ProxyJump(),
+		"SSM_CONNECTOR=" + connector,
+		"SSM_CONFIG=" + h.File,
+	}
+}
+
+// ResultEnv returns the variables describing how the session
+// ended to the post hooks.
+func ResultEnv(exitCode int, d time.Duration) []string {
+	return []string{
+		"SSM_EXIT_CODE=" + strconv.Itoa(exitCode),
+		"SSM_DURATION=" + strconv.Itoa(int(d.Seconds())),
+	}
+}
+
+// Deferred are the post-connect hooks of a session running outside
+// of ssm, passed as JSON to the `ssm hook` wrapper of the session.
+// The wrapper appends the session to the History file when set.
+type Deferred struct {
+	Post      []string `json:"post"`
+	Env       []string `json:"env"`
+	History   string   `json:"history,omitempty"`
+	Host      string   `json:"host,omitempty"`
+	Connector string   `json:"connector,omitempty"`
+}
+
+// Error is a failed hook command.
+type Error struct {
+	Command string
+	Output  []byte
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hook `%s`: %v", e.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run runs cmds in order with env added to the ssm environment,
+// and stops at the first failure returning an *Error. The output
+// of the commands is returned combined.
+func Run(ctx context.Context, cmds, env []string, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	var all bytes.Buffer
+	for _, c := range cmds {
+		out, err := run(ctx, c, env, timeout)
+		all.Write(out)
+		if err != nil {
+			return all.Bytes(), &Error{Command: c, Output: out, Err: err}
+		}
+	}
+	return all.Bytes(), nil
+}
+
+func run(ctx context.Context, c string, env []string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", c)
+	// the connection may hold a #secret: password, not its hooks
+	cmd.Env = append(secret.Scrub(os.Environ()), env...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// background jobs keeping the pipes open don't hold the hook
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return out.Bytes(), err
+}
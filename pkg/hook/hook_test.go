@@ -0,0 +1,80 @@
+package hook_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+const config = `
+Host lab1
+    #tag: lab,home
+    #pre-connect: echo meta
+    HostName 10.0.0.5
+    User pi
+`
+
+func host(t *testing.T) sshconf.Host {
+	t.Helper()
+	return sshconftest.Parse(t, config).GetHost("lab1")
+}
+
+func TestFor(t *testing.T) {
+	c := hook.Config{
+		Hooks: hook.Hooks{Pre: []string{"global"}, Post: []string{"global down"}},
+		Tags: map[string]hook.Hooks{
+			"home": {Pre: []string{"home"}},
+			"lab":  {Pre: []string{"lab"}},
+			"prod": {Pre: []string{"prod"}},
+		},
+		Hosts: map[string]hook.Hooks{"lab1": {Post: []string{"host down"}}},
+	}
+	got := c.For(host(t))
+	if want := []string{"global", "lab", "home", "echo meta"}; !slices.Equal(got.Pre, want) {
+		t.Errorf("pre %q, want %q", got.Pre, want)
+	}
+	if want := []string{"global down", "host down"}; !slices.Equal(got.Post, want) {
+		t.Errorf("post %q, want %q", got.Post, want)
+	}
+	if len(c.Pre) != 1 {
+		t.Errorf("config modified: %q", c.Pre)
+	}
+}
+
+func TestRun(t *testing.T) {
+	env := hook.Env(host(t), "ssh")
+	out, err := hook.Run(context.Background(), []string{`echo "$SSM_USER@$SSM_HOSTNAME:$SSM_PORT $SSM_TAGS"`}, env, 0)
+	if err != nil || string(out) != "pi@10.0.0.5:22 lab,home\n" {
+		t.Errorf("got %q %v", out, err)
+	}
+
+	out, err = hook.Run(context.Background(), []string{"echo up", "echo no vpn >&2; exit 3", "echo unreachable"}, nil, 0)
+	var hookErr *hook.Error
+	if !errors.As(err, &hookErr) || hookErr.Command != "echo no vpn >&2; exit 3" || string(hookErr.Output) != "no vpn\n" {
+		t.Fatalf("got %v", err)
+	}
+	if string(out) != "up\nno vpn\n" {
+		t.Errorf("output %q", out)
+	}
+
+	// the wrapper of a #secret: host has the password in its environment
+	t.Setenv("SSHPASS", "hunter2")
+	t.Setenv("SSH_ASKPASS", "ssm")
+	t.Setenv("SSM_ASKPASS", "1")
+	out, err = hook.Run(context.Background(), []string{`echo "$SSHPASS$SSH_ASKPASS$SSM_ASKPASS"`}, nil, 0)
+	if err != nil || string(out) != "\n" {
+		t.Errorf("password in hook environment: %q %v", out, err)
+	}
+
+	_, err = hook.Run(context.Background(), []string{"sleep 5"}, nil, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want timeout", err)
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package jumpgraph builds the graph of the hosts reaching each
+// other through `ProxyJump` and `ProxyCommand ssh -W` bastions.
+package jumpgraph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+// Graph is the jump topology of an ssh config.
+type Graph struct {
+	hosts map[string]sshconf.Host
+	// names keeps the config order.
+	names []string
+	// jumps are the hops of each host, in ssh order.
+	jumps map[string][]string
+}
+
+// New returns the graph of hosts.
+func New(hosts []sshconf.Host) *Graph {
+	g := &Graph{
+		hosts: map[string]sshconf.Host{},
+		jumps: map[string][]string{},
+	}
+	for _, h := range hosts {
+		if _, ok := g.hosts[h.Name]; ok {
+			continue
+		}
+		g.hosts[h.Name] = h
+		g.names = append(g.names, h.Name)
+		if hops := Jumps(h); len(hops) > 0 {
+			g.jumps[h.Name] = hops
+		}
+	}
+	return g
+}
+
+// Has reports whether name is a host of g.
+func (g *Graph) Has(name string) bool {
+	_, ok := g.hosts[name]
+	return ok
+}
+
+// Jumps returns the jump hosts of h in ssh order: ProxyJump
+// entries, or the destination of a `ProxyCommand ssh -W`.
+func Jumps(h sshconf.Host) []string {
+	if spec := h.ProxyJump(); spec != "" {
+		var hops []string
+		for _, hop := range strings.Split(spec, ",") {
+			if name := hopName(hop); name != "" {
+				hops = append(hops, name)
+			}
+		}
+		return hops
+	}
+	if name := proxyCommandJump(h.ProxyCommand()); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+// hopName returns the host of a `[user@]host[:port]` or
+// `ssh://[user@]host[:port]` jump.
+func hopName(hop string) string {
+	hop = strings.TrimPrefix(strings.TrimSpace(hop), "ssh://")
+	if i := strings.LastIndex(hop, "@"); i >= 0 {
+		hop = hop[i+1:]
+	}
+	if strings.HasPrefix(hop, "[") {
+		// [::1]:22
+		if end := strings.Index(hop, "]"); end > 0 {
+			return hop[1:end]
+		}
+	}
+	if strings.Count(hop, ":") == 1 {
+		hop, _, _ = strings.Cut(hop, ":")
+	}
+	return hop
+}
+
+// sshArgOptions are the ssh options taking an argument.
+const sshArgOptions = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// proxyCommandJump returns the host of `ssh [options] host -W %h:%p`,
+// empty for other proxy commands.
+func proxyCommandJump(cmd string) string {
+	args := strings.Fields(cmd)
+	if len(args) == 0 || (args[0] != "ssh" && !strings.HasSuffix(args[0], "/ssh")) {
+		return ""
+	}
+	var host string
+	forward := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+			if host == "" {
+				host = hopName(arg)
+			}
+			continue
+		}
+		// flags may be grouped, the option taking an argument
+		// ends the group: -qW %h:%p, -W%h:%p
+		for j := 1; j < len(arg); j++ {
+			if arg[j] == 'W' {
+				forward = true
+			}
+			if strings.IndexByte(sshArgOptions, arg[j]) >= 0 {
+				if j == len(arg)-1 {
+					i++
+				}
+				break
+			}
+		}
+	}
+	if !forward {
+		return ""
+	}
+	return host
+}
+
+// Chain is the route to a host, from the first bastion.
+type Chain struct {
+	// Hops ends with the host itself.
+	Hops []string
+	// Cycle is set when a host of the route jumps back to it.
+	Cycle bool
+	// Missing are the jump hosts not defined in the config.
+	Missing []string
+}
+
+// String renders the chain as `a → b → host`, a cycle
+// leaves the start of the route unknown: `… → b → host`.
+func (c Chain) String() string {
+	s := strings.Join(c.Hops, " → ")
+	if c.Cycle {
+		s = "… → " + s
+	}
+	return s
+}
+
+// Chain returns the route to name. ProxyJump a,b reaches b
+// through a, a itself through its own jumps.
+func (g *Graph) Chain(name string) Chain {
+	var c Chain
+	var walk func(name string, seen []string) []string
+	walk = func(name string, seen []string) []string {
+		if slices.Contains(seen, name) {
+			c.Cycle = true
+			return nil
+		}
+		seen = append(seen, name)
+		if _, ok := g.hosts[name]; !ok && !slices.Contains(c.Missing, name) {
+			c.Missing = append(c.Missing, name)
+		}
+		hops := g.jumps[name]
+		if len(hops) == 0 {
+			return []string{name}
+		}
+		route := walk(hops[0], seen)
+		for _, hop := range hops[1:] {
+			if slices.Contains(seen, hop) || slices.Contains(route, hop) {
+				c.Cycle = true
+				continue
+			}
+			if _, ok := g.hosts[hop]; !ok && !slices.Contains(c.Missing, hop) {
+				c.Missing = append(c.Missing, hop)
+			}
+			route = append(route, hop)
+		}
+		return append(route, name)
+	}
+	c.Hops = walk(name, nil)
+	// the host itself isn't a jump
+	c.Missing = slices.DeleteFunc(c.Missing, func(m string) bool { return m == name })
+	return c
+}
+
+// Edge connects a jump host to the host it leads to.
+type Edge struct {
+	From, To string
+}
+
+// Edges returns the jump edges in config order.
+func (g *Graph) Edges() []Edge {
+	var out []Edge
+	for _, name := range g.names {
+		hops := g.jumps[name]
+		for i, hop := range hops {
+			to := name
+			if i+1 < len(hops) {
+				to = hops[i+1]
+			}
+			e := Edge{From: hop, To: to}
+			if !slices.Contains(out, e) {
+				out = append(out, e)
+			}
+		}
+	}
+	return out
+}
+
+// nodes returns the hosts involved in jumps, config hosts
+// first then the missing ones.
+func (g *Graph) nodes() []string {
+	var out []string
+	add := func(n string) {
+		if !slices.Contains(out, n) {
+			out = append(out, n)
+		}
+	}
+	edges := g.Edges()
+	for _, name := range g.names {
+		for _, e := range edges {
+			if e.From == name || e.To == name {
+				add(name)
+				break
+			}
+		}
+	}
+	for _, e := range edges {
+		add(e.From)
+		add(e.To)
+	}
+	return out
+}
+
+// Only keeps the hosts of names and their routes.
+func (g *Graph) Only(names []string) *Graph {
+	keep := map[string]bool{}
+	for _, n := range names {
+		for _, hop := range g.Chain(n).Hops {
+			keep[hop] = true
+		}
+	}
+	out := &Graph{hosts: map[string]sshconf.Host{}, jumps: map[string][]string{}}
+	for _, n := range g.names {
+		if keep[n] {
+			out.hosts[n] = g.hosts[n]
+			out.names = append(out.names, n)
+			if hops, ok := g.jumps[n]; ok {
+				out.jumps[n] = hops
+			}
+		}
+	}
+	return out
+}
+
+// flagged returns the hosts on a cycle and the missing ones.
+func (g *Graph) flagged() (cycle, missing map[string]bool) {
+	cycle, missing = map[string]bool{}, map[string]bool{}
+	for _, n := range g.nodes() {
+		if _, ok := g.hosts[n]; !ok {
+			missing[n] = true
+			continue
+		}
+		if c := g.Chain(n); c.Cycle {
+			cycle[n] = true
+		}
+	}
+	return cycle, missing
+}
+
+func (g *Graph) label(n string) string {
+	h, ok := g.hosts[n]
+	if !ok {
+		return n + "\\n(not in config)"
+	}
+	if hn := h.HostName(); hn != n {
+		return n + "\\n" + hn
+	}
+	return n
+}
+
+// DOT renders the graph for Graphviz.
+func (g *Graph) DOT() string {
+	cycle, missing := g.flagged()
+	var b strings.Builder
+	b.WriteString("digraph ssm {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, n := range g.nodes() {
+		attrs := fmt.Sprintf("label=%q", g.label(n))
+		attrs = strings.ReplaceAll(attrs, `\\n`, `\n`)
+		switch {
+		case missing[n]:
+			attrs += ", style=dashed"
+		case cycle[n]:
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "\t%q [%s];\n", n, attrs)
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "\t%q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *Graph) Mermaid() string {
+	cycle, missing := g.flagged()
+	ids := map[string]string{}
+	for i, n := range g.nodes() {
+		ids[n] = fmt.Sprintf("n%d", i)
+	}
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.nodes() {
+		label := strings.ReplaceAll(g.label(n), `\n`, "<br/>")
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		class := ""
+		switch {
+		case missing[n]:
+			class = ":::missing"
+		case cycle[n]:
+			class = ":::cycle"
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]%s\n", ids[n], label, class)
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "    %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	if len(missing) > 0 {
+		b.WriteString("    classDef missing stroke-dasharray: 5 5\n")
+	}
+	if len(cycle) > 0 {
+		b.WriteString("    classDef cycle stroke:#f00\n")
+	}
+	return b.String()
+}
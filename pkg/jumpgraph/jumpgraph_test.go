@@ -0,0 +1,109 @@
+package jumpgraph_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/jumpgraph"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+const config = `
+Host app.corp
+    HostName 10.1.0.2
+
+Host edge
+    HostName 203.0.113.1
+
+Host bastion
+    ProxyJump admin@edge:2222
+
+Host db
+    ProxyJump bastion,inner
+
+Host inner
+    HostName 10.0.0.2
+
+Host legacy
+    ProxyCommand ssh -q -W %h:%p -p 2200 bastion
+
+Host lab
+    ProxyJump gone
+
+Host loop1
+    ProxyJump loop2
+
+Host loop2
+    ProxyCommand ssh loop1 -W %h:%p
+
+Host nc
+    ProxyCommand nc -X 5 -x proxy:1080 %h %p
+
+Host direct
+    ProxyJump none
+    ProxyCommand ssh -W %h:%p bastion
+
+Host direct.corp
+    ProxyJump none
+
+Host *.corp
+    ProxyJump bastion
+`
+
+func graph(t *testing.T) *jumpgraph.Graph {
+	t.Helper()
+	return jumpgraph.New(sshconftest.Parse(t, config).Hosts)
+}
+
+func TestChain(t *testing.T) {
+	g := graph(t)
+	for _, tc := range []struct {
+		host    string
+		hops    string
+		cycle   bool
+		missing []string
+	}{
+		{"edge", "edge", false, nil},
+		{"db", "edge → bastion → inner → db", false, nil},
+		{"legacy", "edge → bastion → legacy", false, nil},
+		{"lab", "gone → lab", false, []string{"gone"}},
+		{"loop1", "… → loop2 → loop1", true, nil},
+		{"nc", "nc", false, nil},
+		{"app.corp", "edge → bastion → app.corp", false, nil},
+		{"direct", "direct", false, nil},
+		{"direct.corp", "direct.corp", false, nil},
+	} {
+		c := g.Chain(tc.host)
+		if c.String() != tc.hops {
+			t.Errorf("%s: chain %s, want %s", tc.host, c, tc.hops)
+		}
+		if c.Cycle != tc.cycle || !slices.Equal(c.Missing, tc.missing) {
+			t.Errorf("%s: cycle %v missing %v", tc.host, c.Cycle, c.Missing)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	g := graph(t)
+	dot := g.DOT()
+	for _, want := range []string{
+		`"edge" -> "bastion";`,
+		`"bastion" -> "inner";`,
+		`"inner" -> "db";`,
+		`"gone" [label="gone\n(not in config)", style=dashed];`,
+		`"loop1" [label="loop1", color=red];`,
+		`"edge" [label="edge\n203.0.113.1"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot misses %s\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, `"nc"`) {
+		t.Error("hosts without jumps are left out")
+	}
+	mm := g.Only([]string{"db"}).Mermaid()
+	if !strings.HasPrefix(mm, "flowchart LR\n") || strings.Contains(mm, "loop1") || !strings.Contains(mm, "-->") {
+		t.Errorf("mermaid:\n%s", mm)
+	}
+}
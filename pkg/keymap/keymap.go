@@ -0,0 +1,228 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package keymap loads the user key bindings of keymap.toml,
+// every entry replaces the keys of an action. The actions of
+// a screen are in its table, their IDs are `screen.action`:
+//
+//	connect = ["enter", "o"]
+//	cursor-up = ["k"]
+//	sftp = [] # unbound, keeps ctrl+s free for tmux
+//
+//	[masters]
+//	stop-all = ["shift+s"]
+package keymap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const fileName = "keymap.toml"
+
+// Keymap binds action IDs to keys.
+type Keymap map[string][]string
+
+// Path returns $XDG_CONFIG_HOME/ssm/keymap.toml.
+func Path() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the keymap from Path, a missing file is empty.
+func Load() (Keymap, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(path)
+}
+
+// LoadPath reads the keymap of path, a missing file is empty.
+func LoadPath(path string) (Keymap, error) {
+	var file map[string]any
+	_, err := toml.DecodeFile(path, &file)
+	if errors.Is(err, os.ErrNotExist) {
+		return Keymap{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+	km := Keymap{}
+	if err := km.add("", file); err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+	return km, nil
+}
+
+// add binds the entries of table, the IDs of nested
+// tables are prefixed with their name.
+func (k Keymap) add(prefix string, table map[string]any) error {
+	for name, v := range table {
+		id := prefix + name
+		switch v := v.(type) {
+		case map[string]any:
+			if prefix != "" {
+				return fmt.Errorf("%s: tables nest one level", id)
+			}
+			if err := k.add(id+".", v); err != nil {
+				return err
+			}
+		case []any:
+			keys := make([]string, 0, len(v))
+			for _, key := range v {
+				s, ok := key.(string)
+				if !ok {
+					return fmt.Errorf("%s: keys are strings, got %v", id, key)
+				}
+				keys = append(keys, Normalize(s))
+			}
+			k[id] = keys
+		default:
+			return fmt.Errorf("%s: keys are a list, got %v", id, v)
+		}
+	}
+	return nil
+}
+
+// Scope returns the screen of a `screen.action` ID,
+// "" for the actions of the host list.
+func Scope(id string) string {
+	screen, _, ok := strings.Cut(id, ".")
+	if !ok {
+		return ""
+	}
+	return screen
+}
+
+// Merge returns k with the bindings of over replacing its own,
+// unknown actions in over are an error.
+func (k Keymap) Merge(over Keymap) (Keymap, error) {
+	out := make(Keymap, len(k))
+	for id, keys := range k {
+		out[id] = keys
+	}
+	var unknown []string
+	for id, keys := range over {
+		if _, ok := k[id]; !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		out[id] = keys
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return out, fmt.Errorf("keymap: unknown actions %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
+
+// Lookup returns the host list action bound to key.
+func (k Keymap) Lookup(key string) (string, bool) {
+	return k.LookupIn("", key)
+}
+
+// LookupIn returns the action of screen bound to key,
+// without its screen prefix.
+func (k Keymap) LookupIn(screen, key string) (string, bool) {
+	for id, keys := range k {
+		if Scope(id) == screen && slices.Contains(keys, key) {
+			return strings.TrimPrefix(id, screen+"."), true
+		}
+	}
+	return "", false
+}
+
+// Conflict is a key bound to more than one action of a screen.
+type Conflict struct {
+	Key     string
+	Actions []string
+}
+
+func (c Conflict) Error() string {
+	return fmt.Sprintf("%s is bound to %s", c.Key, strings.Join(c.Actions, " and "))
+}
+
+// Conflicts returns the keys bound to several actions of the
+// same screen, sorted by key. Screens never see each other keys.
+func (k Keymap) Conflicts() []Conflict {
+	type scoped struct{ screen, key string }
+	byKey := map[scoped][]string{}
+	for id, keys := range k {
+		for _, key := range keys {
+			s := scoped{Scope(id), key}
+			byKey[s] = append(byKey[s], id)
+		}
+	}
+	var out []Conflict
+	for s, ids := range byKey {
+		if len(ids) > 1 {
+			sort.Strings(ids)
+			out = append(out, Conflict{Key: s.key, Actions: ids})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Actions[0] < out[j].Actions[0]
+	})
+	return out
+}
+
+// Validate reports every conflict of k as a single error.
+func (k Keymap) Validate() error {
+	var errs []error
+	for _, c := range k.Conflicts() {
+		errs = append(errs, c)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("keymap: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// modifiers in the order keys are reported by the terminal.
+var modifiers = []string{"ctrl", "alt", "shift", "meta", "hyper", "super"}
+
+// Normalize writes key the way key presses are reported:
+// modifiers lowercase and ordered, `H` becomes `shift+h`.
+func Normalize(key string) string {
+	parts := strings.Split(strings.TrimSpace(key), "+")
+	base := parts[len(parts)-1]
+	if key == "+" || strings.HasSuffix(key, "++") {
+		base = "+"
+	}
+	mods := map[string]bool{}
+	for _, p := range parts[:len(parts)-1] {
+		if p != "" {
+			mods[strings.ToLower(p)] = true
+		}
+	}
+	// ctrl+E is ctrl+e, terminals can't tell them apart
+	if r := []rune(base); len(r) == 1 && r[0] >= 'A' && r[0] <= 'Z' && !mods["ctrl"] {
+		mods["shift"] = true
+	}
+	if len(parts) > 1 || len([]rune(base)) > 1 || mods["shift"] {
+		base = strings.ToLower(base)
+	}
+	var b strings.Builder
+	for _, m := range modifiers {
+		if mods[m] {
+			b.WriteString(m + "+")
+		}
+	}
+	b.WriteString(base)
+	return b.String()
+}
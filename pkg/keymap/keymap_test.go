@@ -0,0 +1,134 @@
+package keymap_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/keymap"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"H":            "shift+h",
+		"shift+ctrl+A": "ctrl+shift+a",
+		"Ctrl+E":       "ctrl+e",
+		"enter":        "enter",
+		"PgDown":       "pgdown",
+		"*":            "*",
+		"ctrl++":       "ctrl++",
+		"/":            "/",
+	} {
+		if got := keymap.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadMerge(t *testing.T) {
+	defaults := keymap.Keymap{
+		"connect":     {"enter"},
+		"cursor-up":   {"up", "k", "ctrl+p"},
+		"cursor-down": {"down", "j", "ctrl+n"},
+		"sftp":        {"ctrl+s"},
+		"history":     {"shift+h"},
+	}
+	if err := defaults.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keymap.toml")
+	file := `
+cursor-up = ["k"]
+cursor-down = ["j"]
+sftp = []
+connect = ["enter", "H"]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	user, err := keymap.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := defaults.Merge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := km.Lookup("ctrl+s"); ok {
+		t.Errorf("ctrl+s still bound to %s", id)
+	}
+	if id, _ := km.Lookup("k"); id != "cursor-up" {
+		t.Errorf("k bound to %q, want cursor-up", id)
+	}
+	if _, ok := km.Lookup("up"); ok {
+		t.Error("up still bound")
+	}
+
+	err = km.Validate()
+	if err == nil || !strings.Contains(err.Error(), "shift+h is bound to connect and history") {
+		t.Errorf("expected shift+h conflict, got %v", err)
+	}
+
+	if _, err := defaults.Merge(keymap.Keymap{"nope": {"x"}}); err == nil {
+		t.Error("expected unknown action error")
+	}
+	if km, err := keymap.LoadPath(filepath.Join(t.TempDir(), "missing.toml")); err != nil || len(km) != 0 {
+		t.Errorf("missing file: got %v, %v", km, err)
+	}
+}
+
+func TestScreens(t *testing.T) {
+	defaults := keymap.Keymap{
+		"quit":           {"q", "esc"},
+		"masters.back":   {"esc", "q"},
+		"masters.start":  {"s", "enter"},
+		"masters.stop":   {"x"},
+		"health.back":    {"esc", "q"},
+		"health.refresh": {"r"},
+	}
+	// screens share keys with the host list and each other
+	if err := defaults.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keymap.toml")
+	file := `
+health.refresh = ["R"]
+
+[masters]
+stop = ["s"]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	user, err := keymap.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := defaults.Merge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := km.LookupIn("health", "shift+r"); id != "refresh" {
+		t.Errorf("shift+r bound to %q in health, want refresh", id)
+	}
+	if id, ok := km.Lookup("r"); ok {
+		t.Errorf("r bound to %s in the host list", id)
+	}
+	if id, _ := km.Lookup("q"); id != "quit" {
+		t.Errorf("q bound to %q, want quit", id)
+	}
+	err = km.Validate()
+	if err == nil || err.Error() != "keymap: s is bound to masters.start and masters.stop" {
+		t.Errorf("expected s conflict in masters, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("[masters]\nstop = \"x\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keymap.LoadPath(path); err == nil || !strings.Contains(err.Error(), "masters.stop: keys are a list") {
+		t.Errorf("expected list error, got %v", err)
+	}
+}
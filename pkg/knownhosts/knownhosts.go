@@ -0,0 +1,370 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package knownhosts reads and repairs known_hosts files:
+// plain and hashed entries, @cert-authority and @revoked markers.
+// ref: https://man.openbsd.org/sshd.8#SSH_KNOWN_HOSTS_FILE_FORMAT
+package knownhosts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	MarkerCA      = "@cert-authority"
+	MarkerRevoked = "@revoked"
+)
+
+// Default files used by ssh when the config doesn't set them.
+var (
+	DefaultUserFiles   = []string{"~/.ssh/known_hosts", "~/.ssh/known_hosts2"}
+	DefaultGlobalFiles = []string{"/etc/ssh/ssh_known_hosts", "/etc/ssh/ssh_known_hosts2"}
+)
+
+// Entry is a single known_hosts line.
+type Entry struct {
+	File        string
+	Line        int
+	Marker      string
+	Patterns    []string
+	Key         ssh.PublicKey
+	Comment     string
+	Fingerprint string
+}
+
+// Hashed reports whether the host patterns are hashed.
+func (e Entry) Hashed() bool {
+	return len(e.Patterns) == 1 && strings.HasPrefix(e.Patterns[0], "|1|")
+}
+
+// Type returns the key algorithm.
+func (e Entry) Type() string {
+	return e.Key.Type()
+}
+
+// Files returns the known_hosts files ssh reads for h,
+// missing files are kept so callers can create them.
+// `none` reads no file.
+func Files(h sshconf.Host) (user, global []string) {
+	u, g := h.KnownHostsFiles()
+	return fields(u, DefaultUserFiles), fields(g, DefaultGlobalFiles)
+}
+
+// fields splits the files of an option, fallback when it's unset.
+func fields(v string, fallback []string) []string {
+	list := strings.Fields(v)
+	switch {
+	case strings.EqualFold(v, "none"):
+		return nil
+	case len(list) == 0:
+		list = fallback
+	}
+	out := make([]string, 0, len(list))
+	for _, f := range list {
+		out = append(out, sshconf.ExpandPath(f))
+	}
+	return out
+}
+
+// Parse reads every entry of path, a missing file has no entries.
+func Parse(path string) ([]Entry, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		marker, hosts, key, comment, _, err := ssh.ParseKnownHosts([]byte(line))
+		if err != nil {
+			// unknown key types are skipped like ssh does
+			continue
+		}
+		if marker == "revoked" {
+			marker = MarkerRevoked
+		} else if marker == "cert-authority" {
+			marker = MarkerCA
+		}
+		entries = append(entries, Entry{
+			File:        path,
+			Line:        n,
+			Marker:      marker,
+			Patterns:    hosts,
+			Key:         key,
+			Comment:     comment,
+			Fingerprint: ssh.FingerprintSHA256(key),
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// Lookup returns the entries of files matching host on port.
+func Lookup(files []string, host, port string) ([]Entry, error) {
+	var out []Entry
+	for _, f := range files {
+		entries, err := Parse(f)
+		if err != nil {
+			return out, err
+		}
+		for _, e := range entries {
+			if e.Matches(host, port) {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Matches reports whether e applies to host on port,
+// hashed entries are matched through their HMAC-SHA1.
+func (e Entry) Matches(host, port string) bool {
+	name := knownhosts.Normalize(net.JoinHostPort(host, port))
+	var matched bool
+	for _, p := range e.Patterns {
+		if strings.HasPrefix(p, "|1|") {
+			if matchHashed(p, name) {
+				matched = true
+			}
+			continue
+		}
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		ok := matchPattern(strings.ToLower(p), strings.ToLower(name))
+		if ok && negate {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern matches a `host` or `[host]:port` pattern
+// with `*` and `?` wildcards against a normalized name.
+func matchPattern(pattern, name string) bool {
+	pHost, pPort := splitBracket(pattern)
+	nHost, nPort := splitBracket(name)
+	return pPort == nPort && wildcard(pHost, nHost)
+}
+
+func splitBracket(s string) (host, port string) {
+	if i := strings.Index(s, "]:"); strings.HasPrefix(s, "[") && i > 0 {
+		return s[1:i], s[i+2:]
+	}
+	return s, ""
+}
+
+// wildcard matches s against p where `*` matches any run
+// of characters and `?` a single one.
+func wildcard(p, s string) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for i := 0; i <= len(s); i++ {
+				if wildcard(p[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || p[0] != s[0] {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchHashed checks a `|1|salt|hash` pattern against name.
+func matchHashed(pattern, name string) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// ErrProxied is returned by Scan for hosts reached through
+// ProxyJump or ProxyCommand: ssh-keyscan can only dial directly.
+var ErrProxied = errors.New("ssh-keyscan can't reach hosts behind ProxyJump or ProxyCommand")
+
+// Scan returns the host keys h presents now, using ssh-keyscan.
+func Scan(ctx context.Context, h sshconf.Host, timeout time.Duration) ([]ssh.PublicKey, error) {
+	if h.ProxyJump() != "" || h.ProxyCommand() != "" {
+		return nil, fmt.Errorf("%s: %w", h.Name, ErrProxied)
+	}
+	host, port := h.HostName(), h.Port()
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	defer cancel()
+	secs := fmt.Sprintf("%d", max(1, int(timeout.Seconds())))
+	out, err := exec.CommandContext(ctx, "ssh-keyscan", "-T", secs, "-p", port, host).Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("ssh-keyscan %s: %w", host, err)
+	}
+	var keys []ssh.PublicKey
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 3 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(f[1] + " " + f[2]))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("ssh-keyscan %s: no host keys", host)
+	}
+	return keys, nil
+}
+
+// Mismatch returns the plain entries whose key type is presented
+// with a different key: these make ssh refuse the connection.
+func Mismatch(recorded []Entry, presented []ssh.PublicKey) []Entry {
+	byType := map[string][]byte{}
+	for _, k := range presented {
+		byType[k.Type()] = k.Marshal()
+	}
+	var stale []Entry
+	for _, e := range recorded {
+		if e.Marker != "" {
+			continue
+		}
+		want, ok := byType[e.Type()]
+		if ok && !bytes.Equal(want, e.Key.Marshal()) {
+			stale = append(stale, e)
+		}
+	}
+	return stale
+}
+
+// Remove deletes entries from their files, every modified file
+// is first copied to <file>.old like `ssh-keygen -R` does.
+func Remove(entries []Entry) error {
+	lines := map[string]map[int]bool{}
+	for _, e := range entries {
+		if lines[e.File] == nil {
+			lines[e.File] = map[int]bool{}
+		}
+		lines[e.File][e.Line] = true
+	}
+	for file, drop := range lines {
+		if err := rewrite(file, drop, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace removes the stale entries and records the presented keys
+// for host in file, hashed when the stale entries were hashed.
+func Replace(file, host, port string, stale []Entry, presented []ssh.PublicKey) error {
+	drop := map[int]bool{}
+	hashed := false
+	for _, e := range stale {
+		if e.File == file {
+			drop[e.Line] = true
+			hashed = hashed || e.Hashed()
+		}
+	}
+	if err := Remove(otherFiles(stale, file)); err != nil {
+		return err
+	}
+	addr := knownhosts.Normalize(net.JoinHostPort(host, port))
+	if hashed {
+		addr = knownhosts.HashHostname(addr)
+	}
+	var add []string
+	for _, k := range presented {
+		add = append(add, knownhosts.Line([]string{addr}, k))
+	}
+	return rewrite(file, drop, add)
+}
+
+func otherFiles(entries []Entry, file string) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if e.File != file {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// rewrite drops the given line numbers from file, appends add
+// and keeps a backup in <file>.old.
+func rewrite(file string, drop map[int]bool, add []string) error {
+	b, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	mode := os.FileMode(0o600)
+	if fi, err := os.Stat(file); err == nil {
+		mode = fi.Mode().Perm()
+		if err := os.WriteFile(file+".old", b, mode); err != nil {
+			return fmt.Errorf("backup %s: %w", file, err)
+		}
+	}
+	var out bytes.Buffer
+	lines := strings.SplitAfter(string(b), "\n")
+	for i, line := range lines {
+		if drop[i+1] || line == "" {
+			continue
+		}
+		out.WriteString(line)
+	}
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	for _, line := range add {
+		out.WriteString(line + "\n")
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
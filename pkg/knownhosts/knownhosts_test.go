@@ -0,0 +1,126 @@
+package knownhosts_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/knownhosts"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"golang.org/x/crypto/ssh"
+	xknownhosts "golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLookupAndReplace(t *testing.T) {
+	oldKey, newKey_, caKey := newKey(t), newKey(t), newKey(t)
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	lines := []string{
+		"# comment",
+		xknownhosts.Line([]string{"web.example.com"}, oldKey),
+		xknownhosts.Line([]string{xknownhosts.HashHostname("[db.example.com]:2222")}, oldKey),
+		"@cert-authority *.example.com " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(caKey))),
+		xknownhosts.Line([]string{"other.example.org"}, oldKey),
+	}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	web, err := knownhosts.Lookup([]string{file}, "web.example.com", "22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(web) != 2 || web[1].Marker != knownhosts.MarkerCA {
+		t.Fatalf("want plain and CA entries, got %+v", web)
+	}
+	db, _ := knownhosts.Lookup([]string{file}, "db.example.com", "2222")
+	if len(db) != 1 || !db[0].Hashed() {
+		t.Fatalf("want hashed entry, got %+v", db)
+	}
+	if none, _ := knownhosts.Lookup([]string{file}, "db.example.com", "22"); len(none) != 1 {
+		t.Fatalf("port must be part of the match, got %+v", none)
+	}
+
+	stale := knownhosts.Mismatch(db, []ssh.PublicKey{newKey_})
+	if len(stale) != 1 {
+		t.Fatalf("want 1 stale entry, got %d", len(stale))
+	}
+	if err := knownhosts.Replace(file, "db.example.com", "2222", stale, []ssh.PublicKey{newKey_}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file + ".old"); err != nil {
+		t.Fatalf("missing backup: %v", err)
+	}
+	db, _ = knownhosts.Lookup([]string{file}, "db.example.com", "2222")
+	if len(knownhosts.Mismatch(db, []ssh.PublicKey{newKey_})) != 0 || !db[len(db)-1].Hashed() {
+		t.Fatalf("want hashed replacement, got %+v", db)
+	}
+	all, _ := knownhosts.Parse(file)
+	if len(all) != 4 || all[len(all)-1].Line != 5 {
+		t.Fatalf("want 4 entries after replace, got %d", len(all))
+	}
+}
+
+func TestFiles(t *testing.T) {
+	c := sshconftest.Parse(t, `Host none
+  UserKnownHostsFile none
+  GlobalKnownHostsFile none
+Host unset
+  HostName unset.example.com
+Host custom
+  UserKnownHostsFile /tmp/a /tmp/b
+Host inherited
+Match originalhost inherited
+  GlobalKnownHostsFile /tmp/g
+Host inherit*
+  UserKnownHostsFile /tmp/c
+`)
+	if user, global := knownhosts.Files(c.GetHost("none")); len(user) != 0 || len(global) != 0 {
+		t.Errorf("none: %v %v", user, global)
+	}
+	user, global := knownhosts.Files(c.GetHost("unset"))
+	if len(user) != len(knownhosts.DefaultUserFiles) || !slices.Equal(global, knownhosts.DefaultGlobalFiles) {
+		t.Errorf("unset: %v %v", user, global)
+	}
+	if user, _ := knownhosts.Files(c.GetHost("custom")); !slices.Equal(user, []string{"/tmp/a", "/tmp/b"}) {
+		t.Errorf("custom: %v", user)
+	}
+	user, global = knownhosts.Files(c.GetHost("inherited"))
+	if !slices.Equal(user, []string{"/tmp/c"}) || !slices.Equal(global, []string{"/tmp/g"}) {
+		t.Errorf("inherited: %v %v", user, global)
+	}
+}
+
+func TestScanProxied(t *testing.T) {
+	c := sshconftest.Parse(t, `Host jumped
+  ProxyJump bastion
+Host proxied
+Host *
+  ProxyCommand nc %h %p
+`)
+	for _, name := range []string{"jumped", "proxied"} {
+		_, err := knownhosts.Scan(context.Background(), c.GetHost(name), time.Second)
+		if !errors.Is(err, knownhosts.ErrProxied) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package launch opens connections outside of ssm: in tmux
+// windows and panes, or through a terminal command, so ssm stays
+// open as a launcher. A terminal command is an argv of Go templates
+// over the host name and the shell-quoted connector command:
+//
+//	terminal = ["kitty", "@", "launch", "--type=tab", "--title", "{{.Name}}", "sh", "-c", "{{.Command}}"]
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Target is where a connection opens.
+type Target string
+
+const (
+	// Replace hands the terminal to the connection until it ends.
+	Replace    Target = "replace"
+	TmuxWindow Target = "tmux-window"
+	TmuxPane   Target = "tmux-pane"
+	Terminal   Target = "terminal"
+)
+
+// Targets returns the targets in cycle order.
+func Targets() []Target {
+	return []Target{Replace, TmuxWindow, TmuxPane, Terminal}
+}
+
+// Parse returns the target called s.
+func Parse(s string) (Target, error) {
+	t := Target(s)
+	if !slices.Contains(Targets(), t) {
+		return "", fmt.Errorf("unknown launch target %q, want %s", s, strings.Join(names(), "|"))
+	}
+	return t, nil
+}
+
+func names() []string {
+	var out []string
+	for _, t := range Targets() {
+		out = append(out, string(t))
+	}
+	return out
+}
+
+func (t Target) String() string {
+	return string(t)
+}
+
+// Next returns the target after t.
+func (t Target) Next() Target {
+	ts := Targets()
+	return ts[(slices.Index(ts, t)+1)%len(ts)]
+}
+
+// Session is a connection to open.
+type Session struct {
+	Name string
+	Argv []string
+}
+
+// Launcher opens sessions at its target.
+type Launcher struct {
+	Target Target
+	// Terminal is the argv of the terminal target.
+	Terminal []string
+	// Sync types in every pane of a tiled window at once.
+	Sync bool
+	// Run runs a command returning its output, tmux by default.
+	Run func(argv ...string) (string, error)
+}
+
+// ErrNoTmux is returned by tmux targets outside of tmux.
+var ErrNoTmux = errors.New("tmux targets need ssm running inside tmux")
+
+// InTmux reports whether ssm runs inside tmux.
+func InTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+func (l *Launcher) run(argv ...string) (string, error) {
+	if l.Run != nil {
+		return l.Run(argv...)
+	}
+	out, err := exec.Command(argv[0], argv[1:]...).Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+		err = fmt.Errorf("%s: %s", argv[0], strings.TrimSpace(string(exit.Stderr)))
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// Open starts s at the target, Replace is up to the caller.
+func (l *Launcher) Open(s Session) error {
+	switch l.Target {
+	case TmuxWindow, TmuxPane:
+		if !InTmux() {
+			return ErrNoTmux
+		}
+		argv := []string{"tmux", "new-window", "-n", s.Name, "--"}
+		if l.Target == TmuxPane {
+			argv = []string{"tmux", "split-window", "--"}
+		}
+		_, err := l.run(append(argv, s.Argv...)...)
+		return err
+	case Terminal:
+		argv, err := TerminalArgv(l.Terminal, s)
+		if err != nil {
+			return err
+		}
+		// the terminal outlives ssm, it isn't waited for
+		cmd := exec.Command(argv[0], argv[1:]...)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("launch: terminal: %w", err)
+		}
+		go cmd.Wait() //nolint:errcheck
+		return nil
+	}
+	return fmt.Errorf("launch: %s opens in place", l.Target)
+}
+
+// Tile opens ss as the panes of one tiled tmux window. Outside of
+// tmux the window is in a new detached session, attach is the
+// command attaching to it.
+func (l *Launcher) Tile(ss []Session) (attach []string, err error) {
+	if len(ss) == 0 {
+		return nil, errors.New("launch: no sessions")
+	}
+	first := []string{"tmux", "new-window", "-P", "-F", "#{window_id}", "-n", "ssm"}
+	session := ""
+	if !InTmux() {
+		// tmux reserves . and : in session names
+		session = "ssm-" + strings.NewReplacer(".", "_", ":", "_").Replace(ss[0].Name)
+		first = []string{"tmux", "new-session", "-d", "-P", "-F", "#{window_id}", "-s", session, "-n", "ssm"}
+	}
+	window, err := l.run(append(append(first, "--"), ss[0].Argv...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ss[1:] {
+		if _, err := l.run(append([]string{"tmux", "split-window", "-t", window, "--"}, s.Argv...)...); err != nil {
+			return nil, err
+		}
+		// re-tiling after every split leaves room for the next one
+		if _, err := l.run("tmux", "select-layout", "-t", window, "tiled"); err != nil {
+			return nil, err
+		}
+	}
+	if l.Sync {
+		if _, err := l.run("tmux", "set-window-option", "-t", window, "synchronize-panes", "on"); err != nil {
+			return nil, err
+		}
+	}
+	if session != "" {
+		return []string{"tmux", "attach-session", "-t", session}, nil
+	}
+	return nil, nil
+}
+
+// TerminalArgv renders the terminal command opening s.
+func TerminalArgv(terminal []string, s Session) ([]string, error) {
+	if len(terminal) == 0 {
+		return nil, errors.New("launch: terminal target needs a terminal command in config.toml")
+	}
+	data := struct{ Name, Command string }{s.Name, Quote(s.Argv)}
+	var argv []string
+	for _, arg := range terminal {
+		t, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("launch: terminal: %w", err)
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("launch: terminal: %w", err)
+		}
+		if b.Len() > 0 {
+			argv = append(argv, b.String())
+		}
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("launch: terminal: empty command")
+	}
+	return argv, nil
+}
+
+// Quote joins argv into a POSIX shell command.
+func Quote(argv []string) string {
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+			quoted = append(quoted, arg)
+			continue
+		}
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
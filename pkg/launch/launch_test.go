@@ -0,0 +1,84 @@
+package launch_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/launch"
+)
+
+func TestTile(t *testing.T) {
+	for _, inTmux := range []bool{true, false} {
+		if inTmux {
+			t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+		} else {
+			t.Setenv("TMUX", "")
+		}
+		var calls []string
+		l := launch.Launcher{
+			Sync: true,
+			Run: func(argv ...string) (string, error) {
+				calls = append(calls, strings.Join(argv, " "))
+				return "@7", nil
+			},
+		}
+		attach, err := l.Tile([]launch.Session{
+			{Name: "web1", Argv: []string{"ssh", "web1"}},
+			{Name: "web2", Argv: []string{"ssh", "web2"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := "tmux new-window -P -F #{window_id} -n ssm -- ssh web1"
+		if !inTmux {
+			first = "tmux new-session -d -P -F #{window_id} -s ssm-web1 -n ssm -- ssh web1"
+		}
+		want := []string{
+			first,
+			"tmux split-window -t @7 -- ssh web2",
+			"tmux select-layout -t @7 tiled",
+			"tmux set-window-option -t @7 synchronize-panes on",
+		}
+		if !slices.Equal(calls, want) {
+			t.Errorf("in tmux %v: got\n%s\nwant\n%s", inTmux, strings.Join(calls, "\n"), strings.Join(want, "\n"))
+		}
+		if inTmux != (attach == nil) {
+			t.Errorf("in tmux %v: attach %q", inTmux, attach)
+		}
+	}
+}
+
+func TestOpenOutsideTmux(t *testing.T) {
+	t.Setenv("TMUX", "")
+	l := launch.Launcher{Target: launch.TmuxWindow}
+	if err := l.Open(launch.Session{Name: "web1", Argv: []string{"ssh", "web1"}}); err != launch.ErrNoTmux {
+		t.Errorf("got %v, want ErrNoTmux", err)
+	}
+}
+
+func TestTerminalArgv(t *testing.T) {
+	s := launch.Session{Name: "db1", Argv: []string{"ssh", "db1", "-F", "/home/me/my config"}}
+	got, err := launch.TerminalArgv([]string{"alacritty", "--title", "{{.Name}}", "-e", "sh", "-c", "{{.Command}}"}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"alacritty", "--title", "db1", "-e", "sh", "-c", "ssh db1 -F '/home/me/my config'"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := launch.TerminalArgv(nil, s); err == nil {
+		t.Error("expected error without terminal command")
+	}
+	if _, err := launch.Parse("tmux"); err == nil {
+		t.Error("expected unknown target error")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	got := launch.Quote([]string{"mosh", "--ssh=ssh -F /cfg", "it's", ""})
+	want := `mosh '--ssh=ssh -F /cfg' 'it'\''s' ''`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package liveness
+
+import (
+	"context"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshexec"
+)
+
+// CheckAuth verifies non-interactive authentication to host
+// running `true` with sshexec.Batch.
+func CheckAuth(ctx context.Context, configPath, host string, timeout time.Duration) error {
+	_, err := sshexec.Batch(ctx, configPath, host, "true", timeout)
+	return err
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package liveness checks whether hosts accept ssh connections
+// by reading their identification banner.
+package liveness
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+// Status is the liveness of a host.
+type Status int
+
+const (
+	Unknown Status = iota
+	Up
+	Down
+)
+
+func (s Status) String() string {
+	switch s {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Host string
+	Addr string
+	Jump string
+	// Proxy is the ProxyCommand run, tokens expanded.
+	Proxy  string
+	Status Status
+	// Connected reports the dial or the tunnel succeeded,
+	// Unknown results without it weren't checked at all.
+	Connected bool
+	Latency   time.Duration
+	Banner    string
+	Err       error
+	Checked   time.Time
+}
+
+// JumpFunc opens a stream to addr through the jump host.
+type JumpFunc func(ctx context.Context, jump, addr string) (io.ReadCloser, error)
+
+// ProxyFunc opens a stream running a ProxyCommand.
+type ProxyFunc func(ctx context.Context, command string) (io.ReadCloser, error)
+
+// Checker checks hosts concurrently.
+type Checker struct {
+	// Timeout bounds every check, defaults to 5s.
+	Timeout time.Duration
+	// Workers bounds concurrent checks, defaults to 16.
+	Workers int
+	// Jump is used for hosts with a ProxyJump, defaults to `ssh -W`.
+	Jump JumpFunc
+	// Proxy is used for hosts with a ProxyCommand, defaults to sh.
+	Proxy ProxyFunc
+}
+
+// New returns a Checker tunneling jumps through `ssh -F configPath -W`.
+func New(configPath string) *Checker {
+	return &Checker{
+		Timeout: 5 * time.Second,
+		Workers: 16,
+		Jump:    SSHJump(configPath),
+		Proxy:   ShellProxy,
+	}
+}
+
+// CheckAll checks every host, results keep the hosts order.
+func (c *Checker) CheckAll(ctx context.Context, hosts []sshconf.Host) []Result {
+	workers := c.Workers
+	if workers <= 0 {
+		workers = 16
+	}
+	results := make([]Result, len(hosts))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.Check(ctx, h)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Check dials the effective HostName and Port of h, through its
+// ProxyJump or ProxyCommand when set, and reads the ssh banner.
+func (c *Checker) Check(ctx context.Context, h sshconf.Host) Result {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res := Result{
+		Host:    h.Name,
+		Addr:    net.JoinHostPort(h.HostName(), h.Port()),
+		Jump:    h.ProxyJump(),
+		Checked: time.Now(),
+	}
+	if strings.Contains(res.Addr, "%") {
+		res.Err = fmt.Errorf("unsupported token in %s", res.Addr)
+		return res
+	}
+	if proxy := h.ProxyCommand(); proxy != "" && c.Proxy != nil {
+		var err error
+		if res.Proxy, err = expandTokens(proxy, h); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+	tunnel := res.Jump != "" || res.Proxy != ""
+
+	start := time.Now()
+	var conn io.ReadCloser
+	var err error
+	switch {
+	case res.Jump != "" && c.Jump != nil:
+		conn, err = c.Jump(ctx, res.Jump, res.Addr)
+	case res.Proxy != "":
+		conn, err = c.Proxy(ctx, res.Proxy)
+	default:
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", res.Addr)
+	}
+	if err != nil {
+		res.Status = Down
+		res.Err = err
+		return res
+	}
+	defer conn.Close()
+	res.Connected = true
+	if !tunnel {
+		res.Latency = time.Since(start)
+	}
+
+	// unblock the read on timeout
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	banner, err := ReadBanner(conn)
+	if tunnel {
+		res.Latency = time.Since(start)
+	}
+	if err != nil {
+		res.Err = err
+		if tunnel {
+			// nothing came through the tunnel
+			res.Status = Down
+		}
+		return res
+	}
+	res.Status = Up
+	res.Banner = banner
+	return res
+}
+
+// ReadBanner returns the ssh identification string sent by servers,
+// lines preceding it are allowed by RFC 4253.
+func ReadBanner(r io.Reader) (string, error) {
+	br := bufio.NewReaderSize(r, 256)
+	for range 10 {
+		line, err := br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("no ssh banner")
+			}
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no ssh banner")
+}
+
+// SSHJump tunnels through jump with `ssh -W`, authentication
+// must not be interactive.
+func SSHJump(configPath string) JumpFunc {
+	return func(ctx context.Context, jump, addr string) (io.ReadCloser, error) {
+		args := []string{"-o", "BatchMode=yes", "-W", addr}
+		if configPath != "" {
+			args = append([]string{"-F", configPath}, args...)
+		}
+		// the last hop is the destination of -W, the rest are jumps
+		hops := strings.Split(jump, ",")
+		last := hops[len(hops)-1]
+		if len(hops) > 1 {
+			args = append(args, "-J", strings.Join(hops[:len(hops)-1], ","))
+		}
+		if strings.Contains(last, ":") && !strings.HasPrefix(last, "ssh://") {
+			// host:port is only valid as an URI destination
+			last = "ssh://" + last
+		}
+		args = append(args, last)
+		cmd := exec.CommandContext(ctx, "ssh", args...)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &cmdReader{ReadCloser: out, cmd: cmd}, nil
+	}
+}
+
+// ShellProxy runs command like ssh runs a ProxyCommand, its stdin
+// stays open until the stream is closed.
+func ShellProxy(ctx context.Context, command string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", "exec "+command)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &cmdReader{ReadCloser: out, cmd: cmd, stdin: in}, nil
+}
+
+// expandTokens expands the ProxyCommand tokens of h: %h, %p,
+// %r, %n and %%, others are unsupported.
+func expandTokens(command string, h sshconf.Host) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' {
+			b.WriteByte(command[i])
+			continue
+		}
+		if i++; i == len(command) {
+			return "", fmt.Errorf("unterminated token in %s", command)
+		}
+		switch command[i] {
+		case 'h':
+			b.WriteString(h.HostName())
+		case 'p':
+			b.WriteString(h.Port())
+		case 'r':
+			b.WriteString(h.User())
+		case 'n':
+			b.WriteString(h.Name)
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unsupported token %%%c in %s", command[i], command)
+		}
+	}
+	return b.String(), nil
+}
+
+type cmdReader struct {
+	io.ReadCloser
+	cmd   *exec.Cmd
+	stdin io.Closer
+}
+
+func (r *cmdReader) Close() error {
+	if r.stdin != nil {
+		_ = r.stdin.Close()
+	}
+	_ = r.ReadCloser.Close()
+	if r.cmd.Process != nil {
+		_ = r.cmd.Process.Kill()
+	}
+	return r.cmd.Wait()
+}
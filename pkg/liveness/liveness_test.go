@@ -0,0 +1,137 @@
+package liveness_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/liveness"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	som "github.com/thalesfsp/go-common-types/safeorderedmap"
+)
+
+// listen serves banner to every connection.
+func listen(t *testing.T, banner string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(c, banner)
+			c.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func host(name, addr, jump string) sshconf.Host {
+	h := sshconf.Host{Name: name, Options: som.New[string]()}
+	ip, port, _ := net.SplitHostPort(addr)
+	h.Options.Add("hostname", ip)
+	h.Options.Add("port", port)
+	if jump != "" {
+		h.Options.Add("proxyjump", jump)
+	}
+	return h
+}
+
+func TestCheckAll(t *testing.T) {
+	up := listen(t, "welcome\r\nSSH-2.0-OpenSSH_9.6\r\n")
+	http := listen(t, "HTTP/1.1 400 Bad Request\r\n")
+	down := closedAddr(t)
+
+	var jumped []string
+	c := &liveness.Checker{
+		Timeout: time.Second,
+		Jump: func(ctx context.Context, jump, addr string) (io.ReadCloser, error) {
+			jumped = append(jumped, jump+">"+addr)
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", up)
+		},
+	}
+	hosts := []sshconf.Host{
+		host("up", up, ""),
+		host("http", http, ""),
+		host("down", down, ""),
+		host("behind", "10.0.0.1:22", "bastion"),
+	}
+	results := c.CheckAll(context.Background(), hosts)
+	want := []liveness.Status{liveness.Up, liveness.Unknown, liveness.Down, liveness.Up}
+	for i, r := range results {
+		if r.Host != hosts[i].Name || r.Status != want[i] {
+			t.Errorf("%s: got %v (%v), want %v", hosts[i].Name, r.Status, r.Err, want[i])
+		}
+	}
+	if !strings.HasPrefix(results[0].Banner, "SSH-2.0-") {
+		t.Errorf("unexpected banner %q", results[0].Banner)
+	}
+	if len(jumped) != 1 || jumped[0] != "bastion>10.0.0.1:22" {
+		t.Errorf("unexpected jumps %v", jumped)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// accepts but never writes a banner
+	go func() {
+		c, err := l.Accept()
+		if err == nil {
+			defer c.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+	c := &liveness.Checker{Timeout: 200 * time.Millisecond}
+	start := time.Now()
+	r := c.Check(context.Background(), host("slow", l.Addr().String(), ""))
+	if r.Status != liveness.Unknown || time.Since(start) > time.Second {
+		t.Fatalf("got %v after %v", r.Status, time.Since(start))
+	}
+}
+
+func TestProxyCommand(t *testing.T) {
+	cfg := sshconftest.Parse(t, `
+Host proxied
+    HostName 10.255.0.1
+    Port 2222
+    ProxyCommand printf 'SSH-2.0-%h:%p\r\n'
+
+Host token
+    ProxyCommand nc %C
+`)
+	c := &liveness.Checker{Timeout: time.Second, Proxy: liveness.ShellProxy}
+	r := c.Check(context.Background(), cfg.GetHost("proxied"))
+	if r.Status != liveness.Up || r.Banner != "SSH-2.0-10.255.0.1:2222" {
+		t.Errorf("proxied: %v %q %v", r.Status, r.Banner, r.Err)
+	}
+	r = c.Check(context.Background(), cfg.GetHost("token"))
+	if r.Status != liveness.Unknown || r.Err == nil {
+		t.Errorf("token: %v %v", r.Status, r.Err)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package mux manages the ControlMaster connections of hosts:
+// it resolves their ControlPath with `ssh -G`, checks, starts
+// and stops masters with `ssh -O` and `ssh -fNM`.
+package mux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshexec"
+)
+
+// DefaultTimeout bounds starting a master, which connects.
+const DefaultTimeout = 15 * time.Second
+
+// workers bounds the ssh processes run in parallel.
+const workers = 8
+
+// Master is the ControlMaster setup of a host.
+type Master struct {
+	Host string
+	// Path is the expanded ControlPath, empty when the host
+	// isn't multiplexed.
+	Path string
+	// Mode is the ControlMaster option: auto, yes, no...
+	Mode string
+	// Persist is the ControlPersist option, in seconds or no.
+	Persist string
+}
+
+// Multiplexed reports whether connections to the host can share a master.
+func (m Master) Multiplexed() bool {
+	return m.Path != ""
+}
+
+// Status is the state of the master of a host.
+type Status struct {
+	Master
+	Alive bool
+	PID   int
+	Err   error
+}
+
+// Resolve reads the ControlMaster options of host from `ssh -G`.
+func Resolve(ctx context.Context, configPath, host string) (Master, error) {
+	args := []string{"-G"}
+	if configPath != "" {
+		args = append(args, "-F", configPath)
+	}
+	out, err := exec.CommandContext(ctx, "ssh", append(args, host)...).Output()
+	if err != nil {
+		return Master{}, fmt.Errorf("ssh -G %s: %w", host, err)
+	}
+	return parse(host, out), nil
+}
+
+func parse(host string, out []byte) Master {
+	m := Master{Host: host}
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		k, v, _ := strings.Cut(scanner.Text(), " ")
+		values[strings.ToLower(k)] = v
+	}
+	m.Mode = values["controlmaster"]
+	m.Persist = values["controlpersist"]
+	if p := values["controlpath"]; p != "" && p != "none" {
+		m.Path = expand(p, values)
+	}
+	return m
+}
+
+// expand resolves ~ and the tokens old ssh versions leave
+// in the ControlPath printed by -G.
+func expand(path string, values map[string]string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if !strings.Contains(path, "%") {
+		return path
+	}
+	return strings.NewReplacer(
+		"%%", "%",
+		"%h", values["hostname"],
+		"%p", values["port"],
+		"%r", values["user"],
+		"%n", values["host"],
+	).Replace(path)
+}
+
+// masterRunning matches `Master running (pid=1234)`.
+var masterRunning = regexp.MustCompile(`pid=(\d+)`)
+
+// Check reports whether the master of m is alive, hosts without
+// a socket are not checked.
+func Check(ctx context.Context, configPath string, m Master) Status {
+	s := Status{Master: m}
+	if !m.Multiplexed() {
+		return s
+	}
+	if _, err := os.Stat(m.Path); err != nil {
+		return s
+	}
+	out, err := control(ctx, configPath, m.Host, "check")
+	if err != nil {
+		// a stale socket: the master died
+		return s
+	}
+	s.Alive = true
+	if match := masterRunning.FindStringSubmatch(out); match != nil {
+		s.PID, _ = strconv.Atoi(match[1])
+	}
+	return s
+}
+
+// CheckAll resolves and checks the masters of hosts in parallel,
+// statuses are in the order of hosts.
+func CheckAll(ctx context.Context, configPath string, hosts []string) []Status {
+	statuses := make([]Status, len(hosts))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m, err := Resolve(ctx, configPath, h)
+			if err != nil {
+				statuses[i] = Status{Master: Master{Host: h}, Err: err}
+				return
+			}
+			statuses[i] = Check(ctx, configPath, m)
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+// Start opens a master for m in the background with
+// `ssh -fNM` in BatchMode: it can't prompt. A live master
+// is kept, ssh would open a connection not multiplexed.
+func Start(ctx context.Context, configPath string, m Master, timeout time.Duration) error {
+	if !m.Multiplexed() {
+		return fmt.Errorf("%s: no ControlPath set", m.Host)
+	}
+	if Check(ctx, configPath, m).Alive {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ssh", sshexec.Args(configPath, timeout, "-fNM", m.Host)...)
+	// the forked master keeps stderr open: a pipe would
+	// never reach EOF, a file doesn't wait for it.
+	stderr, err := os.CreateTemp("", "ssm-mux-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: timed out after %v", m.Host, timeout)
+	}
+	if err != nil {
+		out, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s: %w", m.Host, sshexec.Err(out, err))
+	}
+	return nil
+}
+
+// StartAll resolves hosts and starts their masters in parallel,
+// hosts without a ControlPath are skipped. Statuses are checked
+// after starting, in the order of hosts; errors are joined.
+func StartAll(ctx context.Context, configPath string, hosts []string, timeout time.Duration) ([]Status, error) {
+	statuses := make([]Status, len(hosts))
+	errs := make([]error, len(hosts))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m, err := Resolve(ctx, configPath, h)
+			if err != nil {
+				statuses[i] = Status{Master: Master{Host: h}, Err: err}
+				errs[i] = err
+				return
+			}
+			if m.Multiplexed() {
+				errs[i] = Start(ctx, configPath, m, timeout)
+			}
+			statuses[i] = Check(ctx, configPath, m)
+			statuses[i].Err = errs[i]
+		}()
+	}
+	wg.Wait()
+	return statuses, errors.Join(errs...)
+}
+
+// Stop closes the master of host with `ssh -O exit`.
+func Stop(ctx context.Context, configPath, host string) error {
+	_, err := control(ctx, configPath, host, "exit")
+	return err
+}
+
+// StopAll stops the masters of hosts, the errors are joined.
+func StopAll(ctx context.Context, configPath string, hosts []string) error {
+	var errs []error
+	for _, h := range hosts {
+		errs = append(errs, Stop(ctx, configPath, h))
+	}
+	return errors.Join(errs...)
+}
+
+// control sends command to the master of host.
+func control(ctx context.Context, configPath, host, command string) (string, error) {
+	_, stderr, err := sshexec.Run(ctx, configPath, 0, "-O", command, host)
+	out := strings.TrimSpace(string(stderr))
+	if err != nil {
+		return out, fmt.Errorf("%s: %w", host, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,85 @@
+package mux_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/mux"
+)
+
+func writeConfig(t *testing.T) (config, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh not installed")
+	}
+	dir = t.TempDir()
+	config = filepath.Join(dir, "config")
+	body := "Host cm\n" +
+		"  HostName 127.0.0.1\n" +
+		"  User deploy\n" +
+		"  Port 2299\n" +
+		"  ControlMaster auto\n" +
+		"  ControlPath " + dir + "/%r@%h:%p\n" +
+		"  ControlPersist 10m\n" +
+		"Host plain\n" +
+		"  HostName 127.0.0.1\n"
+	if err := os.WriteFile(config, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return config, dir
+}
+
+func TestResolve(t *testing.T) {
+	config, dir := writeConfig(t)
+	ctx := context.Background()
+
+	m, err := mux.Resolve(ctx, config, "cm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "deploy@127.0.0.1:2299")
+	if !m.Multiplexed() || m.Path != want || m.Mode != "auto" || m.Persist != "600" {
+		t.Errorf("cm: %+v, want path %s", m, want)
+	}
+
+	plain, err := mux.Resolve(ctx, config, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Multiplexed() {
+		t.Errorf("plain: %+v", plain)
+	}
+}
+
+func TestNoMaster(t *testing.T) {
+	config, _ := writeConfig(t)
+	ctx := context.Background()
+
+	statuses := mux.CheckAll(ctx, config, []string{"cm", "plain"})
+	if len(statuses) != 2 || statuses[0].Host != "cm" || statuses[1].Host != "plain" {
+		t.Fatalf("statuses: %+v", statuses)
+	}
+	for _, s := range statuses {
+		if s.Alive || s.Err != nil {
+			t.Errorf("%s: %+v", s.Host, s)
+		}
+	}
+
+	plain := statuses[1].Master
+	err := mux.Start(ctx, config, plain, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "no ControlPath") {
+		t.Errorf("start plain: %v", err)
+	}
+	// skipped by StartAll
+	if _, err := mux.StartAll(ctx, config, []string{"plain"}, time.Second); err != nil {
+		t.Errorf("start all: %v", err)
+	}
+	if err := mux.Stop(ctx, config, "cm"); err == nil {
+		t.Error("stopped a master not running")
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package prefs loads the persistent preferences of config.toml,
+// they're defaults: flags and environment variables override them.
+//
+//	connector = "mosh"
+//	sort = "frecency"
+//	theme = "sky"
+//	editor = "nvim"
+//	ping-interval = "1m"
+//	debug-log = "~/.local/state/ssm/debug.log"
+//	launch = "tmux-window"
+//	terminal = ["alacritty", "--title", "{{.Name}}", "-e", "sh", "-c", "{{.Command}}"]
+//	synchronize-panes = true
+//
+//	[filter]
+//	start = true
+//	query = "-tag:legacy"
+//
+//	[record]
+//	tags = ["prod"]
+//
+//	[hooks.tags.office]
+//	pre = ["wg-quick up office"]
+//
+//	[facts]
+//	ttl = "12h"
+//	background = true
+//
+//	[health]
+//	interval = "1m"
+//
+//	[[health.probes]]
+//	name = "nginx"
+//	command = "pgrep -c nginx"
+//
+//	[[connectors]]
+//	name = "ssh-tmux"
+//	command = ["ssh", "-t", "-F", "{{.Config}}", "{{.Name}}", "tmux new -A -s ssm"]
+package prefs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/facts"
+	"github.com/lfaoro/ssm/pkg/health"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const fileName = "config.toml"
+
+// Prefs are the settings of config.toml.
+type Prefs struct {
+	Connector    string        `toml:"connector"`
+	Sort         string        `toml:"sort"`
+	Theme        string        `toml:"theme"`
+	SSHConfig    string        `toml:"ssh-config"`
+	Show         bool          `toml:"show"`
+	Exit         bool          `toml:"exit"`
+	Order        bool          `toml:"order"`
+	Ping         bool          `toml:"ping"`
+	PingInterval time.Duration `toml:"ping-interval"`
+	Editor       string        `toml:"editor"`
+	Debug        bool          `toml:"debug"`
+	DebugLog     string        `toml:"debug-log"`
+	Filter       Filter        `toml:"filter"`
+	// Launch is where connections open, Terminal the command of
+	// the terminal target and SyncPanes synchronizes tiled panes.
+	Launch    string   `toml:"launch"`
+	Terminal  []string `toml:"terminal"`
+	SyncPanes bool     `toml:"synchronize-panes"`
+	// Record picks the hosts whose sessions are recorded.
+	Record recording.Policy `toml:"record"`
+	// Hooks run before and after connecting.
+	Hooks hook.Config `toml:"hooks"`
+	// Facts configures the cache of host facts.
+	Facts facts.Config `toml:"facts"`
+	// Health configures the probes of the health dashboard.
+	Health health.Config `toml:"health"`
+	// Connectors add to the built-in connectors or replace them.
+	Connectors []connector.Connector `toml:"connectors"`
+
+	path string
+	// values holds the defined keys, dotted for tables.
+	values map[string]string
+}
+
+// Filter is the filter behaviour at startup.
+type Filter struct {
+	// Start focuses the filter input.
+	Start bool `toml:"start"`
+	// Query is applied when no query is given on the command line.
+	Query string `toml:"query"`
+}
+
+// Path returns $XDG_CONFIG_HOME/ssm/config.toml.
+func Path() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the preferences from Path, a missing file has none.
+func Load() (*Prefs, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(path)
+}
+
+// LoadPath reads the preferences of path, a missing file has none.
+func LoadPath(path string) (*Prefs, error) {
+	p := &Prefs{path: path, values: map[string]string{}}
+	raw := map[string]any{}
+	_, err := toml.DecodeFile(path, &raw)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	md, err := toml.DecodeFile(path, p)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("config: %s: unknown key %s", path, undecoded[0])
+	}
+	flatten("", raw, p.values)
+	// paths may start with ~/
+	for key, field := range map[string]*string{
+		"ssh-config": &p.SSHConfig,
+		"debug-log":  &p.DebugLog,
+	} {
+		if v, ok := p.values[key]; ok {
+			p.values[key] = expandHome(v)
+			*field = p.values[key]
+		}
+	}
+	return p, nil
+}
+
+func flatten(prefix string, raw map[string]any, out map[string]string) {
+	for k, v := range raw {
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(prefix+k+".", v, out)
+			continue
+		case []map[string]any:
+			// arrays of tables aren't settings
+			continue
+		}
+		out[prefix+k] = fmt.Sprint(v)
+	}
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// File returns the path the preferences were read from.
+func (p *Prefs) File() string {
+	return p.path
+}
+
+// Lookup returns the value of key as written in the file,
+// keys of tables are dotted: `filter.start`.
+func (p *Prefs) Lookup(key string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	v, ok := p.values[key]
+	return v, ok
+}
+
+// Keys returns the defined keys sorted.
+func (p *Prefs) Keys() []string {
+	keys := make([]string, 0, len(p.values))
+	for k := range p.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
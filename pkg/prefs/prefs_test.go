@@ -0,0 +1,98 @@
+package prefs_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/prefs"
+)
+
+func TestLoadPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	file := `
+connector = "mosh"
+sort = "frecency"
+show = true
+ping-interval = "1m"
+debug-log = "~/ssm.log"
+
+[filter]
+start = true
+query = "-tag:legacy"
+
+[hooks.hosts.nas]
+pre = ["wakeonlan 00:11:22:33:44:55"]
+
+[health]
+interval = "2m"
+
+[[health.probes]]
+name = "nginx"
+command = "pgrep -c nginx"
+
+[[connectors]]
+name = "ssh-tmux"
+command = ["ssh", "-t", "{{.Name}}", "tmux new -A"]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := prefs.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Connector != "mosh" || !p.Show || p.PingInterval != time.Minute || !p.Filter.Start {
+		t.Errorf("decoded: %+v", p)
+	}
+	for key, want := range map[string]string{
+		"sort":          "frecency",
+		"show":          "true",
+		"ping-interval": "1m",
+		"filter.query":  "-tag:legacy",
+	} {
+		if got, ok := p.Lookup(key); !ok || got != want {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", key, got, ok, want)
+		}
+	}
+	if len(p.Connectors) != 1 || p.Connectors[0].Name != "ssh-tmux" {
+		t.Errorf("connectors: %+v", p.Connectors)
+	}
+	if pre := p.Hooks.Hosts["nas"].Pre; len(pre) != 1 {
+		t.Errorf("hooks: %+v", p.Hooks)
+	}
+	if p.Health.Interval != 2*time.Minute || len(p.Health.Probes) != 1 {
+		t.Errorf("health: %+v", p.Health)
+	}
+	if _, ok := p.Lookup("theme"); ok {
+		t.Error("theme is not defined")
+	}
+	if v, _ := p.Lookup("debug-log"); strings.HasPrefix(v, "~") || !strings.HasSuffix(v, "ssm.log") {
+		t.Errorf("debug-log not expanded: %s", v)
+	}
+	if got := strings.Join(p.Keys(), ","); got != "connector,debug-log,filter.query,filter.start,health.interval,hooks.hosts.nas.pre,ping-interval,show,sort" {
+		t.Errorf("keys: %s", got)
+	}
+}
+
+func TestLoadPathErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown": "colour = \"red\"\n",
+		"type":    "show = \"yes\"\n",
+		"syntax":  "sort = \n",
+	} {
+		path := filepath.Join(t.TempDir(), name+".toml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := prefs.LoadPath(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	p, err := prefs.LoadPath(filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil || len(p.Keys()) != 0 {
+		t.Errorf("missing file: %v, %v", p, err)
+	}
+}
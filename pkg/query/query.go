@@ -0,0 +1,319 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package query parses and evaluates host filter expressions.
+//
+// A query is a list of terms combined with AND, alternatives
+// are separated by `|` or `OR`:
+//
+//	web user:root -tag:legacy | host:*.eu port:2222
+//
+// Terms without a field are fuzzy matched against the host,
+// terms with a field match that field only:
+// tag and port match exactly, every other field matches a substring,
+// `*` and `?` globs work in field terms only.
+// A leading `-` negates the term.
+package query
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/sahilm/fuzzy"
+)
+
+// Known fields, records may carry more.
+const (
+	FieldHost = "host"
+	FieldUser = "user"
+	FieldTag  = "tag"
+	FieldPort = "port"
+	FieldJump = "jump"
+)
+
+// exact lists the fields matched exactly instead of by substring.
+var exact = map[string]bool{
+	FieldTag:  true,
+	FieldPort: true,
+}
+
+// Term is a single query condition.
+type Term struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// Query is an OR of AND-ed term groups.
+type Query struct {
+	alts [][]Term
+}
+
+// Record is what a query is evaluated against.
+// Name is the Host alias and the only highlighted value.
+type Record struct {
+	Name   string
+	Fields map[string][]string
+}
+
+// Match describes a successful evaluation.
+type Match struct {
+	Score int
+	// Indexes are the matched rune positions in Record.Name.
+	Indexes []int
+}
+
+// Result is a match for the record at Index.
+type Result struct {
+	Index int
+	Match
+}
+
+// Parse parses s into a Query, it never fails:
+// anything that isn't a field term is free text.
+func Parse(s string) Query {
+	var q Query
+	var group []Term
+	for _, tok := range tokenize(s) {
+		if tok == "|" || tok == "OR" {
+			if len(group) > 0 {
+				q.alts = append(q.alts, group)
+			}
+			group = nil
+			continue
+		}
+		group = append(group, parseTerm(tok))
+	}
+	if len(group) > 0 {
+		q.alts = append(q.alts, group)
+	}
+	return q
+}
+
+// Empty reports whether the query has no terms.
+func (q Query) Empty() bool {
+	return len(q.alts) == 0
+}
+
+// Match evaluates the query against r,
+// the best scoring alternative wins.
+func (q Query) Match(r Record) (Match, bool) {
+	if q.Empty() {
+		return Match{}, true
+	}
+	var best Match
+	var found bool
+	for _, group := range q.alts {
+		m, ok := matchGroup(group, r)
+		if !ok {
+			continue
+		}
+		if !found || m.Score > best.Score {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Filter evaluates the query against every record and
+// returns the matches sorted by descending score.
+func (q Query) Filter(records []Record) []Result {
+	var results []Result
+	for i, r := range records {
+		m, ok := q.Match(r)
+		if !ok {
+			continue
+		}
+		results = append(results, Result{Index: i, Match: m})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// FromHost builds the searchable record of an ssh host.
+func FromHost(h sshconf.Host) Record {
+	return Record{
+		Name: h.Name,
+		Fields: map[string][]string{
+			FieldHost: {h.Name, h.HostName()},
+			FieldUser: {h.User()},
+			FieldTag:  h.Tags(),
+			FieldPort: {h.Port()},
+			FieldJump: {h.ProxyJump()},
+		},
+	}
+}
+
+// FromTagArg converts the legacy `ssm tag1,tag2` argument into a query,
+// arguments already using the query syntax are returned as is.
+func FromTagArg(arg string) string {
+	arg = strings.TrimSpace(arg)
+	if strings.ContainsAny(arg, ": |\t") {
+		return arg
+	}
+	var alts []string
+	for _, t := range strings.Split(arg, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "#")
+		if t != "" {
+			alts = append(alts, FieldTag+":"+t)
+		}
+	}
+	return strings.Join(alts, " | ")
+}
+
+func matchGroup(group []Term, r Record) (Match, bool) {
+	var out Match
+	for _, t := range group {
+		score, idx, ok := matchTerm(t, r)
+		if t.Negate {
+			if ok {
+				return Match{}, false
+			}
+			continue
+		}
+		if !ok {
+			return Match{}, false
+		}
+		out.Score += score
+		out.Indexes = mergeIndexes(out.Indexes, idx)
+	}
+	return out, true
+}
+
+// matchTerm reports whether t matches r, with its score
+// and the matched positions in r.Name.
+func matchTerm(t Term, r Record) (int, []int, bool) {
+	if t.Field == "" {
+		return matchText(t.Value, r)
+	}
+	values, ok := r.Fields[t.Field]
+	if !ok {
+		return 0, nil, false
+	}
+	want := strings.ToLower(t.Value)
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		lv := strings.ToLower(v)
+		switch {
+		case want == "":
+			return 1, nil, true
+		case strings.ContainsAny(want, "*?["):
+			if ok, _ := path.Match(want, lv); ok {
+				return 10, nameIndexes(r.Name, v, 0, len([]rune(v))), true
+			}
+		case lv == want:
+			return 20, nameIndexes(r.Name, v, 0, len([]rune(v))), true
+		case !exact[t.Field] && strings.Contains(lv, want):
+			start := len([]rune(lv[:strings.Index(lv, want)]))
+			return 10, nameIndexes(r.Name, v, start, start+len([]rune(want))), true
+		}
+	}
+	return 0, nil, false
+}
+
+// matchText fuzzy matches free text against the Host alias first
+// and falls back to every other field value.
+func matchText(text string, r Record) (int, []int, bool) {
+	if m := fuzzy.Find(text, []string{r.Name}); len(m) > 0 {
+		return m[0].Score + 10, m[0].MatchedIndexes, true
+	}
+	var rest []string
+	for _, values := range r.Fields {
+		for _, v := range values {
+			if v != "" && v != r.Name {
+				rest = append(rest, v)
+			}
+		}
+	}
+	if m := fuzzy.Find(text, rest); len(m) > 0 {
+		return m[0].Score, nil, true
+	}
+	return 0, nil, false
+}
+
+// nameIndexes returns positions start..end when v is the Host alias.
+func nameIndexes(name, v string, start, end int) []int {
+	if v != name {
+		return nil
+	}
+	idx := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+func mergeIndexes(a, b []int) []int {
+	if len(b) == 0 {
+		return a
+	}
+	seen := make(map[int]bool, len(a))
+	for _, i := range a {
+		seen[i] = true
+	}
+	for _, i := range b {
+		if !seen[i] {
+			a = append(a, i)
+			seen[i] = true
+		}
+	}
+	sort.Ints(a)
+	return a
+}
+
+func parseTerm(tok string) Term {
+	var t Term
+	if len(tok) > 1 && tok[0] == '-' {
+		t.Negate = true
+		tok = tok[1:]
+	}
+	if i := strings.IndexByte(tok, ':'); i > 0 && isField(tok[:i]) {
+		t.Field = strings.ToLower(tok[:i])
+		t.Value = strings.Trim(tok[i+1:], `"`)
+		return t
+	}
+	t.Value = strings.Trim(tok, `"`)
+	return t
+}
+
+func isField(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits s on whitespace, double quotes group words.
+func tokenize(s string) []string {
+	var toks []string
+	var cur strings.Builder
+	var quoted bool
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				toks = append(toks, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		toks = append(toks, cur.String())
+	}
+	return toks
+}
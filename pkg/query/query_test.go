@@ -0,0 +1,103 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/query"
+)
+
+func TestQuery(t *testing.T) {
+	records := []query.Record{
+		{Name: "web-eu", Fields: map[string][]string{
+			"host": {"web-eu", "web.example.eu"},
+			"user": {"root"},
+			"tag":  {"prod", "web"},
+			"port": {"22"},
+		}},
+		{Name: "db", Fields: map[string][]string{
+			"host": {"db", "db.example.com"},
+			"user": {"postgres"},
+			"tag":  {"prod", "legacy"},
+			"port": {"2222"},
+			"jump": {"bastion"},
+		}},
+		{Name: "lab", Fields: map[string][]string{
+			"host": {"lab", "10.0.0.5"},
+			"user": {"root"},
+			"tag":  {"test"},
+			"port": {"22"},
+		}},
+	}
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"", []string{"web-eu", "db", "lab"}},
+		{"user:root", []string{"web-eu", "lab"}},
+		{"tag:prod -tag:legacy", []string{"web-eu"}},
+		{"port:2222", []string{"db"}},
+		{"port:22", []string{"web-eu", "lab"}},
+		{"host:*.eu", []string{"web-eu"}},
+		{"jump:bastion", []string{"db"}},
+		{"tag:test | jump:bastion", []string{"db", "lab"}},
+		{"tag:test OR tag:web", []string{"web-eu", "lab"}},
+		{"postgres", []string{"db"}},
+		{"os:debian", nil},
+	}
+	for _, tt := range tests {
+		results := query.Parse(tt.q).Filter(records)
+		var got []string
+		for _, r := range results {
+			got = append(got, records[r.Index].Name)
+		}
+		if !sameSet(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestRanking(t *testing.T) {
+	records := []query.Record{
+		{Name: "xwxexbx"},
+		{Name: "web"},
+	}
+	results := query.Parse("web").Filter(records)
+	if len(results) != 2 || records[results[0].Index].Name != "web" {
+		t.Fatalf("want exact match ranked first, got %v", results)
+	}
+	if len(results[0].Indexes) != 3 {
+		t.Errorf("want 3 highlighted runes, got %v", results[0].Indexes)
+	}
+}
+
+func TestFromTagArg(t *testing.T) {
+	tests := map[string]string{
+		"vpn":          "tag:vpn",
+		"vpn,prod":     "tag:vpn | tag:prod",
+		"user:root":    "user:root",
+		"#vpn":         "tag:vpn",
+		"web -tag:old": "web -tag:old",
+	}
+	for in, want := range tests {
+		if got := query.FromTagArg(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := map[string]bool{}
+	for _, s := range a {
+		m[s] = true
+	}
+	for _, s := range b {
+		if !m[s] {
+			return false
+		}
+	}
+	return true
+}
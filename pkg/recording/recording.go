@@ -0,0 +1,336 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package recording records connection sessions as asciicast v2
+// files under $XDG_DATA_HOME/ssm/recordings and replays them.
+// Recording is opt-in, per host with a `#record: yes` comment
+// or through config.toml:
+//
+//	[record]
+//	hosts = ["db1"]
+//	tags = ["prod"]
+//
+// ref: https://docs.asciinema.org/manual/asciicast/v2/
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const (
+	dirName = "recordings"
+	ext     = ".cast"
+)
+
+// Dir returns $XDG_DATA_HOME/ssm/recordings.
+func Dir() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Policy picks the hosts whose sessions are recorded.
+type Policy struct {
+	Hosts []string `toml:"hosts"`
+	Tags  []string `toml:"tags"`
+}
+
+// Records reports whether sessions to h are recorded,
+// the `#record:` comment of h wins over the policy.
+func (p Policy) Records(h sshconf.Host) bool {
+	switch strings.ToLower(h.Meta()["record"]) {
+	case "yes", "true", "on":
+		return true
+	case "no", "false", "off":
+		return false
+	}
+	if slices.Contains(p.Hosts, h.Name) {
+		return true
+	}
+	for _, t := range h.Tags() {
+		if slices.Contains(p.Tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Writer writes asciicast v2 events timed from its creation.
+type Writer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	// part holds an incomplete UTF-8 sequence, events are JSON strings.
+	part []byte
+}
+
+// NewWriter writes h to w and returns a Writer of its events.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	h.Version = 2
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, start: time.Now()}, nil
+}
+
+// Write records p as output.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := append(w.part, p...)
+	cut := len(data)
+	// hold back a rune split across writes, at most 3 bytes
+	for i := len(data) - 1; i >= 0 && i >= len(data)-3; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	w.part = slices.Clone(data[cut:])
+	if cut == 0 {
+		return len(p), nil
+	}
+	if err := w.event("o", string(data[:cut])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize records the terminal size change.
+func (w *Writer) Resize(width, height int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+func (w *Writer) event(code, data string) error {
+	b, err := json.Marshal([]any{time.Since(w.start).Seconds(), code, data})
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+// Event is an asciicast v2 event.
+type Event struct {
+	Time time.Duration
+	Code string
+	Data string
+}
+
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var raw []any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("event of %d fields", len(raw))
+	}
+	t, ok1 := raw[0].(float64)
+	code, ok2 := raw[1].(string)
+	data, ok3 := raw[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return errors.New("malformed event")
+	}
+	*e = Event{Time: time.Duration(t * float64(time.Second)), Code: code, Data: data}
+	return nil
+}
+
+// Reader reads an asciicast v2 file.
+type Reader struct {
+	Header  Header
+	scanner *bufio.Scanner
+}
+
+// NewReader reads the header of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("recording: empty file")
+	}
+	var h Header
+	if err := json.Unmarshal(s.Bytes(), &h); err != nil {
+		return nil, fmt.Errorf("recording: header: %w", err)
+	}
+	if h.Version != 2 {
+		return nil, fmt.Errorf("recording: asciicast version %d, want 2", h.Version)
+	}
+	return &Reader{Header: h, scanner: s}, nil
+}
+
+// Next returns the next event, io.EOF at the end.
+func (r *Reader) Next() (Event, error) {
+	for r.scanner.Scan() {
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(r.scanner.Bytes(), &e); err != nil {
+			return e, fmt.Errorf("recording: %w", err)
+		}
+		return e, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// Play writes the output of r to w in real time divided by speed,
+// pauses are shortened to idle when it's positive.
+func Play(r io.Reader, w io.Writer, speed float64, idle time.Duration) error {
+	rd, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+	var last time.Duration
+	for {
+		e, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		wait := time.Duration(float64(e.Time-last) / speed)
+		if idle > 0 && wait > idle {
+			wait = idle
+		}
+		last = e.Time
+		time.Sleep(wait)
+		if e.Code != "o" {
+			continue
+		}
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// Info describes a recording file.
+type Info struct {
+	Path     string
+	Host     string
+	Start    time.Time
+	Duration time.Duration
+	Size     int64
+}
+
+// Stat reads the header and length of the recording at path.
+func Stat(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	rd, err := NewReader(f)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", path, err)
+	}
+	info := Info{
+		Path:  path,
+		Host:  rd.Header.Title,
+		Start: time.Unix(rd.Header.Timestamp, 0),
+		Size:  fi.Size(),
+	}
+	for {
+		e, err := rd.Next()
+		if err != nil {
+			// a session cut short still has its events
+			break
+		}
+		info.Duration = e.Time
+	}
+	return info, nil
+}
+
+// List returns the recordings of dir, most recent first.
+// Unreadable files are skipped.
+func List(dir string) ([]Info, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	var out []Info
+	for _, p := range paths {
+		info, err := Stat(p)
+		if err != nil {
+			continue
+		}
+		out = append(out, info)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Start.After(out[j].Start)
+	})
+	return out, nil
+}
+
+// NewPath returns the path recording a session to host started at t.
+func NewPath(dir, host string, t time.Time) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator || r == ' ' {
+			return '_'
+		}
+		return r
+	}, host)
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", safe, t.Format("20060102-150405"), ext))
+}
+
+// Latest returns the most recent recording of host in dir.
+func Latest(dir, host string) (Info, error) {
+	list, err := List(dir)
+	if err != nil {
+		return Info{}, err
+	}
+	for _, info := range list {
+		if info.Host == host {
+			return info, nil
+		}
+	}
+	return Info{}, fmt.Errorf("no recordings of %s in %s", host, dir)
+}
@@ -0,0 +1,105 @@
+package recording_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+func TestWriterPlay(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := recording.NewWriter(&buf, recording.Header{Width: 80, Height: 24, Title: "web1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a rune split across writes is kept whole
+	euro := []byte("€")
+	for _, p := range [][]byte{[]byte("hello "), euro[:1], euro[1:], []byte("\r\n")} {
+		if _, err := w.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Resize(100, 30); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], `"version":2`) || !strings.Contains(lines[4], `"r","100x30"`) {
+		t.Fatalf("cast:\n%s", buf.String())
+	}
+
+	var out bytes.Buffer
+	if err := recording.Play(bytes.NewReader(buf.Bytes()), &out, 10, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hello €\r\n" {
+		t.Errorf("played %q", out.String())
+	}
+	if err := recording.Play(strings.NewReader(`{"version":1}`), &out, 1, 0); err == nil {
+		t.Error("expected version error")
+	}
+}
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	path := recording.NewPath(dir, "web1", time.Now())
+	var out, copied bytes.Buffer
+	s := &recording.Session{
+		Cmd:    exec.Command("sh", "-c", "printf 'up 3 days'; echo 'broken pipe' >&2"),
+		Path:   path,
+		Title:  "web1",
+		Output: &copied,
+	}
+	s.SetStdin(strings.NewReader(""))
+	s.SetStdout(&out)
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "up 3 days") || !strings.Contains(out.String(), "broken pipe") {
+		t.Errorf("output %q", out.String())
+	}
+	if copied.String() != out.String() {
+		t.Errorf("copied output %q", copied.String())
+	}
+	cast, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(cast), "broken pipe") {
+		t.Errorf("stderr not recorded: %s %v", cast, err)
+	}
+	list, err := recording.List(dir)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list: %v %v", list, err)
+	}
+	if list[0].Host != "web1" || list[0].Path != path {
+		t.Errorf("info: %+v", list[0])
+	}
+	if _, err := recording.Latest(dir, "db1"); err == nil {
+		t.Error("expected no recordings of db1")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	c := sshconftest.Parse(t, `
+Host web1
+#tag: prod,web
+
+Host web2
+#tag: prod
+#record: no
+
+Host dev
+#record: yes
+
+Host db1
+`)
+	p := recording.Policy{Tags: []string{"prod"}}
+	for name, want := range map[string]bool{"web1": true, "web2": false, "dev": true, "db1": false} {
+		if got := p.Records(c.GetHost(name)); got != want {
+			t.Errorf("%s records: %v, want %v", name, got, want)
+		}
+	}
+}
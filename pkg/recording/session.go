@@ -0,0 +1,127 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package recording
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/muesli/cancelreader"
+	"golang.org/x/term"
+)
+
+// Session runs a command under a pty recording its output,
+// it satisfies tea.ExecCommand.
+type Session struct {
+	Cmd *exec.Cmd
+	// Path is the asciicast file written.
+	Path  string
+	Title string
+	// Output receives a copy of the terminal output when set.
+	Output io.Writer
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (s *Session) SetStdin(r io.Reader)  { s.stdin = r }
+func (s *Session) SetStdout(w io.Writer) { s.stdout = w }
+
+// SetStderr is a no-op: the pty merges stderr into the output.
+func (s *Session) SetStderr(io.Writer) {}
+
+// Run starts the command and copies the terminal to it
+// until it exits.
+func (s *Session) Run() error {
+	if s.stdin == nil {
+		s.stdin = os.Stdin
+	}
+	if s.stdout == nil {
+		s.stdout = os.Stdout
+	}
+	// the input copy is cancelled on exit, a blocked read
+	// would steal the next key from the caller
+	var in io.Reader = s.stdin
+	if cr, err := cancelreader.NewReader(s.stdin); err == nil {
+		// files like /dev/null can't be cancelled, nor block
+		defer cr.Close()
+		defer cr.Cancel()
+		in = cr
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	width, height := 80, 24
+	tty, isTTY := s.stdin.(*os.File)
+	isTTY = isTTY && term.IsTerminal(int(tty.Fd()))
+	if isTTY {
+		if w, h, err := term.GetSize(int(tty.Fd())); err == nil {
+			width, height = w, h
+		}
+	}
+	cast, err := NewWriter(f, Header{
+		Width:     width,
+		Height:    height,
+		Timestamp: time.Now().Unix(),
+		Title:     s.Title,
+		Env:       map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+	})
+	if err != nil {
+		return err
+	}
+
+	ptmx, err := pty.StartWithSize(s.Cmd, &pty.Winsize{Cols: uint16(width), Rows: uint16(height)}) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	if isTTY {
+		state, err := term.MakeRaw(int(tty.Fd()))
+		if err == nil {
+			defer term.Restore(int(tty.Fd()), state) //nolint:errcheck
+		}
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer signal.Stop(winch)
+		go func() {
+			for range winch {
+				if err := pty.InheritSize(tty, ptmx); err != nil {
+					continue
+				}
+				if w, h, err := term.GetSize(int(tty.Fd())); err == nil {
+					cast.Resize(w, h) //nolint:errcheck
+				}
+			}
+		}()
+	}
+
+	go io.Copy(ptmx, in) //nolint:errcheck
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		out := io.MultiWriter(s.stdout, cast)
+		if s.Output != nil {
+			out = io.MultiWriter(out, s.Output)
+		}
+		// reads fail with EIO once the command exits
+		io.Copy(out, ptmx) //nolint:errcheck
+	}()
+	err = s.Cmd.Wait()
+	<-done
+	return err
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/lfaoro/ssm/pkg/xdg"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileName = "secrets.enc"
+	// PassphraseEnv holds the passphrase of the secrets file.
+	PassphraseEnv = "SSM_SECRETS_PASSPHRASE"
+)
+
+var (
+	// ErrNoPassphrase is returned by files without a passphrase.
+	ErrNoPassphrase = errors.New("secrets file passphrase not set, set " + PassphraseEnv)
+	// ErrPassphrase is returned when the file can't be decrypted.
+	ErrPassphrase = errors.New("wrong passphrase or corrupted secrets file")
+)
+
+// FilePath returns $XDG_DATA_HOME/ssm/secrets.enc.
+func FilePath() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// File stores secrets encrypted with XChaCha20-Poly1305,
+// the key is derived from a passphrase with scrypt.
+type File struct {
+	Path string
+	// Passphrase defaults to $SSM_SECRETS_PASSPHRASE.
+	Passphrase string
+}
+
+// sealed is the file format.
+type sealed struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func (f *File) passphrase() (string, error) {
+	if f.Passphrase != "" {
+		return f.Passphrase, nil
+	}
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	return "", ErrNoPassphrase
+}
+
+func key(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+}
+
+// Load decrypts the secrets, a missing file has none.
+func (f *File) Load() (map[string]string, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pass, err := f.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	var s sealed
+	if err := json.Unmarshal(b, &s); err != nil || s.Version != 1 {
+		return nil, fmt.Errorf("%s: %w", f.Path, ErrPassphrase)
+	}
+	k, err := key(pass, s.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(k)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, s.Nonce, s.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, ErrPassphrase)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return secrets, nil
+}
+
+// Save encrypts secrets to the file with a new salt and nonce.
+func (f *File) Save(secrets map[string]string) error {
+	pass, err := f.passphrase()
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	s := sealed{
+		Version: 1,
+		Salt:    make([]byte, 16),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return err
+	}
+	k, err := key(pass, s.Salt)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(k)
+	if err != nil {
+		return err
+	}
+	s.Data = aead.Seal(nil, s.Nonce, plain, nil)
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Secret returns the secret called ref.
+func (f *File) Secret(ref string) (string, error) {
+	secrets, err := f.Load()
+	if err != nil {
+		return "", err
+	}
+	v, ok := secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("%s not found in %s", ref, f.Path)
+	}
+	return v, nil
+}
+
+// Set stores value as name.
+func (f *File) Set(name, value string) error {
+	secrets, err := f.Load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return f.Save(secrets)
+}
+
+// Delete removes name.
+func (f *File) Delete(name string) error {
+	secrets, err := f.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("%s not found in %s", name, f.Path)
+	}
+	delete(secrets, name)
+	return f.Save(secrets)
+}
+
+// Names returns the stored names sorted.
+func (f *File) Names() ([]string, error) {
+	secrets, err := f.Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for n := range secrets {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names, nil
+}
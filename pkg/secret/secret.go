@@ -0,0 +1,176 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package secret resolves the passwords of hosts from providers
+// picked with a `#secret: provider:ref` comment:
+//
+//	#secret: env:PROD_DB_PASSWORD
+//	#secret: cmd:pass show prod/db
+//	#secret: file:prod/db
+//
+// Passwords reach the connector through the environment, read
+// by `sshpass -e` or by ssm itself as SSH_ASKPASS, never in argv.
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+// MetaKey is the host comment naming the secret.
+const MetaKey = "secret"
+
+// Spec returns the secret of h, empty without one.
+func Spec(h sshconf.Host) string {
+	return strings.TrimSpace(h.Meta()[MetaKey])
+}
+
+// Provider returns the secret called ref.
+type Provider interface {
+	Secret(ref string) (string, error)
+}
+
+// Env reads secrets from environment variables.
+type Env struct{}
+
+func (Env) Secret(ref string) (string, error) {
+	v, ok := os.LookupEnv(ref)
+	if !ok || v == "" {
+		return "", fmt.Errorf("env %s is not set", ref)
+	}
+	return v, nil
+}
+
+// Command reads secrets from the first line printed by a shell
+// command, like `pass show` or a keyring CLI.
+type Command struct{}
+
+func (Command) Secret(ref string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", ref)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", ref, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", ref, err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" {
+		return "", fmt.Errorf("%s: printed no secret", ref)
+	}
+	return line, nil
+}
+
+// Resolver resolves `provider:ref` specs.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver returns a resolver of the env and cmd providers,
+// and of file when not nil.
+func NewResolver(file *File) *Resolver {
+	r := &Resolver{providers: map[string]Provider{
+		"env": Env{},
+		"cmd": Command{},
+	}}
+	if file != nil {
+		r.Register("file", file)
+	}
+	return r
+}
+
+// Register adds p as provider, replacing the one of the same name.
+func (r *Resolver) Register(name string, p Provider) {
+	r.providers[name] = p
+}
+
+// Resolve returns the secret of spec.
+func (r *Resolver) Resolve(spec string) (string, error) {
+	name, ref, ok := strings.Cut(spec, ":")
+	name, ref = strings.TrimSpace(name), strings.TrimSpace(ref)
+	p, known := r.providers[name]
+	if !ok || ref == "" || !known {
+		var names []string
+		for n := range r.providers {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return "", fmt.Errorf("secret %q: want provider:ref, providers: %s", spec, strings.Join(names, ", "))
+	}
+	v, err := p.Secret(ref)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return v, nil
+}
+
+const (
+	// PasswordEnv holds the password in the connector
+	// environment, the one `sshpass -e` reads.
+	PasswordEnv = "SSHPASS"
+	// askpassEnv marks ssm running as SSH_ASKPASS.
+	askpassEnv = "SSM_ASKPASS"
+)
+
+// Environ returns the environment passing password to the connector,
+// askpass is the program answering the ssh password prompt.
+func Environ(password, askpass string) []string {
+	return append(Scrub(os.Environ()),
+		PasswordEnv+"="+password,
+		"SSH_ASKPASS="+askpass,
+		// OpenSSH 8.4+ uses askpass with a terminal too
+		"SSH_ASKPASS_REQUIRE=force",
+		askpassEnv+"=1",
+	)
+}
+
+// Scrub returns env without the variables Environ sets: commands
+// run next to a connection, like its hooks, never see the password.
+func Scrub(env []string) []string {
+	return slices.DeleteFunc(slices.Clone(env), func(kv string) bool {
+		k, _, _ := strings.Cut(kv, "=")
+		return k == PasswordEnv || k == "SSH_ASKPASS" || k == "SSH_ASKPASS_REQUIRE" || k == askpassEnv
+	})
+}
+
+// IsAskpass reports whether ssh runs ssm as SSH_ASKPASS with args,
+// the prompt alone. The environment of the connection is inherited
+// by the `ssm hook` and `ssm record` wrappers too, never run so.
+func IsAskpass(args []string) bool {
+	return len(args) == 1 && os.Getenv(askpassEnv) == "1" && os.Getenv(PasswordEnv) != ""
+}
+
+// Askpass answers the ssh prompt of args: the password from
+// the environment, other prompts like host key confirmations
+// are asked on the terminal. It returns the exit status.
+func Askpass(args []string, stdout io.Writer) int {
+	prompt := strings.Join(args, " ")
+	if strings.Contains(strings.ToLower(prompt), "password") {
+		fmt.Fprintln(stdout, os.Getenv(PasswordEnv))
+		return 0
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return 1
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 1
+	}
+	fmt.Fprintln(stdout, strings.TrimSpace(answer))
+	return 0
+}
@@ -0,0 +1,89 @@
+package secret_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/secret"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SSM_TEST_PASS", "hunter2")
+	file := &secret.File{Path: filepath.Join(t.TempDir(), "secrets.enc"), Passphrase: "correct horse"}
+	if err := file.Set("prod/db", "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+	r := secret.NewResolver(file)
+	for spec, want := range map[string]string{
+		"env:SSM_TEST_PASS":             "hunter2",
+		"cmd:printf 'line1\\nline2\\n'": "line1",
+		"file:prod/db":                  "s3cr3t",
+	} {
+		got, err := r.Resolve(spec)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q %v, want %q", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"env:SSM_TEST_UNSET", "cmd:exit 1", "file:missing", "vault:x", "nocolon"} {
+		if _, err := r.Resolve(spec); err == nil {
+			t.Errorf("%s: expected error", spec)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	f := &secret.File{Path: path, Passphrase: "pw"}
+	if err := f.Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("b", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := f.Names(); !slices.Equal(names, []string{"b"}) {
+		t.Errorf("names %v", names)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte(`"b"`)) {
+		t.Error("secrets stored in clear")
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode %v", fi.Mode())
+	}
+	wrong := &secret.File{Path: path, Passphrase: "nope"}
+	if _, err := wrong.Load(); !errors.Is(err, secret.ErrPassphrase) {
+		t.Errorf("got %v, want ErrPassphrase", err)
+	}
+	t.Setenv(secret.PassphraseEnv, "")
+	if _, err := (&secret.File{Path: path}).Load(); !errors.Is(err, secret.ErrNoPassphrase) {
+		t.Errorf("got %v, want ErrNoPassphrase", err)
+	}
+}
+
+func TestAskpass(t *testing.T) {
+	env := secret.Environ("hunter2", "/usr/bin/ssm")
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+	prompt := []string{"root@db's password: "}
+	if !secret.IsAskpass(prompt) {
+		t.Fatal("expected askpass mode")
+	}
+	// the wrappers of sessions inherit the environment
+	if secret.IsAskpass([]string{"hook", "--spec", "{}", "--", "ssh", "db"}) {
+		t.Error("askpass mode for a wrapper")
+	}
+	var out bytes.Buffer
+	if code := secret.Askpass(prompt, &out); code != 0 || out.String() != "hunter2\n" {
+		t.Errorf("got %d %q", code, out.String())
+	}
+}
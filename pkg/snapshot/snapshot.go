@@ -0,0 +1,186 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package snapshot keeps copies of the ssh config taken before
+// editing it, to review the edit and to undo it. Snapshots of a
+// config live in $XDG_STATE_HOME/ssm/snapshots/<escaped path>.
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const (
+	dirName    = "snapshots"
+	timeLayout = "20060102-150405.000000000"
+	// Keep is the number of snapshots kept by config.
+	Keep = 50
+)
+
+// Dir returns $XDG_STATE_HOME/ssm/snapshots.
+func Dir() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Snapshot is a copy of a config.
+type Snapshot struct {
+	Path   string
+	Config string
+	Time   time.Time
+	Size   int64
+}
+
+// Read returns the content of the snapshot.
+func (s Snapshot) Read() ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+// Store keeps the snapshots in Dir.
+type Store struct {
+	Dir string
+}
+
+func (s Store) configDir(config string) (string, error) {
+	abs, err := filepath.Abs(config)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, url.PathEscape(abs)), nil
+}
+
+// Take snapshots config, the latest snapshot is returned
+// instead when config didn't change since.
+func (s Store) Take(config string) (Snapshot, error) {
+	data, err := os.ReadFile(config)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snaps, err := s.List(config)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snaps) > 0 {
+		if latest, err := snaps[0].Read(); err == nil && bytes.Equal(latest, data) {
+			return snaps[0], nil
+		}
+	}
+	dir, err := s.configDir(config)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Snapshot{}, err
+	}
+	now := time.Now().UTC()
+	snap := Snapshot{
+		Path:   filepath.Join(dir, now.Format(timeLayout)),
+		Config: config,
+		Time:   now,
+		Size:   int64(len(data)),
+	}
+	if err := os.WriteFile(snap.Path, data, 0o600); err != nil {
+		return Snapshot{}, err
+	}
+	return snap, s.prune(append([]Snapshot{snap}, snaps...))
+}
+
+// List returns the snapshots of config, newest first.
+func (s Store) List(config string) ([]Snapshot, error) {
+	dir, err := s.configDir(config)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Snapshot
+	for _, e := range entries {
+		t, err := time.Parse(timeLayout, e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, Snapshot{
+			Path:   filepath.Join(dir, e.Name()),
+			Config: config,
+			Time:   t,
+			Size:   info.Size(),
+		})
+	}
+	slices.SortFunc(snaps, func(a, b Snapshot) int { return b.Time.Compare(a.Time) })
+	return snaps, nil
+}
+
+func (s Store) prune(snaps []Snapshot) error {
+	var errs []error
+	for _, old := range snaps[min(len(snaps), Keep):] {
+		if err := os.Remove(old.Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Restore writes snap back to its config, the config is
+// snapshotted first: restoring it again undoes the restore.
+func (s Store) Restore(snap Snapshot) (Snapshot, error) {
+	data, err := snap.Read()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	current, err := s.Take(snap.Config)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot of %s: %w", snap.Config, err)
+	}
+	return current, Write(snap.Config, data)
+}
+
+// Write replaces the content of config keeping its mode,
+// through symlinks: dotfile managers link the config.
+func Write(config string, data []byte) error {
+	path, err := filepath.EvalSymlinks(config)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
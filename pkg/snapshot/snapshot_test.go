@@ -0,0 +1,57 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/snapshot"
+)
+
+func TestSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	if err := os.WriteFile(config, []byte("Host a\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	store := snapshot.Store{Dir: filepath.Join(dir, "snapshots")}
+	first, err := store.Take(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.Take(config); again.Path != first.Path {
+		t.Error("unchanged config snapshotted twice")
+	}
+
+	if err := os.WriteFile(config, []byte("Host b\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	undo, err := store.Restore(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(config); string(b) != "Host a\n" {
+		t.Errorf("restored %q", b)
+	}
+	if fi, _ := os.Stat(config); fi.Mode().Perm() != 0o640 {
+		t.Errorf("mode %v", fi.Mode())
+	}
+	if b, _ := undo.Read(); string(b) != "Host b\n" {
+		t.Errorf("snapshot before restore %q", b)
+	}
+	snaps, err := store.List(config)
+	if err != nil || len(snaps) != 2 || snaps[0].Path != undo.Path {
+		t.Errorf("list %+v %v", snaps, err)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(config, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.Write(link, []byte("Host c\n")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Lstat(link); fi.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink replaced")
+	}
+}
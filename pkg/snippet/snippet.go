@@ -0,0 +1,172 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package snippet loads the named commands of snippets.toml,
+// commands are Go templates rendered over the host fields.
+//
+//	[[snippet]]
+//	name = "restart-nginx"
+//	description = "restart nginx and show its status"
+//	command = "sudo systemctl restart {{.Meta.service}} && systemctl status {{.Meta.service}}"
+//	tags = ["web"]
+package snippet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const fileName = "snippets.toml"
+
+// Snippet is a named command.
+type Snippet struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	Command     string `toml:"command"`
+	// Tags limits the snippet to hosts with any of them,
+	// empty applies to every host.
+	Tags []string `toml:"tags"`
+}
+
+type file struct {
+	Snippets []Snippet `toml:"snippet"`
+}
+
+// Path returns $XDG_CONFIG_HOME/ssm/snippets.toml.
+func Path() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the snippets from Path, a missing file has none.
+func Load() ([]Snippet, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(path)
+}
+
+// LoadPath reads the snippets of path, a missing file has none.
+func LoadPath(path string) ([]Snippet, error) {
+	var f file
+	_, err := toml.DecodeFile(path, &f)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snippets: %w", err)
+	}
+	for i, s := range f.Snippets {
+		if s.Name == "" || strings.TrimSpace(s.Command) == "" {
+			return nil, fmt.Errorf("snippets: %s: entry %d needs a name and a command", path, i+1)
+		}
+		if _, err := parse(s); err != nil {
+			return nil, fmt.Errorf("snippets: %s: %w", path, err)
+		}
+	}
+	return f.Snippets, nil
+}
+
+// Find returns the snippet called name.
+func Find(snippets []Snippet, name string) (Snippet, bool) {
+	for _, s := range snippets {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// For returns the snippets applying to h.
+func For(snippets []Snippet, h sshconf.Host) []Snippet {
+	var out []Snippet
+	for _, s := range snippets {
+		if s.Applies(h) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Applies reports whether s can run on h.
+func (s Snippet) Applies(h sshconf.Host) bool {
+	if len(s.Tags) == 0 {
+		return true
+	}
+	tags := h.Tags()
+	for _, t := range s.Tags {
+		if slices.Contains(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Data is what the command templates see.
+type Data struct {
+	Name     string
+	HostName string
+	User     string
+	Port     string
+	Jump     string
+	Tags     []string
+	// Meta holds the metadata comments of the host, see sshconf.Host.Meta.
+	Meta map[string]string
+	// Options holds the ssh options keyed by lowercase name.
+	Options map[string]string
+}
+
+// DataOf returns the template fields of h.
+func DataOf(h sshconf.Host) Data {
+	d := Data{
+		Name:     h.Name,
+		HostName: h.HostName(),
+		User:     h.User(),
+		Port:     h.Port(),
+		Jump:     h.ProxyJump(),
+		Tags:     h.Tags(),
+		Meta:     h.Meta(),
+		Options:  map[string]string{},
+	}
+	if h.Options != nil {
+		for _, k := range h.Options.Keys() {
+			d.Options[k], _ = h.Options.Get(k)
+		}
+	}
+	return d
+}
+
+// Render returns the command of s for h, referencing
+// a missing Meta or Options key is an error.
+func (s Snippet) Render(h sshconf.Host) (string, error) {
+	t, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, DataOf(h)); err != nil {
+		return "", fmt.Errorf("snippet %s on %s: %w", s.Name, h.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func parse(s Snippet) (*template.Template, error) {
+	t, err := template.New(s.Name).Option("missingkey=error").Parse(s.Command)
+	if err != nil {
+		return nil, fmt.Errorf("snippet %s: %w", s.Name, err)
+	}
+	return t, nil
+}
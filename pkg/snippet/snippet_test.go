@@ -0,0 +1,96 @@
+package snippet_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/snippet"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+const snippets = `
+[[snippet]]
+name = "uptime"
+command = "uptime"
+
+[[snippet]]
+name = "restart"
+description = "restart the service"
+command = "sudo -u {{.User}} systemctl restart {{.Meta.service}} # on {{.HostName}}:{{.Port}}"
+tags = ["web"]
+`
+
+const config = `
+Host web1
+#tag: web
+#ssm-service: nginx
+    User deploy
+    HostName 10.0.0.1
+
+Host db1
+#tag: db
+    HostName 10.0.0.2
+`
+
+func setup(t *testing.T) ([]snippet.Snippet, *sshconf.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snippets.toml")
+	if err := os.WriteFile(path, []byte(snippets), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := snippet.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list, sshconftest.Parse(t, config)
+}
+
+func TestRender(t *testing.T) {
+	list, cfg := setup(t)
+	if len(list) != 2 {
+		t.Fatalf("got %d snippets, want 2", len(list))
+	}
+	web, db := cfg.GetHost("web1"), cfg.GetHost("db1")
+
+	if got := snippet.For(list, web); len(got) != 2 {
+		t.Errorf("web1 snippets = %d, want 2", len(got))
+	}
+	if got := snippet.For(list, db); len(got) != 1 || got[0].Name != "uptime" {
+		t.Errorf("db1 snippets = %v, want [uptime]", got)
+	}
+
+	restart, ok := snippet.Find(list, "restart")
+	if !ok {
+		t.Fatal("restart not found")
+	}
+	cmd, err := restart.Render(web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "sudo -u deploy systemctl restart nginx # on 10.0.0.1:22"
+	if cmd != want {
+		t.Errorf("got %q, want %q", cmd, want)
+	}
+	// db1 has no #ssm-service: metadata
+	if _, err := restart.Render(db); err == nil || !strings.Contains(err.Error(), "service") {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if list, err := snippet.LoadPath(filepath.Join(dir, "missing.toml")); err != nil || list != nil {
+		t.Errorf("missing file: got %v, %v", list, err)
+	}
+	bad := filepath.Join(dir, "bad.toml")
+	if err := os.WriteFile(bad, []byte("[[snippet]]\nname = \"x\"\ncommand = \"{{.Nope\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snippet.LoadPath(bad); err == nil {
+		t.Error("expected template error")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package sshagent talks to the ssh-agent listening on $SSH_AUTH_SOCK:
+// it lists, adds and removes identities and finds the IdentityFile
+// keys of a host the agent doesn't hold.
+package sshagent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshkeys"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrNoAgent is returned when SSH_AUTH_SOCK is not set.
+var ErrNoAgent = errors.New("ssh-agent: SSH_AUTH_SOCK not set")
+
+// Identity is a key loaded in the agent.
+type Identity struct {
+	Type        string
+	Bits        int
+	Fingerprint string
+	Comment     string
+	Key         ssh.PublicKey
+}
+
+// Client is a connection to an agent.
+type Client struct {
+	conn  net.Conn
+	agent agent.ExtendedAgent
+}
+
+// Socket returns the agent socket path from the environment.
+func Socket() string {
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+// Dial connects to the agent listening on sock,
+// an empty sock uses $SSH_AUTH_SOCK.
+func Dial(sock string) (*Client, error) {
+	if sock == "" {
+		sock = Socket()
+	}
+	if sock == "" {
+		return nil, ErrNoAgent
+	}
+	conn, err := net.DialTimeout("unix", sock, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent: %w", err)
+	}
+	return &Client{conn: conn, agent: agent.NewClient(conn)}, nil
+}
+
+// Close closes the agent connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// List returns the loaded identities.
+func (c *Client) List() ([]Identity, error) {
+	keys, err := c.agent.List()
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent: %w", err)
+	}
+	out := make([]Identity, 0, len(keys))
+	for _, k := range keys {
+		pub, err := ssh.ParsePublicKey(k.Blob)
+		if err != nil {
+			continue
+		}
+		out = append(out, Identity{
+			Type:        pub.Type(),
+			Bits:        sshkeys.Bits(pub),
+			Fingerprint: ssh.FingerprintSHA256(pub),
+			Comment:     k.Comment,
+			Key:         pub,
+		})
+	}
+	return out, nil
+}
+
+// AddOptions constrain a key added to the agent.
+type AddOptions struct {
+	// Lifetime removes the key after the duration, zero keeps it.
+	Lifetime time.Duration
+	// Confirm asks the agent to confirm every use of the key.
+	Confirm bool
+	// Passphrase decrypts protected keys.
+	Passphrase []byte
+}
+
+// Add loads the private key at path. Protected keys without
+// a passphrase fail with a *ssh.PassphraseMissingError.
+func (c *Client) Add(path string, opts AddOptions) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var key any
+	if len(opts.Passphrase) > 0 {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(b, opts.Passphrase)
+	} else {
+		key, err = ssh.ParseRawPrivateKey(b)
+	}
+	if err != nil {
+		return err
+	}
+	err = c.agent.Add(agent.AddedKey{
+		PrivateKey:       key,
+		Comment:          comment(path),
+		LifetimeSecs:     uint32(opts.Lifetime.Seconds()),
+		ConfirmBeforeUse: opts.Confirm,
+	})
+	if err != nil {
+		return fmt.Errorf("ssh-agent: add %s: %w", path, err)
+	}
+	return nil
+}
+
+// Remove unloads the identity with the given public key.
+func (c *Client) Remove(pub ssh.PublicKey) error {
+	if err := c.agent.Remove(pub); err != nil {
+		return fmt.Errorf("ssh-agent: %w", err)
+	}
+	return nil
+}
+
+// RemoveAll unloads every identity.
+func (c *Client) RemoveAll() error {
+	if err := c.agent.RemoveAll(); err != nil {
+		return fmt.Errorf("ssh-agent: %w", err)
+	}
+	return nil
+}
+
+// PublicKey returns the public half of the key file at path,
+// read from path.pub when present so no passphrase is needed.
+func PublicKey(path string) (ssh.PublicKey, error) {
+	if b, err := os.ReadFile(path + ".pub"); err == nil {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(b)
+		if err == nil {
+			return pub, nil
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		return missing.PublicKey, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey(), nil
+}
+
+// comment prefers the comment recorded in path.pub, like ssh-add.
+func comment(path string) string {
+	b, err := os.ReadFile(path + ".pub")
+	if err != nil {
+		return path
+	}
+	_, c, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil || c == "" {
+		return path
+	}
+	return c
+}
+
+// Loaded reports whether pub is among ids.
+func Loaded(ids []Identity, pub ssh.PublicKey) bool {
+	want := string(pub.Marshal())
+	for _, id := range ids {
+		if string(id.Key.Marshal()) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the IdentityFile paths of h not loaded in ids,
+// files that don't exist are ignored like ssh does.
+func Missing(h sshconf.Host, ids []Identity) []string {
+	var out []string
+	for _, path := range sshkeys.IdentityFiles(h) {
+		pub, err := PublicKey(path)
+		if err != nil {
+			continue
+		}
+		if !Loaded(ids, pub) {
+			out = append(out, path)
+		}
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package sshagent_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshagent"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveAgent runs an in-process agent on a temp unix socket.
+func serveAgent(t *testing.T) string {
+	t.Helper()
+	// unix socket paths are limited to ~100 bytes
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	return sock
+}
+
+func writeKey(t *testing.T, path string, passphrase []byte) ssh.PublicKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != nil {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + filepath.Base(path) + "@test\n"
+	if err := os.WriteFile(path+".pub", []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return sshPub
+}
+
+func TestAgent(t *testing.T) {
+	sock := serveAgent(t)
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "id_plain")
+	secret := filepath.Join(dir, "id_secret")
+	plainPub := writeKey(t, plain, nil)
+	secretPub := writeKey(t, secret, []byte("pw"))
+
+	config := sshconftest.Parse(t, "Host web\n  IdentityFile "+plain+"\n"+
+		"Host db\n  IdentityFile "+secret+"\n"+
+		"Host gone\n  IdentityFile "+filepath.Join(dir, "id_gone")+"\n"+
+		"Host inherits\n  HostName 10.0.0.9\n"+
+		"Match originalhost inherits\n  IdentityFile "+plain+"\n")
+	web, db, gone, inherits := config.Hosts[0], config.Hosts[1], config.Hosts[2], config.Hosts[3]
+
+	c, err := sshagent.Dial(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ids, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sshagent.Missing(web, ids); len(got) != 1 || got[0] != plain {
+		t.Fatalf("missing = %v, want [%s]", got, plain)
+	}
+	if got := sshagent.Missing(inherits, ids); len(got) != 1 || got[0] != plain {
+		t.Fatalf("missing = %v, want [%s] from Match", got, plain)
+	}
+	if got := sshagent.Missing(gone, ids); len(got) != 0 {
+		t.Fatalf("missing = %v, want nonexistent files ignored", got)
+	}
+
+	if err := c.Add(plain, sshagent.AddOptions{Lifetime: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	var missing *ssh.PassphraseMissingError
+	if err := c.Add(secret, sshagent.AddOptions{}); !errors.As(err, &missing) {
+		t.Fatalf("add protected key without passphrase: got %v", err)
+	}
+	if err := c.Add(secret, sshagent.AddOptions{Passphrase: []byte("pw")}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err = c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("got %d identities, want 2", len(ids))
+	}
+	if ids[0].Comment != "id_plain@test" || ids[0].Bits != 256 {
+		t.Errorf("unexpected identity: %+v", ids[0])
+	}
+	if got := sshagent.Missing(db, ids); len(got) != 0 {
+		t.Errorf("missing = %v, want none", got)
+	}
+
+	if err := c.Remove(secretPub); err != nil {
+		t.Fatal(err)
+	}
+	ids, _ = c.List()
+	if !sshagent.Loaded(ids, plainPub) || sshagent.Loaded(ids, secretPub) {
+		t.Errorf("remove: unexpected identities %+v", ids)
+	}
+	if got := sshagent.Missing(db, ids); len(got) != 1 || got[0] != secret {
+		t.Errorf("missing = %v, want [%s]", got, secret)
+	}
+}
+
+func TestDialWithoutAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := sshagent.Dial(""); !errors.Is(err, sshagent.ErrNoAgent) {
+		t.Fatalf("got %v, want ErrNoAgent", err)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package sshconf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	BlockAdded ChangeKind = iota
+	BlockRemoved
+	BlockRenamed
+	OptionAdded
+	OptionRemoved
+	OptionChanged
+)
+
+// Change is a semantic change between two versions of a config.
+type Change struct {
+	Kind ChangeKind
+	// Block is the block changed, as written after the change
+	// but for removed blocks.
+	Block string
+	// From is the former name of a renamed block.
+	From string
+	// Key, Old and New describe option changes.
+	Key string
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case BlockAdded:
+		return "+ " + c.Block
+	case BlockRemoved:
+		return "- " + c.Block
+	case BlockRenamed:
+		return fmt.Sprintf("~ %s → %s", c.From, c.Block)
+	case OptionAdded:
+		return fmt.Sprintf("  %s: + %s %s", c.Block, c.Key, c.New)
+	case OptionRemoved:
+		return fmt.Sprintf("  %s: - %s %s", c.Block, c.Key, c.Old)
+	}
+	return fmt.Sprintf("  %s: %s %s → %s", c.Block, c.Key, c.Old, c.New)
+}
+
+// diffBlock is a block keyed for diffing, repeated
+// blocks are told apart by their occurrence.
+type diffBlock struct {
+	key     string
+	name    string
+	keys    []string
+	options map[string]string
+}
+
+func diffBlocks(blocks []Block) []diffBlock {
+	seen := map[string]int{}
+	out := make([]diffBlock, 0, len(blocks))
+	for _, b := range blocks {
+		name := b.String()
+		seen[name]++
+		d := diffBlock{
+			key:     fmt.Sprintf("%s#%d", name, seen[name]),
+			name:    name,
+			options: map[string]string{},
+		}
+		for _, o := range b.Options {
+			if v, ok := d.options[o.Key]; ok {
+				d.options[o.Key] = v + ", " + o.Value
+				continue
+			}
+			d.keys = append(d.keys, o.Key)
+			d.options[o.Key] = o.Value
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// similarity counts the options a and b have in common.
+func (a diffBlock) similarity(b diffBlock) int {
+	n := 0
+	for k, v := range a.options {
+		if b.options[k] == v {
+			n++
+		}
+	}
+	return n
+}
+
+func (a diffBlock) optionChanges(b diffBlock) []Change {
+	var out []Change
+	for _, k := range a.keys {
+		nv, ok := b.options[k]
+		switch {
+		case !ok:
+			out = append(out, Change{Kind: OptionRemoved, Block: b.name, Key: k, Old: a.options[k]})
+		case nv != a.options[k]:
+			out = append(out, Change{Kind: OptionChanged, Block: b.name, Key: k, Old: a.options[k], New: nv})
+		}
+	}
+	for _, k := range b.keys {
+		if _, ok := a.options[k]; !ok {
+			out = append(out, Change{Kind: OptionAdded, Block: b.name, Key: k, New: b.options[k]})
+		}
+	}
+	return out
+}
+
+// Diff returns the changes from the blocks of old to those of new:
+// blocks added, removed or renamed, and their options changed.
+// A block removed and one added sharing at least half of their
+// options are a rename.
+func Diff(old, new []Block) []Change {
+	before, after := diffBlocks(old), diffBlocks(new)
+	index := map[string]diffBlock{}
+	for _, b := range before {
+		index[b.key] = b
+	}
+	matched := map[string]bool{}
+	var added []diffBlock
+	for _, b := range after {
+		if a, ok := index[b.key]; ok {
+			matched[a.key] = true
+			continue
+		}
+		added = append(added, b)
+	}
+	var removed []diffBlock
+	for _, b := range before {
+		if !matched[b.key] {
+			removed = append(removed, b)
+		}
+	}
+	renamed := map[string]diffBlock{}
+	for _, r := range removed {
+		best, bestSim := -1, 0
+		for i, a := range added {
+			if _, taken := renamed[a.key]; taken {
+				continue
+			}
+			sim := r.similarity(a)
+			if sim > bestSim && 2*sim >= max(len(r.options), len(a.options)) {
+				best, bestSim = i, sim
+			}
+		}
+		if best >= 0 {
+			renamed[added[best].key] = r
+		}
+	}
+
+	var out []Change
+	renamedFrom := map[string]bool{}
+	for _, b := range after {
+		if r, ok := renamed[b.key]; ok {
+			renamedFrom[r.key] = true
+			out = append(out, Change{Kind: BlockRenamed, Block: b.name, From: r.name})
+			out = append(out, r.optionChanges(b)...)
+			continue
+		}
+		if a, ok := index[b.key]; ok {
+			out = append(out, a.optionChanges(b)...)
+			continue
+		}
+		out = append(out, Change{Kind: BlockAdded, Block: b.name})
+	}
+	for _, r := range removed {
+		if !renamedFrom[r.key] {
+			out = append(out, Change{Kind: BlockRemoved, Block: r.name})
+		}
+	}
+	return out
+}
+
+// Summary counts the changes by kind: `1 added, 2 changed`.
+func Summary(changes []Change) string {
+	var added, removed, renamed, options int
+	for _, c := range changes {
+		switch c.Kind {
+		case BlockAdded:
+			added++
+		case BlockRemoved:
+			removed++
+		case BlockRenamed:
+			renamed++
+		default:
+			options++
+		}
+	}
+	var parts []string
+	for _, p := range []struct {
+		n    int
+		what string
+	}{
+		{added, "added"},
+		{removed, "removed"},
+		{renamed, "renamed"},
+		{options, "options changed"},
+	} {
+		if p.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", p.n, p.what))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
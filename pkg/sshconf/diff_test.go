@@ -0,0 +1,108 @@
+package sshconf_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func blocks(t *testing.T, config string) []sshconf.Block {
+	t.Helper()
+	b, err := sshconf.ParseBlocksReader(strings.NewReader(config), "/tmp/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDiff(t *testing.T) {
+	old := blocks(t, `
+Host web
+    HostName 10.0.0.2
+    User deploy
+    Port 22
+Host db
+    HostName 10.0.0.3
+    User postgres
+Host gone
+    HostName 10.0.0.9
+`)
+	new := blocks(t, `
+Host web
+    HostName 10.0.0.2
+    User root
+    ForwardAgent yes
+Host database
+    HostName 10.0.0.3
+    User postgres
+Host new
+    HostName 10.0.0.4
+`)
+	var got []string
+	for _, c := range sshconf.Diff(old, new) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"  Host web: user deploy → root",
+		"  Host web: - port 22",
+		"  Host web: + forwardagent yes",
+		"~ Host db → Host database",
+		"+ Host new",
+		"- Host gone",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if s := sshconf.Summary(sshconf.Diff(old, new)); s != "1 added, 1 removed, 1 renamed, 3 options changed" {
+		t.Errorf("summary %q", s)
+	}
+	if len(sshconf.Diff(old, old)) != 0 {
+		t.Error("changes between equal configs")
+	}
+}
+
+func TestLint(t *testing.T) {
+	before := sshconf.Lint(blocks(t, `
+Host web
+    Port 22
+`))
+	after := sshconf.Lint(blocks(t, `
+Host web
+    Port 22
+    Port 2222
+    IdentityFile ~/.ssh/a
+    IdentityFile ~/.ssh/b
+Host web
+    User root
+`))
+	if len(before) != 0 || len(after) != 2 {
+		t.Fatalf("before %v after %v", before, after)
+	}
+	if after[0].Line != 4 || !strings.Contains(after[0].Message, `the first value "22" wins`) {
+		t.Errorf("got %v", after[0])
+	}
+	if fresh := sshconf.NewDiagnostics(after[:1], after); len(fresh) != 1 || fresh[0].Line != 7 {
+		t.Errorf("new diagnostics %v", fresh)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh not installed")
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("Host web\n    Bogus 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	diags, err := sshconf.Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Line != 2 || !strings.Contains(diags[0].Message, "bogus") {
+		t.Errorf("got %+v", diags)
+	}
+}
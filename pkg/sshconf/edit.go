@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package sshconf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SetOption sets key to value in the block of host inside file,
+// the existing line is replaced or a new one is appended to the block.
+// Keys given more than once, such as IdentityFile, are left with the
+// single new value: the first line is replaced, the others removed.
+// Comments and formatting of the other lines are preserved.
+func SetOption(file, host, key, value string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(b), "\n")
+
+	start := -1
+	for i, line := range lines {
+		f := strings.Fields(removeComments(line))
+		if len(f) >= 2 && strings.EqualFold(f[0], "host") && strings.Join(f[1:], " ") == host {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("host %s not found in %s", host, file)
+	}
+
+	indent := "    "
+	last := start
+	set := false
+	out := append([]string{}, lines[:start+1]...)
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		f := strings.Fields(removeComments(trimmed))
+		if len(f) > 0 && (strings.EqualFold(f[0], "host") || strings.EqualFold(f[0], "match")) {
+			break
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, commentPrefix) {
+			out = append(out, lines[i])
+			continue
+		}
+		indent = lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+		if len(f) > 0 && strings.EqualFold(f[0], key) {
+			if set {
+				continue
+			}
+			set = true
+			lines[i] = indent + key + " " + value
+		}
+		out = append(out, lines[i])
+		last = len(out) - 1
+	}
+	if !set {
+		out = append(out[:last+1], append([]string{indent + key + " " + value}, out[last+1:]...)...)
+	}
+	return writeFile(file, append(out, lines[i:]...))
+}
+
+func writeFile(file string, lines []string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
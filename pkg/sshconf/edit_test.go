@@ -0,0 +1,34 @@
+package sshconf_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func TestSetOption(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	in := "Host a\n#tag: web\n    User root # admin\n\nHost b\n\tHostName b.example.com\n\nHost d\n    IdentityFile ~/.ssh/id_old\n    # backup key\n    IdentityFile ~/.ssh/id_backup\n    User d\n"
+	if err := os.WriteFile(file, []byte(in), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshconf.SetOption(file, "a", "IdentityFile", "~/.ssh/id_a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshconf.SetOption(file, "b", "HostName", "b.example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshconf.SetOption(file, "d", "IdentityFile", "~/.ssh/id_new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshconf.SetOption(file, "c", "User", "x"); err == nil {
+		t.Fatal("want error for a missing host")
+	}
+	b, _ := os.ReadFile(file)
+	want := "Host a\n#tag: web\n    User root # admin\n    IdentityFile ~/.ssh/id_a\n\nHost b\n\tHostName b.example.org\n\nHost d\n    IdentityFile ~/.ssh/id_new\n    # backup key\n    User d\n"
+	if string(b) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b, want)
+	}
+}
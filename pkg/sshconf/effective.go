@@ -0,0 +1,463 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package sshconf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SystemConfig is the system-wide config, ssh skips it with -F.
+var SystemConfig = "/etc/ssh/ssh_config"
+
+// Block is a Host or Match block of a config file, options
+// before the first block are in a block without conditions.
+type Block struct {
+	// Hosts are the patterns of a Host block.
+	Hosts []string
+	// Match are the criteria of a Match block.
+	Match   string
+	File    string
+	Line    int
+	Options []Option
+}
+
+// Option is an option of a block, keys are lowercase.
+type Option struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// String describes b as written.
+func (b Block) String() string {
+	switch {
+	case b.Match != "":
+		return "Match " + b.Match
+	case len(b.Hosts) > 0:
+		return "Host " + strings.Join(b.Hosts, " ")
+	}
+	return "global"
+}
+
+// ParseBlocks returns the blocks of the config file at path
+// in order, following Include.
+func ParseBlocks(path string) ([]Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBlocks(f, path, path == SystemConfig, Block{}, 0)
+}
+
+// ParseBlocksReader returns the blocks of a user config read from r,
+// path locates it.
+func ParseBlocksReader(r io.Reader, path string) ([]Block, error) {
+	return parseBlocks(r, path, false, Block{}, 0)
+}
+
+// includePattern returns the glob of an Include argument: relative
+// paths are in ~/.ssh for user configs, in /etc/ssh for system
+// ones, wherever the including file is.
+func includePattern(arg string, system bool) string {
+	arg = ExpandPath(arg)
+	if filepath.IsAbs(arg) {
+		return arg
+	}
+	if system {
+		return filepath.Join(filepath.Dir(SystemConfig), arg)
+	}
+	return filepath.Join(ExpandPath("~/.ssh"), arg)
+}
+
+// parseBlocks reads the config at path from r, its options before
+// any block belong to parent: the block of the Include line.
+// The files included by a system config are system configs too.
+func parseBlocks(r io.Reader, path string, system bool, parent Block, depth int) ([]Block, error) {
+	if depth > 16 {
+		return nil, fmt.Errorf("%s: too many nested includes", path)
+	}
+	cur := Block{Hosts: parent.Hosts, Match: parent.Match, File: path}
+	var blocks []Block
+	flush := func() {
+		if len(cur.Options) > 0 {
+			blocks = append(blocks, cur)
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, commentPrefix) && !isMeta(line) {
+			continue
+		}
+		k, v := splitOption(line)
+		if !isMeta(line) {
+			v = removeComments(v)
+		}
+		if k == "" || v == "" {
+			continue
+		}
+		switch k {
+		case "host", "match":
+			flush()
+			cur = Block{File: path, Line: n}
+			if k == "host" {
+				cur.Hosts = strings.Fields(v)
+			} else {
+				cur.Match = v
+			}
+			continue
+		case "include":
+			flush()
+			for _, arg := range strings.Fields(v) {
+				paths, err := filepath.Glob(includePattern(arg, system))
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range paths {
+					included, err := parseInclude(p, system, cur, depth+1)
+					if err != nil {
+						return nil, err
+					}
+					blocks = append(blocks, included...)
+				}
+			}
+			// the block goes on after the included files
+			cur = Block{Hosts: cur.Hosts, Match: cur.Match, File: path, Line: cur.Line}
+			continue
+		}
+		cur.Options = append(cur.Options, Option{Key: k, Value: v, Line: n})
+	}
+	flush()
+	return blocks, scanner.Err()
+}
+
+func parseInclude(path string, system bool, parent Block, depth int) ([]Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBlocks(f, path, system, parent, depth)
+}
+
+// splitOption splits `Key value` and `Key=value` lines,
+// keys are lowercased but metadata comments.
+func splitOption(line string) (string, string) {
+	if isMeta(line) {
+		k, v, _ := strings.Cut(line, " ")
+		return strings.ToLower(k), strings.TrimSpace(v)
+	}
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), ""
+	}
+	k, v := line[:i], strings.TrimLeft(line[i:], " \t")
+	v = strings.TrimLeft(strings.TrimPrefix(v, "="), " \t")
+	return strings.ToLower(k), v
+}
+
+// Source tells where an effective option comes from.
+type Source int
+
+const (
+	// Explicit options are written in the Host block of the host.
+	Explicit Source = iota
+	// Inherited options come from Host patterns, Match blocks,
+	// options before the first block or the system config.
+	Inherited
+	// Default options are the ssh defaults, as printed by ssh -G.
+	Default
+)
+
+func (s Source) String() string {
+	switch s {
+	case Explicit:
+		return "explicit"
+	case Inherited:
+		return "inherited"
+	}
+	return "default"
+}
+
+// Setting is an option in effect for a host.
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+	// Block sets the option, nil for defaults.
+	Block *Block
+	Line  int
+}
+
+// From describes where s is set: `Host * ~/.ssh/config:12`.
+func (s Setting) From() string {
+	if s.Block == nil {
+		return "ssh default"
+	}
+	return fmt.Sprintf("%s %s:%d", s.Block, tildePath(s.Block.File), s.Line)
+}
+
+func tildePath(path string) string {
+	if home, err := os.UserHomeDir(); err == nil {
+		if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+			return "~/" + rest
+		}
+	}
+	return path
+}
+
+// multiValued options add up instead of the first value winning.
+var multiValued = []string{
+	"identityfile", "certificatefile", "localforward",
+	"remoteforward", "dynamicforward", "sendenv", "setenv",
+}
+
+// Effective returns the options in effect for the host called name,
+// with ssh semantics: blocks apply in order when their patterns
+// or criteria match, the first value of an option wins.
+func Effective(name string, blocks []Block) []Setting {
+	var out []Setting
+	seen := map[string]bool{}
+	target := matchTarget{original: name, host: name}
+	for i := range blocks {
+		b := &blocks[i]
+		if !target.matches(b) {
+			continue
+		}
+		explicit := slices.Contains(b.Hosts, name) || strings.Join(b.Hosts, " ") == name
+		for _, o := range b.Options {
+			if strings.HasPrefix(o.Key, commentPrefix) && !explicit {
+				// metadata belongs to the hosts of its block
+				continue
+			}
+			if seen[o.Key] && !slices.Contains(multiValued, o.Key) {
+				continue
+			}
+			seen[o.Key] = true
+			source := Inherited
+			if explicit {
+				source = Explicit
+			}
+			out = append(out, Setting{Key: o.Key, Value: o.Value, Source: source, Block: b, Line: o.Line})
+			switch o.Key {
+			case "hostname":
+				target.host = strings.ReplaceAll(o.Value, "%h", name)
+			case "user":
+				target.user = o.Value
+			case "tag":
+				target.tag = o.Value
+			}
+		}
+	}
+	return out
+}
+
+// resolvedKeys are the options the Host accessors read with
+// ssh semantics, from Host patterns and Match blocks too.
+var resolvedKeys = []string{
+	"hostname", "port", "user", "proxyjump", "proxycommand", "identityfile",
+	"hostkeyalias", "userknownhostsfile", "globalknownhostsfile", "controlpath",
+}
+
+// proxyOther pairs the proxy options excluding each other.
+var proxyOther = map[string]string{"proxyjump": "proxycommand", "proxycommand": "proxyjump"}
+
+// resolve sets the effective values of resolvedKeys on the hosts,
+// the system config isn't read: connectors pass -F.
+func (c *Config) resolve() {
+	blocks, err := c.Blocks(false)
+	if err != nil {
+		// the accessors fall back to the Host block
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.Hosts {
+		h := &c.Hosts[i]
+		h.resolved = map[string][]string{}
+		for _, s := range Effective(h.Name, blocks) {
+			if !slices.Contains(resolvedKeys, s.Key) {
+				continue
+			}
+			// ssh takes the first of ProxyJump and ProxyCommand,
+			// `ProxyJump none` included
+			if _, ok := h.resolved[proxyOther[s.Key]]; ok {
+				continue
+			}
+			h.resolved[s.Key] = append(h.resolved[s.Key], s.Value)
+		}
+	}
+}
+
+// matchTarget holds what Host and Match criteria match against,
+// updated as options are read like ssh does.
+type matchTarget struct {
+	original string
+	host     string
+	user     string
+	tag      string
+}
+
+func (t matchTarget) matches(b *Block) bool {
+	switch {
+	case b.Match != "":
+		return t.matchCriteria(b.Match)
+	case len(b.Hosts) > 0:
+		return matchList(b.Hosts, t.original) || strings.Join(b.Hosts, " ") == t.original
+	}
+	return true
+}
+
+// matchCriteria evaluates the criteria of a Match block, exec and
+// localnetwork aren't evaluated: their blocks don't apply.
+func (t matchTarget) matchCriteria(criteria string) bool {
+	fields := strings.Fields(criteria)
+	for i := 0; i < len(fields); i++ {
+		c := strings.ToLower(fields[i])
+		negate := strings.HasPrefix(c, "!")
+		c = strings.TrimPrefix(c, "!")
+		var ok bool
+		switch c {
+		case "all", "canonical", "final":
+			ok = true
+		default:
+			if i+1 >= len(fields) {
+				return false
+			}
+			i++
+			patterns := strings.Split(fields[i], ",")
+			switch c {
+			case "host":
+				ok = matchList(patterns, t.host)
+			case "originalhost":
+				ok = matchList(patterns, t.original)
+			case "user":
+				u := t.user
+				if u == "" {
+					u = localUser()
+				}
+				ok = matchList(patterns, u)
+			case "localuser":
+				ok = matchList(patterns, localUser())
+			case "tagged":
+				ok = matchList(patterns, t.tag)
+			default:
+				return false
+			}
+		}
+		if ok == negate {
+			return false
+		}
+	}
+	return true
+}
+
+func localUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// matchList reports whether s matches any pattern and no
+// negated `!pattern` of patterns.
+func matchList(patterns []string, s string) bool {
+	matched := false
+	for _, p := range patterns {
+		if neg, ok := strings.CutPrefix(p, "!"); ok {
+			if matchPattern(neg, s) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(p, s) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern matches s against a pattern of `*` and `?` wildcards.
+func matchPattern(p, s string) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if matchPattern(p[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || !strings.EqualFold(p[:1], s[:1]) {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return s == ""
+}
+
+// Defaults returns the ssh defaults missing from settings, as
+// printed by `ssh -G -F config name`.
+func Defaults(config, name string, settings []Setting) ([]Setting, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", "-G", "-F", config, name)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh -G %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	set := map[string]bool{}
+	for _, s := range settings {
+		set[s.Key] = true
+	}
+	var defaults []Setting
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		k, v, _ := strings.Cut(scanner.Text(), " ")
+		// ssh -G prints the host it resolved as `host`
+		k = strings.ToLower(k)
+		if k == "" || k == "host" || set[k] {
+			continue
+		}
+		defaults = append(defaults, Setting{Key: k, Value: v, Source: Default})
+	}
+	return defaults, scanner.Err()
+}
+
+// Blocks returns the blocks of the config file, then of the
+// system config when system is set. They're read once per parse.
+func (c *Config) Blocks(system bool) ([]Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blocks == nil {
+		blocks, err := ParseBlocks(c.path)
+		if err != nil {
+			return nil, err
+		}
+		c.blocks = blocks
+		if sys, err := ParseBlocks(SystemConfig); err == nil {
+			c.systemBlocks = sys
+		}
+	}
+	if !system {
+		return c.blocks, nil
+	}
+	return append(slices.Clip(c.blocks), c.systemBlocks...), nil
+}
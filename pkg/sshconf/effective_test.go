@@ -0,0 +1,132 @@
+package sshconf_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+)
+
+func TestEffective(t *testing.T) {
+	// relative includes are read in ~/.ssh
+	t.Setenv("HOME", t.TempDir())
+	dir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	files := map[string]string{
+		"config": `
+ServerAliveInterval 30
+
+Host web
+    #tag: prod
+    HostName 10.0.0.2
+    IdentityFile ~/.ssh/web
+    Include conf.d/*
+
+Host *.internal web
+    User deploy
+    Port 2222
+
+Match host 10.0.0.* !user root
+    ForwardAgent yes
+
+Match exec "test -f /nope"
+    Compression yes
+
+Host !web *
+    User nobody
+    IdentityFile ~/.ssh/default
+Host *
+    IdentityFile=~/.ssh/all
+    ServerAliveInterval 5
+`,
+		"conf.d/web": "ProxyJump bastion\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocks, err := sshconf.ParseBlocks(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]sshconf.Setting{}
+	for _, s := range sshconf.Effective("web", blocks) {
+		got[s.Key] = append(got[s.Key], s)
+	}
+	for _, c := range []struct {
+		key, value string
+		source     sshconf.Source
+		from       string
+	}{
+		{"serveraliveinterval", "30", sshconf.Inherited, "global"},
+		{"#tag:", "prod", sshconf.Explicit, "Host web"},
+		{"hostname", "10.0.0.2", sshconf.Explicit, "Host web"},
+		{"proxyjump", "bastion", sshconf.Explicit, "Host web"},
+		{"user", "deploy", sshconf.Explicit, "Host *.internal web"},
+		{"port", "2222", sshconf.Explicit, "Host *.internal web"},
+		{"forwardagent", "yes", sshconf.Inherited, "Match host 10.0.0.* !user root"},
+		{"compression", "", 0, ""},
+	} {
+		s := got[c.key]
+		if c.value == "" {
+			if len(s) != 0 {
+				t.Errorf("%s: got %+v, want unset", c.key, s)
+			}
+			continue
+		}
+		if len(s) != 1 || s[0].Value != c.value || s[0].Source != c.source || s[0].Block.String() != c.from {
+			t.Errorf("%s: got %+v, want %s %s from %s", c.key, s, c.value, c.source, c.from)
+		}
+	}
+	ids := got["identityfile"]
+	if len(ids) != 2 || ids[0].Value != "~/.ssh/web" || ids[1].Value != "~/.ssh/all" || ids[1].Source != sshconf.Inherited {
+		t.Errorf("identityfile: %+v", ids)
+	}
+
+	other := sshconf.Effective("db", blocks)
+	if len(other) == 0 || other[1].Key != "user" || other[1].Value != "nobody" {
+		t.Errorf("db: %+v", other)
+	}
+}
+
+func TestHostResolved(t *testing.T) {
+	cfg := sshconftest.Parse(t, `
+Host app.corp
+    #tag: web
+
+Host direct.corp
+    ProxyJump none
+    User root
+    ControlPath none
+
+Match originalhost app.corp
+    User deploy
+
+Host *.corp
+    HostName %h.example.com
+    Port 2200
+    ProxyJump bastion
+    User nobody
+    ControlPath ~/.ssh/cm-%C
+`)
+	for _, tc := range []struct {
+		host, hostname, port, user, jump, control string
+	}{
+		{"app.corp", "app.corp.example.com", "2200", "deploy", "bastion", "~/.ssh/cm-%C"},
+		{"direct.corp", "direct.corp.example.com", "2200", "root", "", ""},
+	} {
+		h := cfg.GetHost(tc.host)
+		if h.HostName() != tc.hostname || h.Port() != tc.port || h.User() != tc.user || h.ProxyJump() != tc.jump {
+			t.Errorf("%s: %s:%s user %q jump %q", tc.host, h.HostName(), h.Port(), h.User(), h.ProxyJump())
+		}
+		if h.ControlPath() != tc.control {
+			t.Errorf("%s: ControlPath %q", tc.host, h.ControlPath())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package sshconf
+
+import (
+	"strings"
+)
+
+// Get returns the value of option key, keys are case-insensitive.
+func (h Host) Get(key string) string {
+	if h.Options == nil {
+		return ""
+	}
+	v, _ := h.Options.Get(strings.ToLower(key))
+	return v
+}
+
+// lookup returns the value of option key in effect for h, inherited
+// ones included, or the one of its Host block when not resolved.
+func (h Host) lookup(key string) string {
+	if h.resolved != nil {
+		if v := h.resolved[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	return h.Get(key)
+}
+
+// HostName returns the HostName in effect, with `%h` expanded,
+// or the Host alias when missing.
+func (h Host) HostName() string {
+	if v := h.lookup("hostname"); v != "" {
+		return strings.ReplaceAll(v, "%h", h.Name)
+	}
+	return h.Name
+}
+
+// Port returns the Port in effect or the ssh default.
+func (h Host) Port() string {
+	if v := h.lookup("port"); v != "" {
+		return v
+	}
+	return "22"
+}
+
+// User returns the User in effect.
+func (h Host) User() string {
+	return h.lookup("user")
+}
+
+// ProxyJump returns the ProxyJump in effect, "none" is treated as unset.
+func (h Host) ProxyJump() string {
+	v := h.lookup("proxyjump")
+	if strings.EqualFold(v, "none") {
+		return ""
+	}
+	return v
+}
+
+// ProxyCommand returns the ProxyCommand in effect, "none" is treated
+// as unset. A ProxyJump, even `none`, disables it when set first.
+func (h Host) ProxyCommand() string {
+	if h.resolved == nil && h.Get("proxyjump") != "" {
+		return ""
+	}
+	v := h.lookup("proxycommand")
+	if strings.EqualFold(v, "none") {
+		return ""
+	}
+	return v
+}
+
+// IdentityFiles returns the IdentityFile options in effect, in the
+// order ssh tries them, unexpanded.
+func (h Host) IdentityFiles() []string {
+	if h.resolved != nil {
+		return h.resolved["identityfile"]
+	}
+	if v := h.Get("identityfile"); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+// ControlPath returns the ControlPath in effect, "none" is treated as unset.
+func (h Host) ControlPath() string {
+	v := h.lookup("controlpath")
+	if strings.EqualFold(v, "none") {
+		return ""
+	}
+	return v
+}
+
+// HostKeyAlias returns the HostKeyAlias in effect.
+func (h Host) HostKeyAlias() string {
+	return h.lookup("hostkeyalias")
+}
+
+// KnownHostsFiles returns the UserKnownHostsFile and
+// GlobalKnownHostsFile in effect, unexpanded.
+func (h Host) KnownHostsFiles() (user, global string) {
+	return h.lookup("userknownhostsfile"), h.lookup("globalknownhostsfile")
+}
+
+// Tags returns the comma separated values of the `#tag:` key.
+func (h Host) Tags() []string {
+	var tags []string
+	for _, t := range strings.Split(h.Get(tagPrefix), ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// Meta returns the `#key: value` metadata comments of h
+// keyed by lowercase name, `#tag:` included. User defined
+// `#ssm-key:` comments are keyed without their prefix.
+func (h Host) Meta() map[string]string {
+	meta := map[string]string{}
+	if h.Options == nil {
+		return meta
+	}
+	for _, k := range h.Options.Keys() {
+		if strings.HasPrefix(k, commentPrefix) && strings.HasSuffix(k, ":") {
+			name := strings.TrimPrefix(strings.Trim(k, "#:"), metaPrefix)
+			meta[name], _ = h.Options.Get(k)
+		}
+	}
+	return meta
+}
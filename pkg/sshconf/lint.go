@@ -0,0 +1,117 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package sshconf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a problem of a config file.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+// Lint reports the mistakes ssh accepts silently: options repeated
+// in a block, only their first value is used, and host blocks
+// written twice.
+func Lint(blocks []Block) []Diagnostic {
+	var out []Diagnostic
+	hosts := map[string]Block{}
+	for _, b := range blocks {
+		first := map[string]Option{}
+		for _, o := range b.Options {
+			if strings.HasPrefix(o.Key, commentPrefix) || slices.Contains(multiValued, o.Key) {
+				continue
+			}
+			if f, ok := first[o.Key]; ok {
+				out = append(out, Diagnostic{
+					File: b.File, Line: o.Line,
+					Message: fmt.Sprintf("%s repeated in %s, the first value %q wins", o.Key, b, f.Value),
+				})
+				continue
+			}
+			first[o.Key] = o
+		}
+		if len(b.Hosts) == 0 || strings.ContainsAny(strings.Join(b.Hosts, ""), "*?!") {
+			continue
+		}
+		name := b.String()
+		if f, ok := hosts[name]; ok {
+			out = append(out, Diagnostic{
+				File: b.File, Line: b.Line,
+				Message: fmt.Sprintf("%s written twice, first at line %d", name, f.Line),
+			})
+			continue
+		}
+		hosts[name] = b
+	}
+	return out
+}
+
+// sshError matches the config errors of ssh:
+// `/path line 2: no argument after keyword "port"`.
+var sshError = regexp.MustCompile(`^(.+?):? line (\d+): (.+)$`)
+
+// Check returns the errors ssh finds parsing the config at path,
+// running `ssh -G`. It fails when ssh can't run.
+func Check(path string) ([]Diagnostic, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", "-G", "-F", path, "ssm-check.invalid")
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		return nil, fmt.Errorf("ssh -G: %w", err)
+	}
+	var out []Diagnostic
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.Contains(line, ": terminating, ") {
+			continue
+		}
+		d := Diagnostic{File: path, Message: line}
+		if m := sshError.FindStringSubmatch(line); m != nil {
+			d.File, d.Message = m[1], m[3]
+			d.Line, _ = strconv.Atoi(m[2])
+		}
+		out = append(out, d)
+	}
+	return out, scanner.Err()
+}
+
+// NewDiagnostics returns the diagnostics of after missing from
+// before, compared by message: lines move while editing.
+func NewDiagnostics(before, after []Diagnostic) []Diagnostic {
+	count := map[string]int{}
+	for _, d := range before {
+		count[d.Message]++
+	}
+	var out []Diagnostic
+	for _, d := range after {
+		if count[d.Message] > 0 {
+			count[d.Message]--
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
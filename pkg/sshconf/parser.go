@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
@@ -24,11 +25,19 @@ type Config struct {
 
 	order Order
 	path  string
+	// blocks are read by Blocks, cleared on parse.
+	blocks       []Block
+	systemBlocks []Block
 }
 
 type Host struct {
 	Name    string
 	Options *som.SafeOrderedMap[string]
+	// File is the config file defining the host.
+	File string
+	// resolved holds the values of resolvedKeys in effect,
+	// inherited from other blocks included, set on parse.
+	resolved map[string][]string
 }
 
 // Order defines how hosts are organized when parsed.
@@ -55,7 +64,11 @@ func (c *Config) Parse() error {
 	if err != nil {
 		return err
 	}
-	return c.parse(path)
+	if err := c.parse(path); err != nil {
+		return err
+	}
+	c.resolve()
+	return nil
 }
 
 // Parse parses SSH config file from custom location.
@@ -67,7 +80,11 @@ func (c *Config) ParsePath(s string) error {
 		}
 		s = filepath.Join(wd, s)
 	}
-	return c.parse(s)
+	if err := c.parse(s); err != nil {
+		return err
+	}
+	c.resolve()
+	return nil
 }
 
 func (c *Config) GetHost(name string) Host {
@@ -102,8 +119,14 @@ const (
 	commentPrefix  = "#"
 	tagPrefix      = "#tag:"
 	tagOrderPrefix = "#tagorder"
+	// metaPrefix starts the user defined `#ssm-key: value` metadata.
+	metaPrefix = "ssm-"
 )
 
+// metaKeys are the `#key: value` comments read by ssm,
+// other comments are left alone.
+var metaKeys = []string{"tag", "connector", "secret", "record", "pre-connect", "post-connect"}
+
 func (c *Config) parse(path string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -112,6 +135,7 @@ func (c *Config) parse(path string) error {
 	// called multiple times.
 	c.Hosts = []Host{}
 	c.secondaryHosts = []Host{}
+	c.blocks = nil
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -136,7 +160,7 @@ func (c *Config) parse(path string) error {
 		// ignore empty or comment line
 		if line == "" ||
 			strings.HasPrefix(line, commentPrefix) &&
-				!strings.HasPrefix(line, tagPrefix) {
+				!isMeta(line) {
 			continue
 		}
 		parts := strings.Fields(line)
@@ -146,42 +170,44 @@ func (c *Config) parse(path string) error {
 		}
 		k, v := strings.ToLower(parts[0]), strings.Join(parts[1:], " ")
 		// remove comment suffixes
-		// when not a tag
-		if !strings.HasPrefix(line, tagPrefix) {
+		// when not metadata
+		if !isMeta(line) {
 			k = removeComments(k)
 			v = removeComments(v)
 		}
 		// recurse include files
 		if k == "include" {
-			if !strings.HasPrefix(v, "/") {
-				path = filepath.Dir(path)
-				v = filepath.Join(path, v)
-			}
-			paths, err := filepath.Glob(v)
-			if err != nil {
-				return err
-			}
-
-			for _, path := range paths {
-				cfg := New()
-				err := cfg.parse(path) // recursion
+			for _, arg := range strings.Fields(v) {
+				paths, err := filepath.Glob(includePattern(arg, false))
 				if err != nil {
 					return err
 				}
-				c.Hosts = append(c.Hosts, cfg.Hosts...)
+
+				for _, path := range paths {
+					cfg := New()
+					err := cfg.parse(path) // recursion
+					if err != nil {
+						return err
+					}
+					c.Hosts = append(c.Hosts, cfg.Hosts...)
+				}
 			}
 		}
 		// all blocks must start with Host key
-		if k == "host" {
-			if strings.Contains(v, "*") {
-				continue
-			}
+		if k == "host" || k == "match" {
 			if currentHost != nil {
 				newHost(tagOrder, currentHost, c)
+				currentHost = nil
+			}
+			// pattern and Match blocks aren't hosts, their
+			// options are resolved with Effective
+			if k == "match" || strings.ContainsAny(v, "*?!") {
+				continue
 			}
 			currentHost = &Host{
 				Name:    v,
 				Options: som.New[string](),
+				File:    c.path,
 			}
 			continue
 		}
@@ -212,6 +238,29 @@ func newHost(tagOrder bool, currentHost *Host, config *Config) {
 	config.Hosts = append(config.Hosts, *currentHost)
 }
 
+// isMeta reports a `#key: value` metadata comment: one of metaKeys
+// or a key prefixed with metaPrefix.
+func isMeta(line string) bool {
+	key, _, ok := strings.Cut(line, " ")
+	if !ok || len(key) < 3 || key[0] != '#' || key[len(key)-1] != ':' {
+		return false
+	}
+	name := strings.ToLower(key[1 : len(key)-1])
+	if slices.Contains(metaKeys, name) {
+		return true
+	}
+	name, ok = strings.CutPrefix(name, metaPrefix)
+	if !ok || name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 func removeComments(input string) string {
 	// find index of '#' and take substring up to that point
 	if index := strings.Index(input, "#"); index != -1 {
@@ -3,9 +3,14 @@ package sshconf_test
 import (
 	"fmt"
 	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
 )
 
 func TestParse(t *testing.T) {
@@ -28,3 +33,104 @@ func TestParse(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestMeta(t *testing.T) {
+	cfg := sshconftest.Parse(t, `
+Host web
+    #tag: prod
+    #Connector: mosh
+    #ssm-service: nginx
+    #ssm-db_name: orders
+    #note: rebuilt in march, ask ops
+    #pass: segfault
+    #ssm-: empty
+    HostName web.example.com
+`)
+	h := cfg.GetHost("web")
+	meta := h.Meta()
+	want := map[string]string{"tag": "prod", "connector": "mosh", "service": "nginx", "db_name": "orders"}
+	if !maps.Equal(meta, want) {
+		t.Errorf("meta: %v, want %v", meta, want)
+	}
+	if _, ok := h.Options.Get("#ssm-service:"); !ok {
+		t.Error("metadata not kept as option")
+	}
+	// plain comments aren't options
+	for _, k := range []string{"#note:", "#pass:", "#ssm-:"} {
+		if _, ok := h.Options.Get(k); ok {
+			t.Errorf("comment %s parsed as option", k)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	other := t.TempDir()
+	files := map[string]string{
+		// relative includes are in ~/.ssh wherever the config is
+		filepath.Join(other, "config"):            "Include conf.d/a conf.d/b\nInclude ~/extra\n",
+		filepath.Join(home, ".ssh/conf.d/a"):      "Host a\n    HostName a.example.com\n",
+		filepath.Join(home, ".ssh/conf.d/b"):      "Host b\n    HostName b.example.com\nInclude nested\n",
+		filepath.Join(home, ".ssh/nested"):        "Host nested\n    HostName nested.example.com\n",
+		filepath.Join(home, ".ssh/conf.d/nested"): "Host wrong\n    HostName wrong.example.com\n",
+		filepath.Join(home, "extra"):              "Host extra\n    HostName extra.example.com\n",
+		filepath.Join(other, "conf.d/a"):          "Host wrong\n    HostName wrong.example.com\n",
+	}
+	for path, body := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := sshconf.New()
+	if err := cfg.ParsePath(filepath.Join(other, "config")); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, h := range cfg.Hosts {
+		names = append(names, h.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"a", "b", "extra", "nested"}; !slices.Equal(names, want) {
+		t.Errorf("hosts %v, want %v", names, want)
+	}
+}
+
+func TestPatternBlocks(t *testing.T) {
+	cfg := sshconftest.Parse(t, `
+Host web
+    Port 2222
+
+Host *.corp !db.corp
+    User deploy
+
+Host db
+    HostName db.example.com
+
+Match host db
+    ForwardAgent yes
+
+Host app?
+    Compression yes
+`)
+	// pattern and Match blocks aren't hosts
+	var names []string
+	for _, h := range cfg.Hosts {
+		names = append(names, h.Name)
+	}
+	if want := []string{"web", "db"}; !slices.Equal(names, want) {
+		t.Fatalf("hosts %v, want %v", names, want)
+	}
+	// nor do their options belong to the host before them
+	for host, key := range map[string]string{"web": "user", "db": "forwardagent"} {
+		if _, ok := cfg.GetHost(host).Options.Get(key); ok {
+			t.Errorf("%s: inherited %s parsed as explicit", host, key)
+		}
+	}
+	if _, ok := cfg.GetHost("db").Options.Get("match"); ok {
+		t.Error("db: Match parsed as an option")
+	}
+}
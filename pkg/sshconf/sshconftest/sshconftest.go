@@ -0,0 +1,29 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package sshconftest parses SSH configs for tests.
+package sshconftest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+// Parse writes config to a temporary file and parses it,
+// failing t on errors. The file stays on disk for the
+// readers of GetPath, such as Effective.
+func Parse(t testing.TB, config string) *sshconf.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := sshconf.New()
+	if err := c.ParsePath(path); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func defaultConfigPath() (string, error) {
@@ -38,3 +39,15 @@ func fileExists(path string) bool {
 	// file exists
 	return true
 }
+
+// ExpandPath expands a leading `~` to the user home directory.
+func ExpandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
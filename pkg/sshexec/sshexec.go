@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package sshexec runs ssh in BatchMode: it never prompts,
+// failures report the last line ssh printed.
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Args returns the ssh arguments running args in BatchMode with
+// the config at configPath, a timeout > 0 bounds the connection.
+func Args(configPath string, timeout time.Duration, args ...string) []string {
+	a := []string{"-o", "BatchMode=yes"}
+	if timeout > 0 {
+		a = append(a, "-o", fmt.Sprintf("ConnectTimeout=%d", max(1, int(timeout.Seconds()))))
+	}
+	if configPath != "" {
+		a = append(a, "-F", configPath)
+	}
+	return append(a, args...)
+}
+
+// Run runs ssh with Args and returns its standard output and error.
+// A timeout > 0 stops ssh once elapsed.
+func Run(ctx context.Context, configPath string, timeout time.Duration, args ...string) (stdout, stderr []byte, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "ssh", Args(configPath, timeout, args...)...)
+	// don't wait forever on processes holding the output open
+	cmd.WaitDelay = time.Second
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	err = cmd.Run()
+	if timeout > 0 && ctx.Err() != nil {
+		return out.Bytes(), errOut.Bytes(), fmt.Errorf("timed out after %v", timeout)
+	}
+	return out.Bytes(), errOut.Bytes(), Err(errOut.Bytes(), err)
+}
+
+// Batch runs script on host without a terminal and returns its output.
+// The login shell of the host may not be a POSIX one: script runs in sh.
+func Batch(ctx context.Context, configPath, host, script string, timeout time.Duration) ([]byte, error) {
+	stdout, _, err := Run(ctx, configPath, timeout, "-T", host, "sh -c "+quote(script))
+	return stdout, err
+}
+
+// Err returns the last line of stderr as the error of a failed ssh,
+// err when stderr is empty.
+func Err(stderr []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSpace(string(stderr)), "\n")
+	if msg := strings.TrimSpace(lines[len(lines)-1]); msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
+
+// quote quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,82 @@
+package sshexec_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshexec"
+)
+
+// fakeSSH puts an ssh in PATH running the command locally,
+// hosts named down fail and the arguments go to $ARGS.
+func fakeSSH(t *testing.T) (args string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	args = filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > \"$ARGS\"\nfor last; do :; done\n" +
+		"case \"$*\" in *down*) echo 'debug1: trying' >&2; echo 'ssh: connect to host down port 22: Connection refused' >&2; exit 255;; esac\n" +
+		"exec sh -c \"$last\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("ARGS", args)
+	return args
+}
+
+func TestBatch(t *testing.T) {
+	args := fakeSSH(t)
+	ctx := context.Background()
+
+	out, err := sshexec.Batch(ctx, "/tmp/config", "web", `echo "it's $0"`, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "it's sh" {
+		t.Errorf("output: %q", got)
+	}
+	b, _ := os.ReadFile(args)
+	want := "-o BatchMode=yes -o ConnectTimeout=5 -F /tmp/config -T web sh -c "
+	if !strings.HasPrefix(string(b), want) {
+		t.Errorf("args: %q, want prefix %q", b, want)
+	}
+
+	_, err = sshexec.Batch(ctx, "", "down", "true", 5*time.Second)
+	if err == nil || err.Error() != "ssh: connect to host down port 22: Connection refused" {
+		t.Errorf("down: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fakeSSH(t)
+	start := time.Now()
+	_, err := sshexec.Batch(context.Background(), "", "web", "sleep 10", 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timed out after %s", elapsed)
+	}
+}
+
+func TestRunWithoutTimeout(t *testing.T) {
+	args := fakeSSH(t)
+	_, stderr, err := sshexec.Run(context.Background(), "", 0, "-O", "check", "sh -c 'echo Master running >&2'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(stderr)) != "Master running" {
+		t.Errorf("stderr: %q", stderr)
+	}
+	if b, _ := os.ReadFile(args); strings.Contains(string(b), "ConnectTimeout") {
+		t.Errorf("args: %q", b)
+	}
+}
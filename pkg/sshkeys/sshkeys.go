@@ -0,0 +1,243 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package sshkeys inspects the keys in ~/.ssh and the hosts using them,
+// keys are generated and deployed with the installed ssh tools.
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"golang.org/x/crypto/ssh"
+)
+
+// Key is a key pair found in the keys directory,
+// either half may be missing.
+type Key struct {
+	Path        string // private key
+	PubPath     string
+	Type        string
+	Bits        int
+	Fingerprint string
+	Comment     string
+	// Encrypted reports a passphrase protected private key.
+	Encrypted bool
+	ModTime   time.Time
+	// Hosts lists the Host aliases using the key as IdentityFile.
+	Hosts []string
+	// Pub is the authorized_keys line of the public key.
+	Pub string
+}
+
+// Name is the key file name without extension.
+func (k Key) Name() string {
+	if k.Path != "" {
+		return filepath.Base(k.Path)
+	}
+	return strings.TrimSuffix(filepath.Base(k.PubPath), ".pub")
+}
+
+// Age returns how old the key is.
+func (k Key) Age() time.Duration {
+	return time.Since(k.ModTime)
+}
+
+// DefaultDir returns ~/.ssh.
+func DefaultDir() string {
+	return sshconf.ExpandPath("~/.ssh")
+}
+
+// List returns the keys in dir sorted by name, with the hosts of
+// config referencing them.
+func List(dir string, config *sshconf.Config) ([]Key, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]*Key{}
+	get := func(name string) *Key {
+		if k, ok := keys[name]; ok {
+			return k
+		}
+		keys[name] = &Key{}
+		return keys[name]
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		b, err := os.ReadFile(path)
+		if err != nil || len(b) > 64*1024 {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(f.Name(), ".pub"):
+			pub, comment, _, _, err := ssh.ParseAuthorizedKey(b)
+			if err != nil {
+				continue
+			}
+			k := get(strings.TrimSuffix(path, ".pub"))
+			k.PubPath = path
+			k.Comment = comment
+			k.Pub = strings.TrimSpace(string(b))
+			setPublic(k, pub)
+			if k.ModTime.IsZero() {
+				k.ModTime = modTime(path)
+			}
+		case bytes.Contains(b, []byte("PRIVATE KEY-----")):
+			k := get(path)
+			k.Path = path
+			k.ModTime = modTime(path)
+			_, err := ssh.ParseRawPrivateKey(b)
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				k.Encrypted = true
+				if k.Type == "" && missing.PublicKey != nil {
+					setPublic(k, missing.PublicKey)
+				}
+			} else if err == nil && k.Type == "" {
+				if signer, err := ssh.ParsePrivateKey(b); err == nil {
+					setPublic(k, signer.PublicKey())
+				}
+			}
+		}
+	}
+
+	out := make([]Key, 0, len(keys))
+	for _, k := range keys {
+		if k.Type == "" {
+			continue
+		}
+		k.Hosts = users(config, k.Path)
+		out = append(out, *k)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name() < out[j].Name()
+	})
+	return out, nil
+}
+
+func setPublic(k *Key, pub ssh.PublicKey) {
+	k.Type = pub.Type()
+	k.Bits = Bits(pub)
+	k.Fingerprint = ssh.FingerprintSHA256(pub)
+	if k.Pub == "" {
+		k.Pub = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	}
+}
+
+// Bits returns the key size.
+func Bits(pub ssh.PublicKey) int {
+	cpk, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		// security keys and certificates
+		return 256
+	}
+	switch k := cpk.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	default:
+		return 256
+	}
+}
+
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// users returns the hosts with path as IdentityFile.
+func users(config *sshconf.Config, path string) []string {
+	if config == nil || path == "" {
+		return nil
+	}
+	var hosts []string
+	for _, h := range config.Hosts {
+		for _, id := range IdentityFiles(h) {
+			if id == path {
+				hosts = append(hosts, h.Name)
+				break
+			}
+		}
+	}
+	return hosts
+}
+
+// IdentityFiles returns the expanded IdentityFile paths of h,
+// inherited ones included.
+func IdentityFiles(h sshconf.Host) []string {
+	var out []string
+	for _, f := range h.IdentityFiles() {
+		f = strings.Trim(f, `"`)
+		if strings.HasPrefix(f, "%d/") {
+			f = "~/" + strings.TrimPrefix(f, "%d/")
+		}
+		f = sshconf.ExpandPath(f)
+		out = append(out, filepath.Clean(f))
+	}
+	return out
+}
+
+// GenerateCmd returns the `ssh-keygen` command creating a key at path,
+// it prompts for the passphrase so it needs a terminal.
+func GenerateCmd(path, keyType, comment string) *exec.Cmd {
+	args := []string{"-t", keyType, "-f", path}
+	if keyType == "rsa" {
+		args = append(args, "-b", "4096")
+	}
+	if comment != "" {
+		args = append(args, "-C", comment)
+	}
+	return exec.Command("ssh-keygen", args...)
+}
+
+// deployScript appends the key read from stdin to authorized_keys
+// unless present, like ssh-copy-id.
+const deployScript = `umask 077; mkdir -p ~/.ssh && touch ~/.ssh/authorized_keys && ` +
+	`read -r key && { grep -qxF "$key" ~/.ssh/authorized_keys || ` +
+	`printf '%s\n' "$key" >> ~/.ssh/authorized_keys; }`
+
+// DeployCmd returns the ssh command installing pub on host,
+// it may prompt for a password so it needs a terminal.
+func DeployCmd(configPath, host, pub string) *exec.Cmd {
+	args := []string{}
+	if configPath != "" {
+		args = append(args, "-F", configPath)
+	}
+	args = append(args, "-o", "IdentitiesOnly=no", host, deployScript)
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = strings.NewReader(pub + "\n")
+	return cmd
+}
+
+// FormatAge renders a duration in days, months or years.
+func FormatAge(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	switch {
+	case days < 1:
+		return "today"
+	case days < 60:
+		return fmt.Sprintf("%dd", days)
+	case days < 730:
+		return fmt.Sprintf("%dmo", days/30)
+	default:
+		return fmt.Sprintf("%dy", days/365)
+	}
+}
@@ -0,0 +1,89 @@
+package sshkeys_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshkeys"
+	"golang.org/x/crypto/ssh"
+)
+
+func writeKey(t *testing.T, path string, passphrase []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != nil {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "test", passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "test")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(sshPub), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, filepath.Join(dir, "id_plain"), nil)
+	writeKey(t, filepath.Join(dir, "id_secret"), []byte("pw"))
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfgPath := filepath.Join(dir, "config")
+	cfg := "Host web\n  IdentityFile " + filepath.Join(dir, "id_secret") + "\nHost db\n  HostName db.local\n" +
+		"Host *\n  IdentityFile " + filepath.Join(dir, "id_plain") + "\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := sshconf.New()
+	if err := config.ParsePath(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := sshkeys.List(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(keys))
+	}
+	plain, secret := keys[0], keys[1]
+	if plain.Name() != "id_plain" || plain.Encrypted {
+		t.Errorf("unexpected plain key: %+v", plain)
+	}
+	// inherited from Host *
+	if !slices.Equal(plain.Hosts, []string{"web", "db"}) {
+		t.Errorf("plain key hosts = %v, want [web db]", plain.Hosts)
+	}
+	if secret.Name() != "id_secret" || !secret.Encrypted {
+		t.Errorf("unexpected secret key: %+v", secret)
+	}
+	if len(secret.Hosts) != 1 || secret.Hosts[0] != "web" {
+		t.Errorf("secret key hosts = %v, want [web]", secret.Hosts)
+	}
+	for _, k := range keys {
+		if k.Type != ssh.KeyAlgoED25519 || k.Bits != 256 || k.Fingerprint == "" || k.Pub == "" {
+			t.Errorf("incomplete key %s: %+v", k.Name(), k)
+		}
+	}
+}
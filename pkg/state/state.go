@@ -0,0 +1,272 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package state persists per-host usage: pins and connections,
+// used to order hosts by favorites, recency and frecency, and
+// the sort mode picked last.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const fileName = "state.json"
+
+// maxVisits bounds the visits kept per host for frecency.
+const maxVisits = 10
+
+type State struct {
+	// protects Hosts and SortBy
+	mu    sync.Mutex
+	Hosts map[string]*Host `json:"hosts"`
+	// SortBy is the name of the sort mode picked last.
+	SortBy string `json:"sort,omitempty"`
+
+	path string
+}
+
+// Host is the usage of a single Host alias.
+type Host struct {
+	Pinned   bool        `json:"pinned,omitempty"`
+	Count    int         `json:"count"`
+	LastUsed time.Time   `json:"last_used"`
+	Visits   []time.Time `json:"visits,omitempty"`
+}
+
+// Load reads the state from $XDG_STATE_HOME/ssm/state.json,
+// a missing file yields an empty state.
+func Load() (*State, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return New(""), err
+	}
+	return LoadPath(filepath.Join(dir, fileName))
+}
+
+// LoadPath reads the state from path.
+func LoadPath(path string) (*State, error) {
+	s := New(path)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		s.Hosts = map[string]*Host{}
+		return s, fmt.Errorf("state %s: %w", path, err)
+	}
+	if s.Hosts == nil {
+		s.Hosts = map[string]*Host{}
+	}
+	return s, nil
+}
+
+// New returns an empty state saved to path,
+// an empty path keeps the state in memory.
+func New(path string) *State {
+	return &State{
+		Hosts: map[string]*Host{},
+		path:  path,
+	}
+}
+
+// Save writes the state atomically.
+func (s *State) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Record registers a connection to name at t.
+func (s *State) Record(name string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.host(name)
+	h.Count++
+	h.LastUsed = t
+	h.Visits = append(h.Visits, t)
+	if len(h.Visits) > maxVisits {
+		h.Visits = h.Visits[len(h.Visits)-maxVisits:]
+	}
+}
+
+// TogglePin pins or unpins name and returns the new value.
+func (s *State) TogglePin(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.host(name)
+	h.Pinned = !h.Pinned
+	return h.Pinned
+}
+
+// SetSort remembers mode as the sort picked last.
+func (s *State) SetSort(mode SortMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SortBy = mode.String()
+}
+
+// LastSort returns the sort picked last, ok is false when none was.
+func (s *State) LastSort() (mode SortMode, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.SortBy == "" {
+		return SortConfig, false
+	}
+	mode, err := ParseSortMode(s.SortBy)
+	return mode, err == nil
+}
+
+// Pinned reports whether name is pinned.
+func (s *State) Pinned(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.Hosts[name]
+	return ok && h.Pinned
+}
+
+// Get returns a copy of the usage of name.
+func (s *State) Get(name string) Host {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.Hosts[name]
+	if !ok {
+		return Host{}
+	}
+	return *h
+}
+
+// host returns the usage of name, creating it, callers hold mu.
+func (s *State) host(name string) *Host {
+	h, ok := s.Hosts[name]
+	if !ok {
+		h = &Host{}
+		s.Hosts[name] = h
+	}
+	return h
+}
+
+// Frecency scores h by frequency weighted by the age of its visits.
+func (h Host) Frecency(now time.Time) float64 {
+	if len(h.Visits) == 0 {
+		return 0
+	}
+	var points float64
+	for _, v := range h.Visits {
+		age := now.Sub(v)
+		switch {
+		case age < 4*time.Hour:
+			points += 100
+		case age < 24*time.Hour:
+			points += 70
+		case age < 7*24*time.Hour:
+			points += 50
+		case age < 30*24*time.Hour:
+			points += 30
+		default:
+			points += 10
+		}
+	}
+	return float64(h.Count) * points / float64(len(h.Visits))
+}
+
+// SortMode defines how hosts are ordered in the list.
+type SortMode int
+
+const (
+	SortConfig SortMode = iota
+	SortAlpha
+	SortFavorites
+	SortRecent
+	SortFrecency
+)
+
+var sortNames = [...]string{
+	SortConfig:    "config",
+	SortAlpha:     "alpha",
+	SortFavorites: "favorites",
+	SortRecent:    "recent",
+	SortFrecency:  "frecency",
+}
+
+func (m SortMode) String() string {
+	if int(m) < len(sortNames) {
+		return sortNames[m]
+	}
+	return "unknown"
+}
+
+// Next returns the following sort mode, wrapping around.
+func (m SortMode) Next() SortMode {
+	return (m + 1) % SortMode(len(sortNames))
+}
+
+// ParseSortMode parses a sort mode name.
+func ParseSortMode(s string) (SortMode, error) {
+	for i, name := range sortNames {
+		if strings.EqualFold(s, name) {
+			return SortMode(i), nil
+		}
+	}
+	return SortConfig, fmt.Errorf("unknown sort %q, available: %s", s, strings.Join(sortNames[:], "|"))
+}
+
+// Sort returns hosts ordered by mode, ties keep config order.
+func (s *State) Sort(hosts []sshconf.Host, mode SortMode) []sshconf.Host {
+	out := make([]sshconf.Host, len(hosts))
+	copy(out, hosts)
+	now := time.Now()
+
+	var less func(a, b sshconf.Host) bool
+	switch mode {
+	case SortAlpha:
+		less = func(a, b sshconf.Host) bool {
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+	case SortFavorites:
+		less = func(a, b sshconf.Host) bool {
+			return s.Pinned(a.Name) && !s.Pinned(b.Name)
+		}
+	case SortRecent:
+		less = func(a, b sshconf.Host) bool {
+			return s.Get(a.Name).LastUsed.After(s.Get(b.Name).LastUsed)
+		}
+	case SortFrecency:
+		less = func(a, b sshconf.Host) bool {
+			return s.Get(a.Name).Frecency(now) > s.Get(b.Name).Frecency(now)
+		}
+	default:
+		return out
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return less(out[i], out[j])
+	})
+	return out
+}
@@ -0,0 +1,67 @@
+package state_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/state"
+)
+
+func TestSort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := state.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	st.Record("old", now.Add(-60*24*time.Hour))
+	st.Record("old", now.Add(-59*24*time.Hour))
+	st.Record("old", now.Add(-58*24*time.Hour))
+	st.Record("new", now.Add(-time.Minute))
+	st.TogglePin("zeta")
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err = state.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := []sshconf.Host{{Name: "zeta"}, {Name: "old"}, {Name: "new"}}
+	tests := map[state.SortMode][]string{
+		state.SortConfig:    {"zeta", "old", "new"},
+		state.SortAlpha:     {"new", "old", "zeta"},
+		state.SortFavorites: {"zeta", "old", "new"},
+		state.SortRecent:    {"new", "old", "zeta"},
+		state.SortFrecency:  {"new", "old", "zeta"},
+	}
+	for mode, want := range tests {
+		got := st.Sort(hosts, mode)
+		for i := range want {
+			if got[i].Name != want[i] {
+				t.Errorf("%s: got %v at %d, want %v", mode, got[i].Name, i, want[i])
+			}
+		}
+	}
+}
+
+func TestLastSort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st := state.New(path)
+	if _, ok := st.LastSort(); ok {
+		t.Fatal("new state has a sort")
+	}
+	st.SetSort(state.SortFrecency)
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode, ok := st.LastSort(); !ok || mode != state.SortFrecency {
+		t.Errorf("sort: %s %v", mode, ok)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package theme loads the color themes of the themes directory,
+// every file is a theme named after it with a dark and a light variant.
+// Colors are ANSI (0-15), ANSI256 (16-255) or hex, unset colors
+// fall back to the defaults of the variant.
+//
+//	# ~/.config/ssm/themes/ocean.toml
+//	[dark]
+//	title = "#4682b4"
+//	selected-title = "#00bfff"
+//	[light]
+//	title = "#1e5a8a"
+//	selected-title = "#0077b6"
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lfaoro/ssm/pkg/xdg"
+)
+
+const dirName = "themes"
+
+// Palette colors every styled element of the interface.
+type Palette struct {
+	// Title is the background of the list titles.
+	Title     string `toml:"title"`
+	TitleText string `toml:"title-text"`
+
+	SelectedBorder      string `toml:"selected-border"`
+	SelectedTitle       string `toml:"selected-title"`
+	SelectedDescription string `toml:"selected-description"`
+
+	StatusBar    string `toml:"status-bar"`
+	FilterCursor string `toml:"filter-cursor"`
+	// Key colors the option names of the side views.
+	Key string `toml:"key"`
+	// Dim colors tags, latency and secondary text.
+	Dim   string `toml:"dim"`
+	Error string `toml:"error"`
+	OK    string `toml:"ok"`
+	Warn  string `toml:"warn"`
+	// Stderr colors the error output of the run screen.
+	Stderr string `toml:"stderr"`
+
+	BarText          string `toml:"bar-text"`
+	SecondaryBarText string `toml:"secondary-bar-text"`
+	SecondaryBar     string `toml:"secondary-bar"`
+}
+
+func (p *Palette) fields() []struct {
+	name  string
+	color *string
+} {
+	return []struct {
+		name  string
+		color *string
+	}{
+		{"title", &p.Title},
+		{"title-text", &p.TitleText},
+		{"selected-border", &p.SelectedBorder},
+		{"selected-title", &p.SelectedTitle},
+		{"selected-description", &p.SelectedDescription},
+		{"status-bar", &p.StatusBar},
+		{"filter-cursor", &p.FilterCursor},
+		{"key", &p.Key},
+		{"dim", &p.Dim},
+		{"error", &p.Error},
+		{"ok", &p.OK},
+		{"warn", &p.Warn},
+		{"stderr", &p.Stderr},
+		{"bar-text", &p.BarText},
+		{"secondary-bar-text", &p.SecondaryBarText},
+		{"secondary-bar", &p.SecondaryBar},
+	}
+}
+
+// fill sets the unset colors of p from base.
+func (p Palette) fill(base Palette) Palette {
+	b := base.fields()
+	for i, f := range p.fields() {
+		if *f.color == "" {
+			*f.color = *b[i].color
+		}
+	}
+	return p
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func (p Palette) validate() error {
+	for _, f := range p.fields() {
+		c := *f.color
+		if c == "" || hexColor.MatchString(c) {
+			continue
+		}
+		if n, err := strconv.Atoi(c); err == nil && n >= 0 && n <= 255 {
+			continue
+		}
+		return fmt.Errorf("%s: invalid color %q, want #rrggbb or 0-255", f.name, c)
+	}
+	return nil
+}
+
+// DefaultDark and DefaultLight are the colors themes fall back to.
+var (
+	DefaultDark = Palette{
+		Title:               "#4682b4",
+		TitleText:           "230",
+		SelectedBorder:      "#00bfff",
+		SelectedTitle:       "#00bfff",
+		SelectedDescription: "#4682b4",
+		StatusBar:           "#777777",
+		FilterCursor:        "12",
+		Key:                 "#4682b4",
+		Dim:                 "8",
+		Error:               "1",
+		OK:                  "2",
+		Warn:                "3",
+		Stderr:              "#e06c75",
+		BarText:             "#000000",
+		SecondaryBarText:    "#FFFDF5",
+		SecondaryBar:        "#343433",
+	}
+	DefaultLight = Palette{
+		Title:               "#1e5a8a",
+		TitleText:           "#ffffff",
+		SelectedBorder:      "#0077b6",
+		SelectedTitle:       "#0077b6",
+		SelectedDescription: "#1e5a8a",
+		StatusBar:           "#A49FA5",
+		FilterCursor:        "4",
+		Key:                 "#1e5a8a",
+		Dim:                 "#8a8a8a",
+		Error:               "#c0392b",
+		OK:                  "#2e7d32",
+		Warn:                "#b36b00",
+		Stderr:              "#b03a48",
+		BarText:             "#ffffff",
+		SecondaryBarText:    "#343433",
+		SecondaryBar:        "#e4e4e4",
+	}
+)
+
+// Theme is a named pair of palettes.
+type Theme struct {
+	Name  string  `toml:"-"`
+	Dark  Palette `toml:"dark"`
+	Light Palette `toml:"light"`
+	// Path is the file of the theme, empty for built-in themes.
+	Path string `toml:"-"`
+}
+
+// Variant returns the palette for a dark or light background.
+func (t Theme) Variant(dark bool) Palette {
+	if dark {
+		return t.Dark
+	}
+	return t.Light
+}
+
+// Builtin returns the themes shipped with ssm.
+func Builtin() []Theme {
+	return []Theme{
+		{
+			Name: "matrix",
+			Dark: Palette{
+				Title:               "#648c11",
+				SelectedTitle:       "#9efd38",
+				SelectedBorder:      "#9efd38",
+				SelectedDescription: "#648c11",
+				Key:                 "#648c11",
+			}.fill(DefaultDark),
+			Light: Palette{
+				Title:               "#3d5c0a",
+				SelectedTitle:       "#2e7d32",
+				SelectedBorder:      "#2e7d32",
+				SelectedDescription: "#3d5c0a",
+				Key:                 "#3d5c0a",
+			}.fill(DefaultLight),
+		},
+		{
+			Name:  "sky",
+			Dark:  DefaultDark,
+			Light: DefaultLight,
+		},
+	}
+}
+
+// Dir returns $XDG_CONFIG_HOME/ssm/themes.
+func Dir() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dirName), nil
+}
+
+// Load returns the built-in themes and the themes of Dir,
+// files replace built-in themes with the same name.
+func Load() ([]Theme, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadDir(dir)
+}
+
+// LoadDir returns the built-in themes and the *.toml themes of dir
+// sorted by name, a missing dir has no themes.
+func LoadDir(dir string) ([]Theme, error) {
+	out := Builtin()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		t, err := LoadPath(path)
+		if err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(out, func(b Theme) bool { return b.Name == t.Name })
+		if i >= 0 {
+			out[i] = t
+		} else {
+			out = append(out, t)
+		}
+	}
+	slices.SortFunc(out, func(a, b Theme) int { return strings.Compare(a.Name, b.Name) })
+	return out, nil
+}
+
+// LoadPath reads a theme file, a theme without [light]
+// uses its [dark] colors on light backgrounds too.
+func LoadPath(path string) (Theme, error) {
+	t := Theme{
+		Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path: path,
+	}
+	md, err := toml.DecodeFile(path, &t)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, fmt.Errorf("theme: %s not found", path)
+	}
+	if err != nil {
+		return t, fmt.Errorf("theme: %w", err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return t, fmt.Errorf("theme: %s: unknown key %s", path, undecoded[0])
+	}
+	for _, p := range []Palette{t.Dark, t.Light} {
+		if err := p.validate(); err != nil {
+			return t, fmt.Errorf("theme: %s: %w", path, err)
+		}
+	}
+	if !md.IsDefined("light") {
+		t.Light = t.Dark
+	}
+	t.Dark = t.Dark.fill(DefaultDark)
+	t.Light = t.Light.fill(DefaultLight)
+	return t, nil
+}
+
+// Find returns the theme called name.
+func Find(themes []Theme, name string) (Theme, bool) {
+	i := slices.IndexFunc(themes, func(t Theme) bool { return t.Name == name })
+	if i < 0 {
+		return Theme{}, false
+	}
+	return themes[i], true
+}
+
+// Names returns the names of themes.
+func Names(themes []Theme) []string {
+	names := make([]string, 0, len(themes))
+	for _, t := range themes {
+		names = append(names, t.Name)
+	}
+	return names
+}
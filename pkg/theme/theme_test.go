@@ -0,0 +1,77 @@
+package theme_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfaoro/ssm/pkg/theme"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ocean.toml", `
+[dark]
+title = "#123456"
+selected-title = "45"
+[light]
+title = "#abc"
+`)
+	write("matrix.toml", `
+[dark]
+title = "#000000"
+`)
+	write("notes.txt", "not a theme")
+
+	themes, err := theme.LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(theme.Names(themes), ","); got != "matrix,ocean,sky" {
+		t.Fatalf("names: %s", got)
+	}
+
+	ocean, _ := theme.Find(themes, "ocean")
+	if ocean.Dark.Title != "#123456" || ocean.Dark.SelectedTitle != "45" {
+		t.Errorf("dark: %+v", ocean.Dark)
+	}
+	if ocean.Dark.Dim != theme.DefaultDark.Dim {
+		t.Errorf("dark dim not filled: %q", ocean.Dark.Dim)
+	}
+	if ocean.Variant(false).Title != "#abc" || ocean.Light.Dim != theme.DefaultLight.Dim {
+		t.Errorf("light: %+v", ocean.Light)
+	}
+
+	// a file replaces the built-in theme, without [light] dark colors are used
+	matrix, _ := theme.Find(themes, "matrix")
+	if matrix.Path == "" || matrix.Light.Title != "#000000" {
+		t.Errorf("matrix: %+v", matrix)
+	}
+
+	if _, err := theme.LoadDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("missing dir: %v", err)
+	}
+}
+
+func TestLoadPathErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"color": "[dark]\ntitle = \"blue\"\n",
+		"ansi":  "[dark]\ndim = \"256\"\n",
+		"key":   "[dark]\ntitel = \"#fff\"\n",
+	} {
+		path := filepath.Join(t.TempDir(), name+".toml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := theme.LoadPath(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
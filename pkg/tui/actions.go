@@ -0,0 +1,452 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/snapshot"
+)
+
+// Action is an operation on the host list, every action
+// is listed in the command palette.
+type Action struct {
+	// ID identifies the action, it never changes.
+	ID    string
+	Title string
+	// Keys are the default bindings of the action,
+	// keymap.toml overrides them.
+	Keys []string
+	// Host marks actions working on the selected host,
+	// they're hidden when no host is selected.
+	Host bool
+	Run  func(m *Model) (tea.Model, tea.Cmd)
+}
+
+var actions []Action
+
+// RegisterAction adds a to the command palette,
+// registering an existing ID replaces it.
+func RegisterAction(a Action) {
+	for i := range actions {
+		if actions[i].ID == a.ID {
+			actions[i] = a
+			return
+		}
+	}
+	actions = append(actions, a)
+}
+
+// Actions returns the registered actions in registration order.
+func Actions() []Action {
+	return slices.Clone(actions)
+}
+
+// ScreenKey binds an action of a screen opened from the host
+// list, its ID is `screen.action`. Screen keys are not listed
+// in the command palette.
+type ScreenKey struct {
+	ID    string
+	Title string
+	// Keys are the default bindings, keymap.toml overrides them.
+	Keys []string
+}
+
+var screenKeys []ScreenKey
+
+// RegisterScreenKeys adds the bindings of a screen,
+// registering an existing ID replaces it.
+func RegisterScreenKeys(keys ...ScreenKey) {
+	for _, k := range keys {
+		i := slices.IndexFunc(screenKeys, func(s ScreenKey) bool { return s.ID == k.ID })
+		if i < 0 {
+			screenKeys = append(screenKeys, k)
+			continue
+		}
+		screenKeys[i] = k
+	}
+}
+
+// ScreenKeys returns the registered screen keys in registration order.
+func ScreenKeys() []ScreenKey {
+	return slices.Clone(screenKeys)
+}
+
+func findAction(id string) (Action, bool) {
+	for _, a := range actions {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
+// DefaultKeymap returns the default bindings of every action,
+// the base keymap.toml is merged into.
+func DefaultKeymap() keymap.Keymap {
+	km := keymap.Keymap{}
+	for _, a := range actions {
+		km[a.ID] = slices.Clone(a.Keys)
+	}
+	for _, k := range screenKeys {
+		km[k.ID] = slices.Clone(k.Keys)
+	}
+	return km
+}
+
+// screenKey returns the action of screen bound to msg,
+// "" when unbound.
+func (m *Model) screenKey(screen string, msg tea.KeyPressMsg) string {
+	action, _ := m.keys.LookupIn(screen, keymap.Normalize(msg.String()))
+	return action
+}
+
+// screenHelp returns the help bindings of the actions
+// of screen with the keys in effect.
+func (m *Model) screenHelp(screen string, actions ...string) []key.Binding {
+	var out []key.Binding
+	for _, a := range actions {
+		k, ok := findScreenKey(screen + "." + a)
+		keys := m.keys[k.ID]
+		if !ok || len(keys) == 0 {
+			continue
+		}
+		out = append(out, key.NewBinding(key.WithKeys(keys...), key.WithHelp(keys[0], k.Title)))
+	}
+	return out
+}
+
+// screenHint renders the help bindings of screen on one line.
+func (m *Model) screenHint(screen string, actions ...string) string {
+	var hints []string
+	for _, b := range m.screenHelp(screen, actions...) {
+		hints = append(hints, b.Help().Key+" "+b.Help().Desc)
+	}
+	return strings.Join(hints, " · ")
+}
+
+// boundKey returns the first key bound to id.
+func (m *Model) boundKey(id string) string {
+	if keys := m.keys[id]; len(keys) > 0 {
+		return keys[0]
+	}
+	return "unbound"
+}
+
+func findScreenKey(id string) (ScreenKey, bool) {
+	i := slices.IndexFunc(screenKeys, func(k ScreenKey) bool { return k.ID == id })
+	if i < 0 {
+		return ScreenKey{}, false
+	}
+	return screenKeys[i], true
+}
+
+// runKey runs the action bound to k, ok is false when k is unbound.
+func (m *Model) runKey(k string) (tea.Model, tea.Cmd, bool) {
+	id, ok := m.keys.Lookup(k)
+	if !ok {
+		return m, nil, false
+	}
+	a, ok := findAction(id)
+	if !ok {
+		return m, nil, false
+	}
+	if _, selected := m.li.SelectedItem().(item); a.Host && !selected {
+		return m, nil, true
+	}
+	next, cmd := a.Run(m)
+	return next, cmd, true
+}
+
+func init() {
+	RegisterAction(Action{
+		ID: "connect", Title: "connect", Keys: []string{"enter"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m.connectSelected() },
+	})
+	RegisterAction(Action{
+		ID: "switch-connector", Title: "cycle installed connectors", Keys: []string{"tab"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m, m.switchConnector() },
+	})
+	RegisterAction(Action{
+		ID: "edit-config", Title: "edit ssh config", Keys: []string{"ctrl+e"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m, m.editConfig() },
+	})
+	RegisterAction(Action{
+		ID: "reload-config", Title: "reload ssh config",
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			return m, func() tea.Msg { return ReloadConfigMsg{} }
+		},
+	})
+	RegisterAction(Action{
+		ID: "toggle-view", Title: "toggle config side view", Keys: []string{"ctrl+v"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.showConfig = !m.showConfig
+			m.setConfig()
+			return m, nil
+		},
+	})
+	RegisterAction(Action{
+		ID: "pin", Title: "pin/unpin host", Keys: []string{"*"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m, m.togglePin() },
+	})
+	RegisterAction(Action{
+		ID: "sort", Title: "cycle sort order", Keys: []string{"ctrl+o"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.sort = m.sort.Next()
+			m.state.SetSort(m.sort)
+			m.reloadList()
+			return m, tea.Batch(AddLog("sort: %s", m.sort), saveState(m.state))
+		},
+	})
+	RegisterAction(Action{
+		ID: "filter", Title: "filter hosts", Keys: []string{"/"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			if m.li.FilterValue() == "" {
+				// shows every item while typing
+				m.li.SetFilterText("")
+			}
+			m.li.SetFilterState(list.Filtering)
+			return m, textinput.Blink
+		},
+	})
+	RegisterAction(Action{
+		ID: "palette", Title: "command palette", Keys: []string{"ctrl+k", ":"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return PaletteModel(m), nil },
+	})
+	RegisterAction(Action{
+		ID: "sftp", Title: "sftp", Keys: []string{"ctrl+s"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			return m, AddError(fmt.Errorf("sftp: not yet implemented"))
+		},
+	})
+	RegisterAction(Action{
+		ID: "cursor-up", Title: "move up", Keys: []string{"up", "k", "ctrl+p"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { m.li.CursorUp(); return m, nil },
+	})
+	RegisterAction(Action{
+		ID: "cursor-down", Title: "move down", Keys: []string{"down", "j", "ctrl+n"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { m.li.CursorDown(); return m, nil },
+	})
+	RegisterAction(Action{
+		ID: "prev-page", Title: "previous page", Keys: []string{"left", "h", "pgup", "b", "u", "ctrl+b"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { m.li.PrevPage(); return m, nil },
+	})
+	RegisterAction(Action{
+		ID: "next-page", Title: "next page", Keys: []string{"right", "l", "pgdown", "f", "d", "ctrl+f"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { m.li.NextPage(); return m, nil },
+	})
+	RegisterAction(Action{
+		ID: "go-to-start", Title: "go to start", Keys: []string{"home", "g"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { m.li.Select(0); return m, nil },
+	})
+	RegisterAction(Action{
+		ID: "go-to-end", Title: "go to end", Keys: []string{"end", "shift+g"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.li.Select(len(m.li.VisibleItems()) - 1)
+			return m, nil
+		},
+	})
+	RegisterAction(Action{
+		ID: "help", Title: "toggle help", Keys: []string{"?"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.li.Help.ShowAll = !m.li.Help.ShowAll
+			// recomputes the pagination for the help height
+			m.li.SetSize(m.li.Width(), m.li.Height())
+			return m, nil
+		},
+	})
+	RegisterAction(Action{
+		ID: "quit", Title: "quit", Keys: []string{"q", "esc"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m, tea.Quit },
+	})
+}
+
+// connectSelected connects to the selected host, warning first
+// when its keys are not loaded in ssh-agent. Hosts selected with
+// space open together instead.
+func (m *Model) connectSelected() (tea.Model, tea.Cmd) {
+	if len(m.selected) > 0 {
+		return m, m.connectSelection()
+	}
+	if host, ok := m.li.SelectedItem().(item); ok {
+		if am := m.agentCheck(host); am != nil {
+			return am, nil
+		}
+	}
+	conncmd := m.connect()
+	return m, tea.Batch(
+		conncmd,
+		AddError(fmt.Errorf("%s", m.errbuf.String())),
+	)
+}
+
+// switchConnector picks the next connector found in PATH.
+func (m *Model) switchConnector() tea.Cmd {
+	var installed []SysCmd
+	for _, c := range m.connectors {
+		if c.Installed() {
+			installed = append(installed, SysCmd(c.Name))
+		}
+	}
+	if len(installed) == 0 {
+		return AddError(fmt.Errorf("none of the connectors is installed: %s",
+			strings.Join(connector.Names(m.connectors), ", ")))
+	}
+	i := slices.Index(installed, m.Cmd)
+	m.Cmd = installed[(i+1)%len(installed)]
+	m.cmdChosen = true
+	m.li.NewStatusMessage(m.status())
+	return nil
+}
+
+func (m *Model) togglePin() tea.Cmd {
+	host, ok := m.li.SelectedItem().(item)
+	if !ok {
+		return nil
+	}
+	pinned := m.state.TogglePin(host.host.Name)
+	m.reloadList()
+	return tea.Batch(
+		saveState(m.state),
+		AddLog("pinned %s: %v", host.host.Name, pinned),
+	)
+}
+
+// editConfig opens the config in the editor, then reviews the
+// edit against a snapshot taken before, or reloads the config
+// when snapshots are disabled.
+func (m *Model) editConfig() tea.Cmd {
+	if m.snapshots == nil {
+		return m.edit(nil)
+	}
+	snap, err := m.snapshots.Take(m.config.GetPath())
+	if err != nil {
+		return AddError(fmt.Errorf("snapshot before editing: %w", err))
+	}
+	return m.edit(&snap)
+}
+
+// edit opens the config in the editor, base is the snapshot
+// the edit is reviewed against.
+func (m *Model) edit(base *snapshot.Snapshot) tea.Cmd {
+	confFile := m.config.GetPath()
+	editorPath := m.editor
+	if editorPath == "" {
+		editorPath = os.Getenv("EDITOR")
+	}
+	// editors may carry arguments: `code --wait`
+	editorArgs := strings.Fields(editorPath)
+	if len(editorArgs) > 0 {
+		editorPath, editorArgs = editorArgs[0], editorArgs[1:]
+	}
+	knownEditors := [...]string{
+		editorPath,
+		"vim",
+		"vi",
+		"nano",
+		"ed",
+	}
+	for i, cmd := range knownEditors {
+		path, err := exec.LookPath(cmd)
+		if err != nil {
+			continue
+		}
+		if i > 0 {
+			editorArgs = nil
+		}
+		editorPath = path
+		break
+	}
+	if editorPath == "" {
+		return AddError(fmt.Errorf("env EDITOR not set, nor any %v found in PATH", knownEditors[1:]))
+	}
+	cmd := exec.Command(editorPath, append(editorArgs, confFile)...)
+	cmd.Dir = filepath.Dir(confFile)
+	cmd.Stderr = &m.errbuf
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editDoneMsg{base: base, err: err}
+	})
+}
+
+// keyHelp renders the bindings of an action.
+func keyHelp(keys []string) string {
+	return strings.Join(keys, " ")
+}
+
+// helpKeys are the full help bindings of the host list
+// with the keys in effect.
+func (m *Model) helpKeys() []key.Binding {
+	ids := []string{
+		"palette",
+		"connect",
+		"switch-connector",
+		"launch-target",
+		"select",
+		"edit-config",
+		"snapshots",
+		"toggle-view",
+		"view-search",
+		"view-defaults",
+		"pin",
+		"sort",
+		"ping",
+		"health",
+		"collect-facts",
+		"masters",
+		"warm-up",
+		"history",
+		"recordings",
+		"known-hosts",
+		"ssh-keys",
+		"ssh-agent",
+	}
+	var out []key.Binding
+	for _, id := range ids {
+		a, ok := findAction(id)
+		if !ok || len(m.keys[id]) == 0 {
+			continue
+		}
+		out = append(out, key.NewBinding(
+			key.WithKeys(m.keys[id]...),
+			key.WithHelp(m.keys[id][0], a.Title),
+		))
+	}
+	return out
+}
+
+// applyKeymap binds the list navigation to the keys in effect,
+// so unbound defaults of the list don't fire.
+func (m *Model) applyKeymap() {
+	bind := func(b *key.Binding, id string) {
+		keys := m.keys[id]
+		if len(keys) == 0 {
+			// nil keys keep the binding disabled, the list
+			// re-enables bindings as its state changes
+			b.SetKeys()
+			return
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+	}
+	km := &m.li.KeyMap
+	bind(&km.CursorUp, "cursor-up")
+	bind(&km.CursorDown, "cursor-down")
+	bind(&km.PrevPage, "prev-page")
+	bind(&km.NextPage, "next-page")
+	bind(&km.GoToStart, "go-to-start")
+	bind(&km.GoToEnd, "go-to-end")
+	bind(&km.Filter, "filter")
+	bind(&km.ShowFullHelp, "help")
+	bind(&km.CloseFullHelp, "help")
+	bind(&km.Quit, "quit")
+	m.li.AdditionalFullHelpKeys = m.helpKeys
+}
@@ -0,0 +1,325 @@
+package tui
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/sshagent"
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "ssh-agent", Title: "ssh-agent identities", Keys: []string{"shift+a"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return AgentModel(m), nil },
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "ssh-agent.add", Title: "add host keys", Keys: []string{"a"}},
+		ScreenKey{ID: "ssh-agent.remove", Title: "remove identity", Keys: []string{"d"}},
+		ScreenKey{ID: "ssh-agent.lifetime", Title: "cycle lifetime", Keys: []string{"l"}},
+		ScreenKey{ID: "ssh-agent.confirm", Title: "toggle confirm", Keys: []string{"c"}},
+		ScreenKey{ID: "ssh-agent.connect", Title: "connect anyway", Keys: []string{"enter"}},
+		ScreenKey{ID: "ssh-agent.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+// agentLifetimes are cycled with `l` when adding keys.
+var agentLifetimes = []time.Duration{0, time.Hour, 4 * time.Hour, 8 * time.Hour, 24 * time.Hour}
+
+type identityItem struct {
+	id sshagent.Identity
+}
+
+func (i identityItem) Title() string {
+	return i.id.Comment
+}
+
+func (i identityItem) Description() string {
+	return fmt.Sprintf("%s %d · %s", strings.TrimPrefix(i.id.Type, "ssh-"), i.id.Bits, i.id.Fingerprint)
+}
+
+func (i identityItem) FilterValue() string {
+	return i.id.Comment + " " + i.id.Fingerprint
+}
+
+type agentModel struct {
+	previousModel *Model
+	li            list.Model
+	input         textinput.Model
+
+	// pending is the host being connected to when
+	// some of its IdentityFile keys are not loaded.
+	pending *item
+	missing []string
+	asking  string // key waiting for its passphrase
+
+	lifetime int
+	confirm  bool
+	status   string
+}
+
+// AgentModel shows the identities loaded in ssh-agent,
+// adds the selected host keys and removes loaded ones.
+func AgentModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	m := &agentModel{previousModel: previousModel}
+	m.li = list.New([]list.Item{}, d, previousModel.li.Width(), previousModel.li.Height()-4)
+	m.li.Title = "ssh-agent"
+	m.li.Styles.Title = previousModel.li.Styles.Title
+	m.li.SetStatusBarItemName("identity", "identities")
+	m.li.DisableQuitKeybindings()
+	m.li.AdditionalFullHelpKeys = func() []key.Binding {
+		return previousModel.screenHelp("ssh-agent", "add", "remove", "lifetime", "confirm", "back")
+	}
+	m.input = textinput.New()
+	m.input.EchoMode = textinput.EchoPassword
+	m.input.VirtualCursor = true
+	m.reload()
+	return m
+}
+
+// agentWarning is shown before connecting to host
+// when the agent lacks the missing IdentityFile keys.
+func agentWarning(base *Model, host item, missing []string) tea.Model {
+	m := AgentModel(base).(*agentModel)
+	m.pending = &host
+	m.missing = missing
+	return m
+}
+
+// reload lists the loaded identities and the keys of the selected
+// host missing from the agent.
+func (m *agentModel) reload() {
+	ids, err := listIdentities()
+	if err != nil {
+		m.status = err.Error()
+	}
+	items := make([]list.Item, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, identityItem{id: id})
+	}
+	m.li.SetItems(items)
+	host, ok := m.previousModel.li.SelectedItem().(item)
+	if m.pending != nil {
+		host, ok = *m.pending, true
+	}
+	if ok && err == nil {
+		m.missing = sshagent.Missing(host.host, ids)
+	}
+}
+
+func listIdentities() ([]sshagent.Identity, error) {
+	c, err := sshagent.Dial("")
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.List()
+}
+
+func (m *agentModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *agentModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width, msg.Height-4)
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		if m.asking != "" {
+			return m.updatePassphrase(msg)
+		}
+		if m.li.FilterState() == list.Filtering {
+			break
+		}
+		switch m.previousModel.screenKey("ssh-agent", msg) {
+		case "back":
+			if m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
+			}
+			return m.previousModel, nil
+		case "connect":
+			if m.pending == nil {
+				break
+			}
+			m.previousModel.agentSkip[m.pending.host.Name] = true
+			return m.previousModel, m.previousModel.connectTo(*m.pending)
+		case "add":
+			return m.addNext(nil)
+		case "remove":
+			selected, ok := m.li.SelectedItem().(identityItem)
+			if !ok {
+				break
+			}
+			m.status = fmt.Sprintf("removed %s", selected.id.Comment)
+			if err := removeIdentity(selected.id.Key); err != nil {
+				m.status = err.Error()
+			}
+			m.reload()
+			return m, nil
+		case "lifetime":
+			m.lifetime = (m.lifetime + 1) % len(agentLifetimes)
+			return m, nil
+		case "confirm":
+			m.confirm = !m.confirm
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	return m, cmd
+}
+
+func (m *agentModel) updatePassphrase(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.Code {
+	case tea.KeyEsc:
+		m.asking = ""
+		m.input.Blur()
+		m.input.SetValue("")
+		return m, nil
+	case tea.KeyEnter:
+		pass := []byte(m.input.Value())
+		m.input.SetValue("")
+		return m.addNext(pass)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// addNext adds the first missing key, asking for its passphrase
+// when protected, and connects to the pending host once all are in.
+func (m *agentModel) addNext(passphrase []byte) (tea.Model, tea.Cmd) {
+	if len(m.missing) == 0 {
+		m.status = "no IdentityFile keys to add for the selected host"
+		return m, nil
+	}
+	path := m.missing[0]
+	err := addIdentity(path, sshagent.AddOptions{
+		Lifetime:   agentLifetimes[m.lifetime],
+		Confirm:    m.confirm,
+		Passphrase: passphrase,
+	})
+	var missing *ssh.PassphraseMissingError
+	switch {
+	case errors.As(err, &missing), errors.Is(err, x509.IncorrectPasswordError):
+		if passphrase != nil {
+			m.status = fmt.Sprintf("%s: wrong passphrase", filepath.Base(path))
+		}
+		m.asking = path
+		m.input.Prompt = fmt.Sprintf("passphrase for %s: ", tildePath(path))
+		return m, m.input.Focus()
+	case err != nil:
+		m.asking = ""
+		m.input.Blur()
+		m.status = err.Error()
+		return m, nil
+	}
+	m.asking = ""
+	m.input.Blur()
+	m.status = fmt.Sprintf("added %s", tildePath(path))
+	m.reload()
+	if len(m.missing) > 0 {
+		return m.addNext(nil)
+	}
+	if m.pending != nil {
+		return m.previousModel, m.previousModel.connectTo(*m.pending)
+	}
+	return m, nil
+}
+
+func addIdentity(path string, opts sshagent.AddOptions) error {
+	c, err := sshagent.Dial("")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Add(path, opts)
+}
+
+func removeIdentity(pub ssh.PublicKey) error {
+	c, err := sshagent.Dial("")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Remove(pub)
+}
+
+// agentCheck returns the warning screen when connecting to host
+// needs keys the agent doesn't hold, nil otherwise. Without
+// a reachable agent there is nothing to warn about.
+func (m *Model) agentCheck(host item) tea.Model {
+	if m.agentSkip[host.host.Name] || sshagent.Socket() == "" {
+		return nil
+	}
+	ids, err := listIdentities()
+	if err != nil {
+		return nil
+	}
+	missing := sshagent.Missing(host.host, ids)
+	if len(missing) == 0 {
+		return nil
+	}
+	return agentWarning(m, host, missing)
+}
+
+func (m *agentModel) View() string {
+	warn := m.previousModel.theme.warn()
+	dim := m.previousModel.theme.dim()
+
+	var b strings.Builder
+	if m.pending != nil && len(m.missing) > 0 {
+		names := make([]string, 0, len(m.missing))
+		for _, p := range m.missing {
+			names = append(names, tildePath(p))
+		}
+		b.WriteString(warn.Render(fmt.Sprintf("%s uses keys not loaded in ssh-agent: %s",
+			m.pending.host.Name, strings.Join(names, ", "))) + "\n")
+		pm := m.previousModel
+		b.WriteString(dim.Render(fmt.Sprintf("%s add and connect • %s connect anyway • %s cancel",
+			pm.boundKey("ssh-agent.add"), pm.boundKey("ssh-agent.connect"), pm.boundKey("ssh-agent.back"))) + "\n")
+	} else if len(m.missing) > 0 {
+		b.WriteString(warn.Render(fmt.Sprintf("%d IdentityFile keys of the selected host not loaded, %s to add",
+			len(m.missing), m.previousModel.boundKey("ssh-agent.add"))) + "\n\n")
+	} else {
+		b.WriteString("\n\n")
+	}
+	b.WriteString(m.li.View() + "\n")
+	lifetime := "forever"
+	if d := agentLifetimes[m.lifetime]; d > 0 {
+		lifetime = d.String()
+	}
+	opts := fmt.Sprintf("add with lifetime:%s confirm:%v", lifetime, m.confirm)
+	switch {
+	case m.asking != "":
+		b.WriteString(m.input.View())
+	case m.status != "":
+		b.WriteString(dim.Render(opts + " · " + m.status))
+	default:
+		b.WriteString(dim.Render(opts))
+	}
+	return b.String()
+}
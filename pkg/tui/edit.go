@@ -0,0 +1,252 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/snapshot"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func init() {
+	RegisterScreenKeys(
+		ScreenKey{ID: "edit-review.accept", Title: "accept", Keys: []string{"a", "enter", "esc", "q"}},
+		ScreenKey{ID: "edit-review.edit", Title: "edit again", Keys: []string{"e"}},
+		ScreenKey{ID: "edit-review.revert", Title: "revert", Keys: []string{"r"}},
+		ScreenKey{ID: "edit-review.diff", Title: "diff", Keys: []string{"d"}},
+	)
+}
+
+// WithSnapshots snapshots the config before editing it in store,
+// the edit is reviewed before being reloaded.
+func WithSnapshots(store snapshot.Store) ModelOption {
+	return func(m *Model) {
+		m.snapshots = &store
+	}
+}
+
+// editDoneMsg is sent when the editor exits, base is the
+// snapshot taken before editing, nil without snapshots.
+type editDoneMsg struct {
+	base *snapshot.Snapshot
+	err  error
+}
+
+// editDone reviews the edit of the config against its base.
+func (m *Model) editDone(msg editDoneMsg) (tea.Model, tea.Cmd) {
+	reload := func() tea.Msg { return ReloadConfigMsg{} }
+	if msg.err != nil {
+		return m, tea.Batch(AddError(msg.err), reload)
+	}
+	if msg.base == nil {
+		return m, reload
+	}
+	data, err := os.ReadFile(m.config.GetPath())
+	if err != nil {
+		return m, AddError(err)
+	}
+	before, err := msg.base.Read()
+	if err != nil {
+		return m, tea.Batch(AddError(err), reload)
+	}
+	if bytes.Equal(before, data) {
+		return m, tea.Batch(reload, AddLog("config unchanged"))
+	}
+	return EditReviewModel(m, *msg.base, before, data), nil
+}
+
+// configDiff is the difference between two versions of a config.
+type configDiff struct {
+	changes []sshconf.Change
+	unified string
+}
+
+func diffConfig(path string, old, new []byte) (configDiff, error) {
+	before, err := sshconf.ParseBlocksReader(bytes.NewReader(old), path)
+	if err != nil {
+		return configDiff{}, err
+	}
+	after, err := sshconf.ParseBlocksReader(bytes.NewReader(new), path)
+	if err != nil {
+		return configDiff{}, err
+	}
+	return configDiff{
+		changes: sshconf.Diff(before, after),
+		unified: udiff.Unified("before", "after", string(old), string(new)),
+	}, nil
+}
+
+// renderChanges colors the changes: additions good,
+// removals bad and renames as warnings.
+func renderChanges(changes []sshconf.Change, theme palette) string {
+	var b strings.Builder
+	for _, c := range changes {
+		style := lg.NewStyle()
+		switch c.Kind {
+		case sshconf.BlockAdded:
+			style = theme.good()
+		case sshconf.BlockRemoved:
+			style = theme.bad()
+		case sshconf.BlockRenamed:
+			style = theme.warn()
+		}
+		b.WriteString(style.Render(c.String()) + "\n")
+	}
+	return b.String()
+}
+
+// renderUnified colors the lines of a unified diff.
+func renderUnified(diff string, theme palette) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = theme.key().Render(line)
+		case strings.HasPrefix(line, "@@"):
+			line = theme.dim().Render(line)
+		case strings.HasPrefix(line, "+"):
+			line = theme.good().Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = theme.bad().Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// EditReviewModel shows what an edit of the config changed and
+// the problems it introduced, to accept, edit again or revert it.
+func EditReviewModel(base tea.Model, snap snapshot.Snapshot, before, after []byte) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width())
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	m := &editReviewModel{
+		previousModel: previousModel,
+		vp:            vp,
+		base:          snap,
+	}
+	path := previousModel.config.GetPath()
+	m.diff, m.err = diffConfig(path, before, after)
+	if m.err == nil {
+		m.review(path, before, after)
+	}
+	m.setContent()
+	return m
+}
+
+type editReviewModel struct {
+	previousModel *Model
+	vp            viewport.Model
+	base          snapshot.Snapshot
+	diff          configDiff
+	// diagnostics are the problems the edit introduced.
+	diagnostics []sshconf.Diagnostic
+	notes       []string
+	showUnified bool
+	err         error
+}
+
+// review collects the problems of after missing from before,
+// reported by ssm and by ssh itself.
+func (m *editReviewModel) review(path string, before, after []byte) {
+	old, _ := sshconf.ParseBlocksReader(bytes.NewReader(before), path)
+	new, _ := sshconf.ParseBlocksReader(bytes.NewReader(after), path)
+	m.diagnostics = sshconf.NewDiagnostics(sshconf.Lint(old), sshconf.Lint(new))
+
+	checkAfter, err := sshconf.Check(path)
+	if err != nil {
+		m.notes = append(m.notes, err.Error())
+		return
+	}
+	checkBefore, err := sshconf.Check(m.base.Path)
+	if err != nil {
+		m.notes = append(m.notes, err.Error())
+		return
+	}
+	m.diagnostics = append(m.diagnostics, sshconf.NewDiagnostics(checkBefore, checkAfter)...)
+}
+
+func (m *editReviewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *editReviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.vp.SetWidth(msg.Width)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		switch m.previousModel.screenKey("edit-review", msg) {
+		case "accept":
+			return m.previousModel, tea.Batch(
+				func() tea.Msg { return ReloadConfigMsg{} },
+				AddLog("config edited: %s", sshconf.Summary(m.diff.changes)),
+			)
+		case "edit":
+			return m.previousModel, m.previousModel.edit(&m.base)
+		case "revert":
+			if _, err := m.previousModel.snapshots.Restore(m.base); err != nil {
+				m.err = err
+				m.setContent()
+				return m, nil
+			}
+			return m.previousModel, tea.Batch(
+				func() tea.Msg { return ReloadConfigMsg{} },
+				AddLog("config edit reverted, undo it from the snapshots"),
+			)
+		case "diff":
+			m.showUnified = !m.showUnified
+			m.setContent()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.vp, cmd = m.vp.Update(msg)
+	return m, cmd
+}
+
+func (m *editReviewModel) setContent() {
+	theme := m.previousModel.theme
+	var b strings.Builder
+	b.WriteString(m.previousModel.li.Styles.Title.Render(
+		"Edit of "+tildePath(m.previousModel.config.GetPath())) + "\n\n")
+	if m.err != nil {
+		b.WriteString(theme.bad().Render(m.err.Error()) + "\n\n")
+	}
+	b.WriteString(theme.key().Render(sshconf.Summary(m.diff.changes)) + "\n\n")
+	b.WriteString(renderChanges(m.diff.changes, theme))
+	if len(m.diagnostics) > 0 {
+		b.WriteString("\n" + theme.bad().Render(fmt.Sprintf("%d new problems", len(m.diagnostics))) + "\n")
+		for _, d := range m.diagnostics {
+			b.WriteString(theme.bad().Render("  "+d.String()) + "\n")
+		}
+	}
+	for _, n := range m.notes {
+		b.WriteString("\n" + theme.dim().Render(n) + "\n")
+	}
+	if m.showUnified {
+		b.WriteString("\n" + renderUnified(m.diff.unified, theme))
+	}
+	b.WriteString("\n" + theme.dim().Render(
+		m.previousModel.screenHint("edit-review", "accept", "edit", "revert", "diff")))
+	m.vp.SetContent(b.String())
+}
+
+func (m *editReviewModel) View() string {
+	return m.vp.View()
+}
@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/facts"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "collect-facts", Title: "collect facts of selected hosts", Keys: []string{"shift+c"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			var hosts []string
+			for _, it := range m.li.Items() {
+				if h := it.(item).host; m.selected[h.Name] {
+					hosts = append(hosts, h.Name)
+				}
+			}
+			if len(hosts) == 0 {
+				if it, ok := m.li.SelectedItem().(item); ok {
+					hosts = append(hosts, it.host.Name)
+				}
+			}
+			return m, m.collectFacts(hosts)
+		},
+	})
+}
+
+// WithFacts shows the facts of cache in the side view and the filter.
+func WithFacts(cache *facts.Cache) ModelOption {
+	return func(m *Model) {
+		m.facts = cache
+	}
+}
+
+type factsMsg struct {
+	collected []facts.Facts
+	err       error
+}
+
+// collectFacts collects the facts of hosts off the UI loop.
+func (m *Model) collectFacts(hosts []string) tea.Cmd {
+	if m.facts == nil {
+		return AddError(fmt.Errorf("facts cache not set"))
+	}
+	if m.collecting || len(hosts) == 0 {
+		return nil
+	}
+	m.collecting = true
+	cache, config := m.facts, m.config.GetPath()
+	return tea.Batch(
+		AddLog("collecting facts of %d hosts", len(hosts)),
+		func() tea.Msg {
+			collected, err := cache.CollectAll(context.Background(), config, hosts,
+				facts.DefaultWorkers, facts.DefaultTimeout)
+			return factsMsg{collected: collected, err: err}
+		},
+	)
+}
+
+// collectStaleFacts collects the facts missing or past their TTL.
+func (m *Model) collectStaleFacts() tea.Cmd {
+	if m.facts == nil {
+		return nil
+	}
+	var hosts []string
+	now := time.Now()
+	for _, h := range m.config.Hosts {
+		if m.facts.Stale(h.Name, now) {
+			hosts = append(hosts, h.Name)
+		}
+	}
+	return m.collectFacts(hosts)
+}
+
+// factFields returns the facts of host matched by the filter.
+func (m *Model) factFields(host string) map[string][]string {
+	if m.facts == nil {
+		return nil
+	}
+	f, ok := m.facts.Get(host)
+	if !ok {
+		return nil
+	}
+	return f.Fields()
+}
+
+// hostFacts renders the cached facts of host for the side view.
+func (m *Model) hostFacts(host string) string {
+	if m.facts == nil {
+		return ""
+	}
+	f, ok := m.facts.Get(host)
+	if !ok {
+		return ""
+	}
+	now := time.Now()
+	var b strings.Builder
+	row := func(k, v string) {
+		if v != "" {
+			fmt.Fprintf(&b, "%s %s\n", m.theme.key().Render(k), v)
+		}
+	}
+	os := f.OSName
+	if os == "" {
+		os = f.OS
+	}
+	row("os", os)
+	row("kernel", f.Kernel+" "+f.Arch)
+	var hw []string
+	switch {
+	case f.CPUs == 1:
+		hw = append(hw, "1 cpu")
+	case f.CPUs > 1:
+		hw = append(hw, fmt.Sprintf("%d cpus", f.CPUs))
+	}
+	if f.Memory > 0 {
+		hw = append(hw, formatMemory(f.Memory))
+	}
+	row("hardware", strings.Join(hw, ", "))
+	if up := f.Uptime(now); up > 0 {
+		row("uptime", formatAge(up))
+	}
+	collected := "facts collected " + formatAge(now.Sub(f.Collected)) + " ago"
+	if m.facts.Stale(host, now) {
+		collected += ", stale"
+	}
+	b.WriteString(m.theme.dim().Render(collected) + "\n\n")
+	return b.String()
+}
+
+func formatMemory(n int64) string {
+	if n >= 1<<30 {
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	}
+	return formatSize(n)
+}
+
+// formatAge renders d in its largest units: 3d 4h, 2h 5m, 40s.
+func formatAge(d time.Duration) string {
+	d = d.Round(time.Second)
+	days, hours, mins := int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	case mins > 0:
+		return fmt.Sprintf("%dm", mins)
+	}
+	return d.String()
+}
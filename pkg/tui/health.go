@@ -0,0 +1,362 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/health"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "health", Title: "health dashboard of selected or listed hosts", Keys: []string{"shift+m"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			hm := HealthModel(m)
+			return hm, hm.(*healthModel).refresh()
+		},
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "health.sort", Title: "sort", Keys: []string{"s"}},
+		ScreenKey{ID: "health.order", Title: "order", Keys: []string{"o"}},
+		ScreenKey{ID: "health.refresh", Title: "refresh", Keys: []string{"r", "ctrl+t"}},
+		ScreenKey{ID: "health.up", Title: "move up", Keys: []string{"up", "k"}},
+		ScreenKey{ID: "health.down", Title: "move down", Keys: []string{"down", "j"}},
+		ScreenKey{ID: "health.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+// WithHealth sets the probes and refresh interval of the health dashboard.
+func WithHealth(c health.Config) ModelOption {
+	return func(m *Model) {
+		m.health = c
+	}
+}
+
+type healthReportMsg struct {
+	gen    int
+	report health.Report
+}
+
+type healthTickMsg struct {
+	gen int
+}
+
+// HealthModel probes the selected hosts, or the listed ones when
+// none is selected, and refreshes them on an interval.
+func HealthModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	var hosts []string
+	for _, it := range previousModel.li.VisibleItems() {
+		if h := it.(item).host; previousModel.selected[h.Name] {
+			hosts = append(hosts, h.Name)
+		}
+	}
+	if len(hosts) == 0 {
+		for _, it := range previousModel.li.VisibleItems() {
+			hosts = append(hosts, it.(item).host.Name)
+		}
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width())
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	c := previousModel.health.WithDefaults()
+	m := &healthModel{
+		previousModel: previousModel,
+		vp:            vp,
+		config:        c,
+		hosts:         hosts,
+		reports:       map[string]health.Report{},
+		sem:           make(chan struct{}, c.Workers),
+		sortColumn:    1,
+		reverse:       true,
+	}
+	m.render()
+	return m
+}
+
+type healthModel struct {
+	previousModel *Model
+	vp            viewport.Model
+	config        health.Config
+	hosts         []string
+	reports       map[string]health.Report
+	// rows are the hosts in the order shown, the cursor
+	// follows the current host when rows are sorted again.
+	rows    []string
+	cursor  int
+	current string
+	// sortColumn follows health.Sort, reverse sorts descending.
+	sortColumn int
+	reverse    bool
+	// gen tells the reports and ticks of the current refresh,
+	// pending counts the hosts it still waits for.
+	gen     int
+	pending int
+	sem     chan struct{}
+	cancel  context.CancelFunc
+}
+
+// refresh probes every host, a tick schedules the next
+// refresh once all of them reported.
+func (m *healthModel) refresh() tea.Cmd {
+	if m.pending > 0 {
+		return nil
+	}
+	if m.cancel != nil {
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.gen++
+	m.pending = len(m.hosts)
+	gen, config := m.gen, m.previousModel.config.GetPath()
+	cmds := make([]tea.Cmd, 0, len(m.hosts))
+	for _, h := range m.hosts {
+		cmds = append(cmds, func() tea.Msg {
+			select {
+			case m.sem <- struct{}{}:
+			case <-ctx.Done():
+				return healthReportMsg{gen: gen, report: health.Report{Host: h, Err: ctx.Err()}}
+			}
+			defer func() { <-m.sem }()
+			return healthReportMsg{gen: gen, report: health.Run(ctx, config, h, m.config.Probes, m.config.Timeout)}
+		})
+	}
+	m.render()
+	return tea.Batch(cmds...)
+}
+
+func (m *healthModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *healthModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.vp.SetWidth(msg.Width)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case healthReportMsg:
+		if msg.gen != m.gen {
+			return m, nil
+		}
+		m.reports[msg.report.Host] = msg.report
+		m.pending--
+		m.render()
+		if m.pending > 0 {
+			return m, nil
+		}
+		return m, tea.Tick(m.config.Interval, func(time.Time) tea.Msg {
+			return healthTickMsg{gen: msg.gen}
+		})
+	case healthTickMsg:
+		if msg.gen != m.gen {
+			return m, nil
+		}
+		return m, m.refresh()
+	case tea.KeyPressMsg:
+		switch m.previousModel.screenKey("health", msg) {
+		case "back":
+			if m.cancel != nil {
+				m.cancel()
+			}
+			return m.previousModel, nil
+		case "up":
+			if len(m.rows) > 0 {
+				m.current = m.rows[max(0, m.cursor-1)]
+			}
+		case "down":
+			if len(m.rows) > 0 {
+				m.current = m.rows[min(len(m.rows)-1, m.cursor+1)]
+			}
+		case "sort":
+			m.sortColumn = (m.sortColumn + 1) % (len(m.config.Probes) + 2)
+		case "order":
+			m.reverse = !m.reverse
+		case "refresh":
+			return m, m.refresh()
+		default:
+			var cmd tea.Cmd
+			m.vp, cmd = m.vp.Update(msg)
+			return m, cmd
+		}
+		m.render()
+		return m, nil
+	}
+	return m, nil
+}
+
+// columns returns the headers of the table: host, status
+// and the probes with their unit.
+func (m *healthModel) columns() []string {
+	cols := []string{"host", "status"}
+	for _, p := range m.config.Probes {
+		name := p.Name
+		if p.Unit != "" {
+			name += " " + p.Unit
+		}
+		cols = append(cols, name)
+	}
+	return cols
+}
+
+// cell renders the value of column for r, styled by its level.
+func (m *healthModel) cell(r health.Report, ok bool, column int) (string, lg.Style) {
+	theme := m.previousModel.theme
+	levelStyle := map[health.Level]lg.Style{
+		health.Unknown: theme.dim(),
+		health.OK:      theme.good(),
+		health.Warn:    theme.warn(),
+		health.Crit:    theme.bad(),
+	}
+	switch {
+	case column == 0:
+		return r.Host, lg.NewStyle()
+	case !ok:
+		return "…", theme.dim()
+	case column == 1:
+		if r.Err != nil {
+			return "down", theme.bad()
+		}
+		return r.Worst().String(), levelStyle[r.Worst()]
+	case r.Err != nil || column-2 >= len(r.Values):
+		return "-", theme.dim()
+	}
+	v, p := r.Values[column-2], m.config.Probes[column-2]
+	switch {
+	case v.Level == health.Unknown && v.Text == "":
+		return "-", theme.dim()
+	case v.Level == health.Unknown:
+		return v.Text, theme.dim()
+	case !p.Thresholds():
+		return strconv.FormatFloat(v.Number, 'f', -1, 64), lg.NewStyle()
+	}
+	return strconv.FormatFloat(v.Number, 'f', -1, 64), levelStyle[v.Level]
+}
+
+func (m *healthModel) render() {
+	theme := m.previousModel.theme
+	reports := make([]health.Report, 0, len(m.hosts))
+	for _, h := range m.hosts {
+		r, ok := m.reports[h]
+		if !ok {
+			r = health.Report{Host: h}
+		}
+		reports = append(reports, r)
+	}
+	health.Sort(reports, m.sortColumn, m.reverse)
+	m.rows, m.cursor = m.rows[:0], 0
+	for i, r := range reports {
+		if r.Host == m.current {
+			m.cursor = i
+		}
+		m.rows = append(m.rows, r.Host)
+	}
+
+	cols := m.columns()
+	widths := make([]int, len(cols))
+	cells := make([][]string, len(reports))
+	styles := make([][]lg.Style, len(reports))
+	for c, name := range cols {
+		widths[c] = lg.Width(name) + 2
+	}
+	for i, r := range reports {
+		_, ok := m.reports[r.Host]
+		for c := range cols {
+			text, style := m.cell(r, ok, c)
+			cells[i] = append(cells[i], text)
+			styles[i] = append(styles[i], style)
+			widths[c] = max(widths[c], lg.Width(text))
+		}
+	}
+
+	var b strings.Builder
+	title := fmt.Sprintf("Health (%d hosts, every %v)", len(m.hosts), m.config.Interval)
+	if m.pending > 0 {
+		title += fmt.Sprintf(" probing %d", m.pending)
+	}
+	b.WriteString(m.previousModel.li.Styles.Title.Render(title) + "\n\n")
+	b.WriteString("  ")
+	for c, name := range cols {
+		if c == m.sortColumn {
+			arrow := "↑"
+			if m.reverse {
+				arrow = "↓"
+			}
+			name += arrow
+		}
+		b.WriteString(theme.key().Render(pad(name, widths[c], c > 0)) + "  ")
+	}
+	b.WriteString("\n")
+	for i := range reports {
+		prefix := "  "
+		if i == m.cursor {
+			prefix = theme.key().Render("› ")
+		}
+		b.WriteString(prefix)
+		for c := range cols {
+			b.WriteString(styles[i][c].Render(pad(cells[i][c], widths[c], c > 0)) + "  ")
+		}
+		b.WriteString("\n")
+	}
+	if len(reports) > 0 {
+		b.WriteString("\n" + m.details(reports[m.cursor]))
+	}
+	b.WriteString("\n" + theme.dim().Render(m.previousModel.screenHint("health", "sort", "order", "refresh", "back")))
+	m.vp.SetContent(b.String())
+	// keep the cursor in view below the title and header
+	if line := m.cursor + 3; line < m.vp.YOffset {
+		m.vp.SetYOffset(line)
+	} else if line >= m.vp.YOffset+m.vp.Height()-2 {
+		m.vp.SetYOffset(line - m.vp.Height() + 3)
+	}
+}
+
+// details renders the error or the raw output of the selected host.
+func (m *healthModel) details(r health.Report) string {
+	theme := m.previousModel.theme
+	if _, ok := m.reports[r.Host]; !ok {
+		return theme.dim().Render(r.Host+": probing") + "\n"
+	}
+	if r.Err != nil {
+		return theme.bad().Render(r.Host+": "+r.Err.Error()) + "\n"
+	}
+	var unparsed []string
+	for _, v := range r.Values {
+		if v.Level == health.Unknown && v.Text != "" {
+			unparsed = append(unparsed, fmt.Sprintf("%s: %q", v.Probe, v.Text))
+		}
+	}
+	line := fmt.Sprintf("%s: probed at %s in %v", r.Host, r.Time.Format("15:04:05"), r.Elapsed.Round(time.Millisecond))
+	if len(unparsed) > 0 {
+		line += ", not numbers " + strings.Join(unparsed, " ")
+	}
+	return theme.dim().Render(line) + "\n"
+}
+
+// pad aligns s in width, right when right is set.
+func pad(s string, width int, right bool) string {
+	gap := strings.Repeat(" ", max(0, width-lg.Width(s)))
+	if right {
+		return gap + s
+	}
+	return s + gap
+}
+
+func (m *healthModel) View() string {
+	return m.vp.View()
+}
@@ -0,0 +1,20 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"github.com/lfaoro/ssm/pkg/tui"
+)
+
+func TestHealthNoHosts(t *testing.T) {
+	config := sshconftest.Parse(t, "")
+	m := tui.HealthModel(tui.NewModel(config, false))
+	for _, code := range []rune{'j', 'k', tea.KeyDown, tea.KeyUp} {
+		m, _ = m.Update(tea.KeyPressMsg{Code: code})
+	}
+	if m.(tea.ViewModel).View() == "" {
+		t.Error("empty view")
+	}
+}
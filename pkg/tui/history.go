@@ -0,0 +1,184 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/history"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "history", Title: "session history", Keys: []string{"shift+h"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return HistoryModel(m), nil },
+	})
+}
+
+type historyItem struct {
+	entry history.Entry
+}
+
+func (i historyItem) Title() string {
+	return fmt.Sprintf("%s · %s", i.entry.Host, i.entry.Connector)
+}
+
+func (i historyItem) Description() string {
+	return fmt.Sprintf("%s  %s  exit %d",
+		i.entry.Start.Format("2006-01-02 15:04"),
+		i.entry.Duration,
+		i.entry.ExitCode,
+	)
+}
+
+func (i historyItem) FilterValue() string {
+	return i.entry.Host + " " + i.entry.Connector + " " + i.entry.Start.Format("2006-01-02")
+}
+
+// HistoryModel browses the recorded sessions,
+// enter re-connects to the selected host.
+func HistoryModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	li := list.New([]list.Item{}, d, previousModel.li.Width()/2, previousModel.li.Height())
+	li.Title = fmt.Sprintf("Sessions (%s)", previousModel.history.Path())
+	li.Styles.Title = previousModel.li.Styles.Title
+	li.SetStatusBarItemName("session", "sessions")
+	li.DisableQuitKeybindings()
+	li.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "re-connect"),
+			),
+			key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
+		}
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width() / 2)
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	m := &historyModel{
+		previousModel: previousModel,
+		li:            li,
+		vp:            vp,
+	}
+	entries, err := previousModel.history.Entries()
+	if err != nil {
+		m.err = err
+	}
+	items := make([]list.Item, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, historyItem{entry: e})
+	}
+	m.li.SetItems(items)
+	m.setDetails()
+	return m
+}
+
+type historyModel struct {
+	previousModel *Model
+	li            list.Model
+	vp            viewport.Model
+	err           error
+}
+
+func (m *historyModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *historyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width/2, msg.Height-1)
+		m.vp.SetWidth(msg.Width - msg.Width/2)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		if m.li.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.Code {
+		case tea.KeyEsc:
+			if m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
+			}
+			return m.previousModel, nil
+		case 'q':
+			return m.previousModel, nil
+		case tea.KeyEnter:
+			selected, ok := m.li.SelectedItem().(historyItem)
+			if !ok {
+				return m, nil
+			}
+			return m.previousModel, func() tea.Msg {
+				return ConnectHostMsg{
+					Name:      selected.entry.Host,
+					Connector: selected.entry.Connector,
+				}
+			}
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	m.setDetails()
+	return m, cmd
+}
+
+// setDetails shows the selected session in the viewport.
+func (m *historyModel) setDetails() {
+	if m.err != nil {
+		m.vp.SetContent(m.previousModel.log.ErrStyle.Render(m.err.Error()))
+		return
+	}
+	selected, ok := m.li.SelectedItem().(historyItem)
+	if !ok {
+		m.vp.SetContent("(no sessions recorded)")
+		return
+	}
+	e := selected.entry
+	keyStyle := m.previousModel.theme.key()
+	var b strings.Builder
+	row := func(k, v string) {
+		fmt.Fprintf(&b, "%s %s\n", keyStyle.Render(fmt.Sprintf("%-10s", k)), v)
+	}
+	row("host", e.Host)
+	row("connector", e.Connector)
+	row("start", e.Start.Format("2006-01-02 15:04:05"))
+	row("duration", e.Duration.String())
+	row("exit", fmt.Sprintf("%d", e.ExitCode))
+	if e.Error != "" {
+		row("error", e.Error)
+	}
+	if len(e.Stderr) > 0 {
+		b.WriteString("\n" + keyStyle.Render("stderr") + "\n")
+		b.WriteString(strings.Join(e.Stderr, "\n"))
+	}
+	m.vp.SetContent(b.String())
+}
+
+func (m *historyModel) View() string {
+	return lg.JoinHorizontal(lg.Top, m.li.View(), m.vp.View())
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/launch"
+)
+
+// hookOutputLines bounds the hook output shown in the log area.
+const hookOutputLines = 5
+
+// WithHooks runs the pre- and post-connect hooks of config.
+func WithHooks(config hook.Config) ModelOption {
+	return func(m *Model) {
+		m.hooks = config
+	}
+}
+
+// dial is a connection waiting for its pre-connect hooks.
+type dial struct {
+	host      item
+	connector string
+	argv      []string
+	env       []string
+	// hookEnv describes the host to its hooks.
+	hookEnv []string
+	post    []string
+}
+
+// preHookMsg reports the pre-connect hooks of connections,
+// they open when the hooks succeed: tiled when tile is set.
+type preHookMsg struct {
+	dials []dial
+	tile  bool
+	out   []byte
+	err   error
+}
+
+// runPreHooks runs pre[i], the pre-connect hooks of dials[i],
+// in order then sends a preHookMsg.
+func (m *Model) runPreHooks(dials []dial, pre [][]string, tile bool) tea.Cmd {
+	timeout := m.hooks.Timeout
+	return func() tea.Msg {
+		var out []byte
+		for i, d := range dials {
+			o, err := hook.Run(context.Background(), pre[i], d.hookEnv, timeout)
+			out = append(out, o...)
+			if err != nil {
+				return preHookMsg{dials: dials, tile: tile, out: out, err: fmt.Errorf("%s: pre-connect %w", d.host.host.Name, err)}
+			}
+		}
+		return preHookMsg{dials: dials, tile: tile, out: out}
+	}
+}
+
+// hookError returns err with the last lines of the failed hook output.
+func hookError(err error) error {
+	var hookErr *hook.Error
+	if !errors.As(err, &hookErr) {
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(string(hookErr.Output)), "\n")
+	if len(lines) > hookOutputLines {
+		lines = lines[len(lines)-hookOutputLines:]
+	}
+	if out := strings.Join(lines, "\n"); out != "" {
+		return fmt.Errorf("%w\n%s", err, out)
+	}
+	return err
+}
+
+// hookLog logs the output of hooks that succeeded.
+func hookLog(phase, host string, out []byte) tea.Cmd {
+	out = []byte(strings.TrimSpace(string(out)))
+	if len(out) == 0 {
+		return nil
+	}
+	return AddLog("%s %s: %s", phase, host, strings.ReplaceAll(string(out), "\n", " ⏎ "))
+}
+
+// runPostHooks runs the post-connect hooks of d once its
+// session ended with exitCode after duration.
+func (m *Model) runPostHooks(d dial, exitCode int, duration time.Duration) tea.Cmd {
+	if len(d.post) == 0 {
+		return nil
+	}
+	env := append(d.hookEnv, hook.ResultEnv(exitCode, duration)...)
+	timeout := m.hooks.Timeout
+	return func() tea.Msg {
+		out, err := hook.Run(context.Background(), d.post, env, timeout)
+		if err != nil {
+			return ErrorMsg{Err: hookError(fmt.Errorf("%s: post-connect %w", d.host.host.Name, err))}
+		}
+		if log := hookLog("post-connect", d.host.host.Name, out); log != nil {
+			return log()
+		}
+		return nil
+	}
+}
+
+// hookArgv wraps the argv of d with `ssm hook` for the sessions
+// running outside of ssm: tmux, terminals and --exit. The wrapper
+// runs their post-connect hooks and records them in the history.
+func (m *Model) hookArgv(d dial) ([]string, error) {
+	hist := m.history.Path()
+	if len(d.post) == 0 && hist == "" {
+		return d.argv, nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("post-connect hooks of %s: %w", d.host.host.Name, err)
+	}
+	spec, err := json.Marshal(hook.Deferred{
+		Post:      d.post,
+		Env:       d.hookEnv,
+		History:   hist,
+		Host:      d.host.host.Name,
+		Connector: d.connector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{self, "hook", "--spec", string(spec), "--"}, d.argv...), nil
+}
+
+// outside reports whether sessions run outside of ssm.
+func (m *Model) outside() bool {
+	return m.launcher.Target != launch.Replace || m.ExitOnCmd
+}
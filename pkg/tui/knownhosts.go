@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/knownhosts"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "known-hosts", Title: "known hosts keys", Keys: []string{"shift+k"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			km := KnownHostsModel(m)
+			return km, km.Init()
+		},
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "known-hosts.scan", Title: "scan", Keys: []string{"s"}},
+		ScreenKey{ID: "known-hosts.remove", Title: "remove stale", Keys: []string{"d"}},
+		ScreenKey{ID: "known-hosts.replace", Title: "replace stale with presented", Keys: []string{"r"}},
+		ScreenKey{ID: "known-hosts.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+// hostKeyChanged is printed by ssh when a recorded host key mismatches.
+const hostKeyChanged = "REMOTE HOST IDENTIFICATION HAS CHANGED"
+
+type knownHostsScanMsg struct {
+	keys []ssh.PublicKey
+	err  error
+}
+
+type knownHostsModel struct {
+	previousModel *Model
+	host          sshconf.Host
+	name, port    string
+	userFiles     []string
+	globalFiles   []string
+
+	entries   []knownhosts.Entry
+	presented []ssh.PublicKey
+	stale     []knownhosts.Entry
+	scanning  bool
+	status    string
+	err       error
+
+	vp viewport.Model
+}
+
+// KnownHostsModel shows the recorded host keys of the selected host
+// and repairs stale entries.
+func KnownHostsModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+	selected, _ := previousModel.li.SelectedItem().(item)
+
+	m := &knownHostsModel{
+		previousModel: previousModel,
+		host:          selected.host,
+		name:          selected.host.HostName(),
+		port:          selected.host.Port(),
+	}
+	if alias := selected.host.HostKeyAlias(); alias != "" {
+		m.name = alias
+	}
+	m.userFiles, m.globalFiles = knownhosts.Files(selected.host)
+	m.vp = viewport.New()
+	m.vp.SetWidth(previousModel.li.Width())
+	m.vp.SetHeight(previousModel.li.Height() - 2)
+	m.load()
+	return m
+}
+
+// load reads the recorded entries for the host.
+func (m *knownHostsModel) load() {
+	files := append(append([]string{}, m.userFiles...), m.globalFiles...)
+	m.entries, m.err = knownhosts.Lookup(files, m.name, m.port)
+	if m.presented != nil {
+		m.stale = knownhosts.Mismatch(m.entries, m.presented)
+	}
+	m.render()
+}
+
+func (m *knownHostsModel) Init() tea.Cmd {
+	return m.scan()
+}
+
+// scan fetches the keys the host presents now.
+func (m *knownHostsModel) scan() tea.Cmd {
+	if m.host.Name == "" || m.scanning {
+		return nil
+	}
+	m.scanning = true
+	m.status = "scanning host keys..."
+	m.render()
+	host := m.host
+	return func() tea.Msg {
+		keys, err := knownhosts.Scan(context.Background(), host, 5*time.Second)
+		return knownHostsScanMsg{keys: keys, err: err}
+	}
+}
+
+func (m *knownHostsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.vp.SetWidth(msg.Width)
+		m.vp.SetHeight(msg.Height - 2)
+		m.previousModel.Update(msg)
+	case knownHostsScanMsg:
+		m.scanning = false
+		m.status = ""
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		}
+		m.presented = msg.keys
+		m.stale = knownhosts.Mismatch(m.entries, m.presented)
+		m.render()
+		return m, nil
+	case tea.KeyPressMsg:
+		switch m.previousModel.screenKey("known-hosts", msg) {
+		case "back":
+			return m.previousModel, nil
+		case "scan":
+			return m, m.scan()
+		case "remove":
+			if len(m.stale) == 0 {
+				m.status = "nothing to remove: scan first or no mismatch found"
+				m.render()
+				return m, nil
+			}
+			if err := knownhosts.Remove(m.stale); err != nil {
+				m.status = err.Error()
+			} else {
+				m.status = fmt.Sprintf("removed %d stale entries, backup saved as <file>.old", len(m.stale))
+			}
+			m.load()
+			return m, nil
+		case "replace":
+			if len(m.stale) == 0 || len(m.presented) == 0 {
+				m.status = "nothing to replace: scan first or no mismatch found"
+				m.render()
+				return m, nil
+			}
+			file := m.stale[0].File
+			err := knownhosts.Replace(file, m.name, m.port, m.stale, m.presented)
+			if err != nil {
+				m.status = err.Error()
+			} else {
+				m.status = fmt.Sprintf("replaced %d stale entries in %s, backup saved as %s.old", len(m.stale), file, file)
+			}
+			m.load()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.vp, cmd = m.vp.Update(msg)
+	return m, cmd
+}
+
+func (m *knownHostsModel) render() {
+	c := m.previousModel.theme
+	keyStyle, dim, bad, good := c.key(), c.dim(), c.bad(), c.good()
+
+	var b strings.Builder
+	if m.host.Name == "" {
+		m.vp.SetContent("no host selected")
+		return
+	}
+	fmt.Fprintf(&b, "%s %s port %s\n", keyStyle.Render("host"), m.name, m.port)
+	files := func(list []string) string {
+		if len(list) == 0 {
+			return "none"
+		}
+		return strings.Join(list, " ")
+	}
+	fmt.Fprintf(&b, "%s %s\n", keyStyle.Render("user files"), files(m.userFiles))
+	fmt.Fprintf(&b, "%s %s\n\n", keyStyle.Render("global files"), files(m.globalFiles))
+	if m.err != nil {
+		b.WriteString(bad.Render(m.err.Error()) + "\n\n")
+	}
+
+	b.WriteString(keyStyle.Render("recorded keys") + "\n")
+	if len(m.entries) == 0 {
+		b.WriteString(dim.Render("  none") + "\n")
+	}
+	for _, e := range m.entries {
+		state := ""
+		if m.presented != nil && e.Marker == "" {
+			state = good.Render("match")
+			if isStale(e, m.stale) {
+				state = bad.Render("MISMATCH")
+			} else if !hasType(m.presented, e.Type()) {
+				state = dim.Render("not presented")
+			}
+		}
+		hashed := ""
+		if e.Hashed() {
+			hashed = dim.Render(" hashed")
+		}
+		marker := ""
+		if e.Marker != "" {
+			marker = e.Marker + " "
+		}
+		fmt.Fprintf(&b, "  %s%s %s %s%s %s\n",
+			marker, e.Type(), e.Fingerprint,
+			dim.Render(fmt.Sprintf("%s:%d", e.File, e.Line)), hashed, state)
+	}
+
+	b.WriteString("\n" + keyStyle.Render("presented keys") + "\n")
+	switch {
+	case m.scanning:
+		b.WriteString(dim.Render("  scanning...") + "\n")
+	case len(m.presented) == 0:
+		b.WriteString(dim.Render("  not scanned") + "\n")
+	}
+	for _, k := range m.presented {
+		fmt.Fprintf(&b, "  %s %s\n", k.Type(), ssh.FingerprintSHA256(k))
+	}
+
+	if len(m.stale) > 0 {
+		b.WriteString("\n" + bad.Render(fmt.Sprintf("%d recorded keys differ from the presented ones", len(m.stale))) + "\n")
+	}
+	if m.status != "" {
+		b.WriteString("\n" + m.status + "\n")
+	}
+	b.WriteString("\n" + dim.Render(m.previousModel.screenHint("known-hosts", "scan", "remove", "replace", "back")))
+	m.vp.SetContent(b.String())
+}
+
+func isStale(e knownhosts.Entry, stale []knownhosts.Entry) bool {
+	for _, s := range stale {
+		if s.File == e.File && s.Line == e.Line {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(keys []ssh.PublicKey, t string) bool {
+	for _, k := range keys {
+		if k.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *knownHostsModel) View() string {
+	bar := m.previousModel.theme.primaryBar("Known Hosts", m.previousModel.theme.SelectedTitle)
+	return bar + "\n\n" + m.vp.View()
+}
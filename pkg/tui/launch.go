@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/launch"
+	"github.com/lfaoro/ssm/pkg/secret"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "select", Title: "select host for a tiled tmux window", Keys: []string{"space"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return m, m.toggleSelect() },
+	})
+	RegisterAction(Action{
+		ID: "launch-target", Title: "cycle launch target: replace, tmux window, tmux pane, terminal",
+		Keys: []string{"shift+tab"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.launcher.Target = m.launcher.Target.Next()
+			m.li.NewStatusMessage(m.status())
+			return m, AddLog("launch: %s", m.launcher.Target)
+		},
+	})
+	RegisterAction(Action{
+		ID: "sync-panes", Title: "toggle synchronize-panes of tiled windows",
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.launcher.Sync = !m.launcher.Sync
+			return m, AddLog("synchronize-panes: %v", m.launcher.Sync)
+		},
+	})
+}
+
+// WithLauncher opens connections at the target of l.
+func WithLauncher(l launch.Launcher) ModelOption {
+	return func(m *Model) {
+		if l.Target == "" {
+			l.Target = launch.Replace
+		}
+		m.launcher = l
+	}
+}
+
+// toggleSelect adds or removes the selected host
+// from the hosts opened together.
+func (m *Model) toggleSelect() tea.Cmd {
+	host, ok := m.li.SelectedItem().(item)
+	if !ok {
+		return nil
+	}
+	if m.selected[host.host.Name] {
+		delete(m.selected, host.host.Name)
+	} else {
+		m.selected[host.host.Name] = true
+	}
+	m.li.CursorDown()
+	m.li.NewStatusMessage(m.status())
+	return m.refreshItems()
+}
+
+// launchOut opens host at the launch target, ssm stays open.
+func (m *Model) launchOut(name string, argv []string) tea.Cmd {
+	if err := m.launcher.Open(launch.Session{Name: name, Argv: argv}); err != nil {
+		return AddError(err)
+	}
+	return AddLog("opened %s: %s", name, m.launcher.Target)
+}
+
+// connectSelection opens the selected hosts as the panes
+// of a tiled tmux window, in list order.
+func (m *Model) connectSelection() tea.Cmd {
+	var (
+		dials []dial
+		pre   [][]string
+		hooks int
+	)
+	for _, it := range m.li.Items() {
+		host := it.(item).host
+		if !m.selected[host.Name] {
+			continue
+		}
+		if secret.Spec(host) != "" {
+			return AddError(errSecretOutside(host.Name))
+		}
+		conn, err := m.connectorFor(host)
+		if err != nil {
+			return AddError(err)
+		}
+		argv, err := conn.Argv(connector.DataOf(host, m.config.GetPath()))
+		if err != nil {
+			return AddError(err)
+		}
+		hs := m.hooks.For(host)
+		dials = append(dials, dial{
+			host:      it.(item),
+			connector: conn.Name,
+			argv:      argv,
+			hookEnv:   hook.Env(host, conn.Name),
+			post:      hs.Post,
+		})
+		pre = append(pre, hs.Pre)
+		hooks += len(hs.Pre)
+	}
+	if hooks > 0 {
+		return tea.Batch(
+			AddLog("running %d pre-connect hooks of %d hosts", hooks, len(dials)),
+			m.runPreHooks(dials, pre, true),
+		)
+	}
+	return m.tile(dials)
+}
+
+// tile opens dials tiled once their pre-connect hooks ran.
+func (m *Model) tile(dials []dial) tea.Cmd {
+	var sessions []launch.Session
+	for _, d := range dials {
+		name := d.host.host.Name
+		if rec := m.recordPath(d.host.host); rec != "" {
+			argv, err := recordArgv(rec, name, d.argv)
+			if err != nil {
+				return AddError(err)
+			}
+			d.argv = argv
+		}
+		argv, err := m.hookArgv(d)
+		if err != nil {
+			return AddError(err)
+		}
+		sessions = append(sessions, launch.Session{Name: name, Argv: argv})
+		m.state.Record(name, time.Now())
+	}
+	attach, err := m.launcher.Tile(sessions)
+	if err != nil {
+		return AddError(fmt.Errorf("tiling %d hosts: %w", len(sessions), err))
+	}
+	clear(m.selected)
+	m.li.NewStatusMessage(m.status())
+	cmds := []tea.Cmd{
+		saveState(m.state),
+		m.refreshItems(),
+		AddLog("opened %d hosts tiled, synchronize-panes: %v", len(sessions), m.launcher.Sync),
+	}
+	switch {
+	case attach == nil && m.ExitOnCmd:
+		cmds = append(cmds, tea.Quit)
+	case attach != nil && m.ExitOnCmd:
+		m.ExitArgv = attach
+		cmds = append(cmds, tea.Quit)
+	case attach != nil:
+		// outside of tmux the tiled session takes the terminal
+		cmds = append(cmds, tea.ExecProcess(exec.Command(attach[0], attach[1:]...), func(err error) tea.Msg {
+			if err != nil {
+				return ErrorMsg{Err: fmt.Errorf("tmux: %w", err)}
+			}
+			return nil
+		}))
+	}
+	return tea.Sequence(cmds...)
+}
@@ -2,37 +2,41 @@ package tui
 
 import (
 	"fmt"
+	"maps"
 
-	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/list"
 	"github.com/charmbracelet/bubbles/v2/textinput"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/jumpgraph"
+	"github.com/lfaoro/ssm/pkg/launch"
+	"github.com/lfaoro/ssm/pkg/query"
 	"github.com/lfaoro/ssm/pkg/sshconf"
 )
 
 type item struct {
 	title, desc string
+	host        sshconf.Host
+	// fields are matched by the filter besides those of host.
+	fields map[string][]string
 }
 
 func (i item) Title() string       { return i.title }
 func (i item) Description() string { return i.desc }
 func (i item) FilterValue() string { return i.title + i.desc }
 
-func listFrom(config *sshconf.Config, theme theme) list.Model {
+func listFrom(path string, items []item, c palette) list.Model {
 	var li list.Model
-	var c = theme
-	lightDark := lg.LightDark(true)
 	d := list.NewDefaultDelegate()
 	d.ShowDescription = true
 	d.SetSpacing(0)
 	d.Styles.SelectedTitle = lg.NewStyle().
 		Border(lg.NormalBorder(), false, false, false, true).
-		BorderForeground(lightDark(lg.Color("#F79F3F"), lg.Color(c.selectedBorderColor))).
-		Foreground(lightDark(lg.Color("#F79F3F"), lg.Color(c.selectedTitleColor))).
+		BorderForeground(lg.Color(c.SelectedBorder)).
+		Foreground(lg.Color(c.SelectedTitle)).
 		Padding(0, 0, 0, 1)
 	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
-		Foreground(lightDark(lg.Color("#F79F3F"), lg.Color(c.selectedDescriptionColor)))
+		Foreground(lg.Color(c.SelectedDescription))
 	// d.Styles.SelectedTitle = lg.NewStyle().
 	// 	Border(lg.NormalBorder(), false, false, false, true).
 	// 	BorderForeground(lightDark(lg.Color("#F79F3F"), lg.Color("#00bfff"))).
@@ -47,26 +51,23 @@ func listFrom(config *sshconf.Config, theme theme) list.Model {
 		0,
 		0,
 	)
-	li.AdditionalFullHelpKeys = func() []key.Binding {
-		return initKeys()
-	}
 	li.FilterInput.Prompt = "Search: "
-	li.FilterInput.CharLimit = 12
+	li.FilterInput.CharLimit = 0
 	li.FilterInput.VirtualCursor = true
-	li.FilterInput.Placeholder = "hostName or tagName"
+	li.FilterInput.Placeholder = "host, user:root, tag:prod -tag:legacy | port:2222"
 	li.FilterInput.Styles.Cursor = textinput.CursorStyle{
-		Color: lg.BrightBlue,
+		Color: lg.Color(c.FilterCursor),
 		Shape: tea.CursorBlock,
 	}
 	li.Styles.StatusBar = lg.NewStyle().
-		Foreground(lightDark(lg.Color("#A49FA5"), lg.Color("#777777"))).
+		Foreground(lg.Color(c.StatusBar)).
 		Padding(0, 0, 1, 2) //nolint:mnd
 	li.Styles.Title = lg.NewStyle().
-		Background(lg.Color(c.mainTitleColor)).
-		Foreground(lg.Color("230")).
+		Background(lg.Color(c.Title)).
+		Foreground(lg.Color(c.TitleText)).
 		Padding(0, 1)
 	li.SetStatusBarItemName("host", "hosts")
-	li.Title = fmt.Sprintf("SSH servers (%v)", config.GetPath())
+	li.Title = fmt.Sprintf("SSH servers (%v)", path)
 
 	// add segfault.net (free root server provider)
 	// segfaultHost := sshconf.Host{
@@ -77,14 +78,103 @@ func listFrom(config *sshconf.Config, theme theme) list.Model {
 	// segfaultHost.Options.Add("user", "root")
 	// config.Hosts = append(config.Hosts, segfaultHost)
 
-	for _, host := range config.Hosts {
-		newitem := formatHost(host)
-		li.InsertItem(len(config.Hosts), newitem)
+	records := make([]query.Record, 0, len(items))
+	for _, newitem := range items {
+		li.InsertItem(len(items), newitem)
+		r := query.FromHost(newitem.host)
+		maps.Copy(r.Fields, newitem.fields)
+		records = append(records, r)
 	}
+	li.Filter = queryFilter(records)
 	return li
 }
 
-func formatHost(host sshconf.Host) item {
+// queryFilter ranks the list items using the query language,
+// records must be in the same order as the list items.
+func queryFilter(records []query.Record) list.FilterFunc {
+	return func(term string, _ []string) []list.Rank {
+		results := query.Parse(term).Filter(records)
+		ranks := make([]list.Rank, 0, len(results))
+		for _, r := range results {
+			ranks = append(ranks, list.Rank{
+				Index:          r.Index,
+				MatchedIndexes: r.Indexes,
+			})
+		}
+		return ranks
+	}
+}
+
+// reloadList rebuilds the host list in the current sort order,
+// keeping size, filter and selection.
+func (m *Model) reloadList() {
+	selected, _ := m.li.SelectedItem().(item)
+	filter := m.li.FilterValue()
+	width, height := m.li.Width(), m.li.Height()
+
+	hosts := m.state.Sort(m.config.Hosts, m.sort)
+	items := make([]item, 0, len(hosts))
+	for _, host := range hosts {
+		items = append(items, m.hostItem(host))
+	}
+	m.jumps = jumpgraph.New(m.config.Hosts)
+	m.li = listFrom(m.config.GetPath(), items, m.theme)
+	m.applyKeymap()
+	m.li.SetSize(width, height)
+	if filter != "" {
+		m.li.SetFilterText(filter)
+	}
+	for i, it := range m.li.VisibleItems() {
+		if it.(item).host.Name == selected.host.Name {
+			m.li.Select(i)
+			break
+		}
+	}
+	m.li.NewStatusMessage(m.status())
+}
+
+// refreshItems re-renders the items in place, unlike reloadList
+// it doesn't interrupt filtering.
+func (m *Model) refreshItems() tea.Cmd {
+	var cmds []tea.Cmd
+	for i, it := range m.li.Items() {
+		cmds = append(cmds, m.li.SetItem(i, m.hostItem(it.(item).host)))
+	}
+	return tea.Batch(cmds...)
+}
+
+// hostItem decorates the host with its pin, liveness and master.
+func (m *Model) hostItem(host sshconf.Host) item {
+	it := formatHost(host, m.theme)
+	if m.state.Pinned(host.Name) {
+		// suffixed to keep filter match positions valid
+		it.title += " ★"
+	}
+	if m.selected[host.Name] {
+		it.title += " ✔"
+	}
+	it.title += m.masterMarker(host.Name)
+	if res, ok := m.alive[host.Name]; ok {
+		it.desc = livenessMarker(res, m.theme) + " " + it.desc + livenessLatency(res, m.theme)
+	}
+	it.fields = m.factFields(host.Name)
+	return it
+}
+
+// status is the list status message: connector, sort order,
+// launch target and selected hosts.
+func (m *Model) status() string {
+	s := fmt.Sprintf("[%s] sort:%s", m.Cmd, m.sort)
+	if m.launcher.Target != launch.Replace {
+		s += " launch:" + m.launcher.Target.String()
+	}
+	if n := len(m.selected); n > 0 {
+		s += fmt.Sprintf(" selected:%d", n)
+	}
+	return s
+}
+
+func formatHost(host sshconf.Host, c palette) item {
 	fmtDescription := func() string {
 		port := func() string {
 			_port, _ := host.Options.Get("port")
@@ -110,8 +200,7 @@ func formatHost(host sshconf.Host) item {
 		tags := func() string {
 			_tags, _ := host.Options.Get("#tag:")
 			if _tags != "" {
-				s := lg.NewStyle().Foreground(lg.Color("8"))
-				return s.Render("#" + _tags)
+				return c.dim().Render("#" + _tags)
 			}
 			return ""
 		}
@@ -121,31 +210,7 @@ func formatHost(host sshconf.Host) item {
 	newitem := item{
 		title: host.Name,
 		desc:  fmtDescription,
+		host:  host,
 	}
 	return newitem
 }
-
-func initKeys() []key.Binding {
-	editKey := key.NewBinding(
-		key.WithKeys("ctrl+e"),
-		key.WithHelp("ctrl+e", "edit config"),
-	)
-	showKey := key.NewBinding(
-		key.WithKeys("ctrl+v"),
-		key.WithHelp("ctrl+v", "show config"),
-	)
-	switchKey := key.NewBinding(
-		key.WithKeys("tab"),
-		key.WithHelp("tab", "switch ssh/mosh"),
-	)
-	connectKey := key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "connect"),
-	)
-	return []key.Binding{
-		connectKey,
-		switchKey,
-		editKey,
-		showKey,
-	}
-}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/liveness"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "ping", Title: "ping hosts", Keys: []string{"ctrl+t"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			return m, func() tea.Msg { return LivenessCheckMsg{} }
+		},
+	})
+}
+
+// defaultPingInterval refreshes liveness when no interval is set.
+const defaultPingInterval = 30 * time.Second
+
+type livenessResultMsg struct {
+	results []liveness.Result
+}
+
+// checkLiveness runs the checks off the UI loop.
+func checkLiveness(configPath string, hosts []sshconf.Host) tea.Cmd {
+	return func() tea.Msg {
+		checker := liveness.New(configPath)
+		results := checker.CheckAll(context.Background(), hosts)
+		return livenessResultMsg{results: results}
+	}
+}
+
+type livenessTickMsg struct {
+	gen int
+}
+
+// nextLivenessCheck schedules the following check,
+// ticks from a stale generation are ignored.
+func nextLivenessCheck(interval time.Duration, gen int) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return livenessTickMsg{gen: gen}
+	})
+}
+
+// startLivenessCheck marks unchecked hosts as unknown and checks every host.
+func (m *Model) startLivenessCheck() tea.Cmd {
+	if m.pinging {
+		return nil
+	}
+	m.pinging = true
+	for _, h := range m.config.Hosts {
+		if _, ok := m.alive[h.Name]; !ok {
+			m.alive[h.Name] = liveness.Result{Host: h.Name}
+		}
+	}
+	return tea.Batch(
+		m.refreshItems(),
+		checkLiveness(m.config.GetPath(), m.config.Hosts),
+		// masters expire with ControlPersist
+		m.checkMasters(true),
+		AddLog("liveness check"),
+	)
+}
+
+// livenessMarker renders an up, down or unknown dot.
+func livenessMarker(res liveness.Result, c palette) string {
+	style := c.dim()
+	switch res.Status {
+	case liveness.Up:
+		style = c.good()
+	case liveness.Down:
+		style = c.bad()
+	}
+	return style.Render("●")
+}
+
+// livenessLatency renders the latency of reachable hosts.
+func livenessLatency(res liveness.Result, c palette) string {
+	if res.Status != liveness.Up {
+		return ""
+	}
+	latency := res.Latency.Round(time.Millisecond)
+	return c.dim().Render(fmt.Sprintf(" %v", latency))
+}
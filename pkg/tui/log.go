@@ -2,7 +2,9 @@ package tui
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -14,6 +16,8 @@ type Log struct {
 	debugActive  bool
 	debugHistory int
 	debugCount   int
+	// out receives every log and error line when set.
+	out io.Writer
 
 	ErrStyle   lipgloss.Style
 	DebugStyle lipgloss.Style
@@ -35,6 +39,13 @@ func WithDebug(debug bool) LogOption {
 	}
 }
 
+// WithLogWriter appends every log and error line to w.
+func WithLogWriter(w io.Writer) LogOption {
+	return func(l *Log) {
+		l.out = w
+	}
+}
+
 func WithDebugHistory(length int) LogOption {
 	return func(l *Log) {
 		l.debugHistory = length
@@ -107,12 +118,23 @@ func (l Log) Update(msg tea.Msg) (Log, tea.Cmd) {
 		if len(l.debugLogs) > l.debugHistory {
 			l.debugLogs = l.debugLogs[len(l.debugLogs)-l.debugHistory:]
 		}
+		l.write("debug", msg.Log)
 	case ErrorMsg:
 		l.err = msg.Err
+		if msg.Err != nil {
+			l.write("error", msg.Err.Error())
+		}
 	}
 	return l, nil
 }
 
+func (l Log) write(level, text string) {
+	if l.out == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	fmt.Fprintf(l.out, "%s %s %s\n", time.Now().Format(time.RFC3339), level, text)
+}
+
 func (l Log) View() string {
 	errMsg := func() string {
 		if l.err != nil {
@@ -7,24 +7,52 @@ package tui
 import (
 	"bytes"
 	"fmt"
-	"image/color"
-	"os"
+	"io"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/textinput"
 	"github.com/charmbracelet/bubbles/v2/viewport"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/facts"
+	"github.com/lfaoro/ssm/pkg/health"
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/jumpgraph"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/launch"
+	"github.com/lfaoro/ssm/pkg/liveness"
+	"github.com/lfaoro/ssm/pkg/mux"
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/lfaoro/ssm/pkg/secret"
+	"github.com/lfaoro/ssm/pkg/snapshot"
+	"github.com/lfaoro/ssm/pkg/snippet"
 	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/state"
 )
 
 type Model struct {
 	config     *sshconf.Config
 	showConfig bool
-	theme      theme
+	// viewDefaults adds the ssh defaults to the side view,
+	// cached by host until the config is reloaded. Failures are
+	// cached too, retried when the defaults are shown again.
+	viewDefaults bool
+	defaults     map[string][]sshconf.Setting
+	defaultsErr  map[string]error
+	// viewSearch filters the side view while viewSearching,
+	// the query is kept once the search is done.
+	viewSearch    textinput.Model
+	viewSearching bool
+	// themeName is the theme in use, theme its variant
+	// for the terminal background.
+	themeName string
+	theme     palette
 
 	li list.Model
 	vp viewport.Model
@@ -32,21 +60,174 @@ type Model struct {
 	Cmd       SysCmd
 	ExitOnCmd bool
 	ExitHost  string
+	// ExitArgv connects to ExitHost once the program exits,
+	// with ExitEnv as environment when set.
+	ExitArgv []string
+	ExitEnv  []string
+
+	connectors []connector.Connector
+	// cmdChosen is set once Cmd is picked in this session,
+	// it overrides the connector pinned by hosts.
+	cmdChosen bool
+
+	launcher launch.Launcher
+	// selected hosts open together in a tiled tmux window.
+	selected map[string]bool
+
+	secrets    *secret.Resolver
+	secretFile *secret.File
+
+	// hooks run before and after connections.
+	hooks hook.Config
+	// health configures the probes of the health dashboard.
+	health health.Config
+
+	// facts caches the facts of hosts, collecting is set
+	// while they're collected.
+	facts      *facts.Cache
+	collecting bool
+
+	// jumps is the ProxyJump graph of config.
+	jumps *jumpgraph.Graph
+
+	// recordDir holds the sessions recorded as picked by recordPolicy.
+	recordDir    string
+	recordPolicy recording.Policy
+
+	// snapshots keeps the config before edits, nil reloads
+	// edits without reviewing them.
+	snapshots *snapshot.Store
 
 	debug bool
 	log   Log
 
 	errbuf bytes.Buffer
 	isDark bool
+
+	state   *state.State
+	sort    state.SortMode
+	history *history.Log
+
+	alive        map[string]liveness.Result
+	pinging      bool
+	pingGen      int
+	pingInterval time.Duration
+
+	// masters holds the ControlMaster status of hosts.
+	masters map[string]mux.Status
+
+	// agentSkip holds the hosts connected to without
+	// loading their keys in ssh-agent.
+	agentSkip map[string]bool
+
+	// editor opens the ssh config, $EDITOR when empty.
+	editor string
+
+	// keys binds action IDs to keys, see DefaultKeymap.
+	keys keymap.Keymap
+
+	snippets []snippet.Snippet
+	// runHistory holds the commands entered in the run screen.
+	runHistory []string
+}
+
+type ModelOption func(*Model)
+
+// WithState persists pins, connections and the sort to st,
+// the list starts sorted as it was left.
+func WithState(st *state.State) ModelOption {
+	return func(m *Model) {
+		m.state = st
+		if mode, ok := st.LastSort(); ok {
+			m.sort = mode
+		}
+	}
+}
+
+// WithHistory records every connection session to l.
+func WithHistory(l *history.Log) ModelOption {
+	return func(m *Model) {
+		m.history = l
+	}
+}
+
+// WithPingInterval sets how often liveness is refreshed.
+func WithPingInterval(d time.Duration) ModelOption {
+	return func(m *Model) {
+		if d > 0 {
+			m.pingInterval = d
+		}
+	}
+}
+
+// WithSnippets offers snippets in the run screen.
+func WithSnippets(list []snippet.Snippet) ModelOption {
+	return func(m *Model) {
+		m.snippets = list
+	}
+}
+
+// WithKeymap replaces the default key bindings with km.
+func WithKeymap(km keymap.Keymap) ModelOption {
+	return func(m *Model) {
+		m.keys = km
+	}
+}
+
+// WithEditor edits the ssh config with editor instead of $EDITOR.
+func WithEditor(editor string) ModelOption {
+	return func(m *Model) {
+		m.editor = editor
+	}
+}
+
+// WithConnector connects with c by default instead of ssh.
+func WithConnector(c SysCmd) ModelOption {
+	return func(m *Model) {
+		if c != "" {
+			m.Cmd = c
+		}
+	}
+}
+
+// WithConnectors replaces the built-in connectors with list.
+func WithConnectors(list []connector.Connector) ModelOption {
+	return func(m *Model) {
+		m.connectors = list
+	}
 }
 
-func NewModel(config *sshconf.Config, debug bool) *Model {
+// WithDebugLog appends every log and error line to w.
+func WithDebugLog(w io.Writer) ModelOption {
+	return func(m *Model) {
+		WithLogWriter(w)(&m.log)
+	}
+}
+
+func NewModel(config *sshconf.Config, debug bool, opts ...ModelOption) *Model {
 	m := &Model{}
 	m.debug = debug
 	m.config = config
-	m.li = listFrom(m.config, m.theme)
+	m.state = state.New("") // default: in memory
+	m.history = history.OpenPath("")
+	m.alive = map[string]liveness.Result{}
+	m.masters = map[string]mux.Status{}
+	m.agentSkip = map[string]bool{}
+	m.selected = map[string]bool{}
+	m.viewSearch = newViewSearch()
+	m.launcher = launch.Launcher{Target: launch.Replace}
+	m.pingInterval = defaultPingInterval
 	m.log = NewLog(WithDebug(debug))
 	m.Cmd = sshCmd // defaults to ssh
+	m.connectors = connector.Builtin()
+	m.secrets = secret.NewResolver(nil)
+	m.keys = DefaultKeymap()
+	m.themeName = defaultTheme
+	m.isDark = true
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.applyTheme()
 	m.vp = viewport.New()
 	m.vp.SetWidth(40)
 	m.vp.SetHeight(20)
@@ -60,15 +241,15 @@ func (m *Model) Init() tea.Cmd {
 		tea.EnterAltScreen,
 		tea.EnableBracketedPaste,
 		tea.EnableReportFocus,
-		tea.SetBackgroundColor(color.Black),
+		tea.RequestBackgroundColor,
 		// tea.EnableMouseAllMotion,
 		// tea.EnableMouseCellMotion,
 	}
 	if m.debug {
 		cmds = append(cmds, AddLog("debug: isdarkbg %v", m.isDark))
 	}
-	m.li.NewStatusMessage(fmt.Sprintf("[%s]", m.Cmd))
-	cmds = append(cmds, tick())
+	m.li.NewStatusMessage(m.status())
+	cmds = append(cmds, tick(), m.checkMasters(true))
 	return tea.Batch(cmds...)
 }
 
@@ -79,6 +260,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.BackgroundColorMsg:
 		m.isDark = msg.IsDark()
+		m.applyTheme()
 	case tea.WindowSizeMsg:
 		var errSize = 1
 		if m.log.err != nil {
@@ -103,17 +285,38 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case AppMsg:
 		return m, AddError(fmt.Errorf("%s", msg.Text))
 	case LivenessCheckMsg:
-		// TODO: not implemented
-		return m, AddLog("liveness check")
-		for _, h := range m.config.Hosts {
-			host, _ := h.Options.Get("hostname")
-			// resolve host
-			// ping server
-			_ = host
-			h.Options.Add("alive", "yes")
+		m.pingGen++
+		return m, m.startLivenessCheck()
+	case livenessTickMsg:
+		if msg.gen != m.pingGen {
+			return m, nil
 		}
-		m.li = listFrom(m.config, m.theme)
-		return m, nil
+		return m, m.startLivenessCheck()
+	case CollectFactsMsg:
+		return m, m.collectStaleFacts()
+	case factsMsg:
+		m.collecting = false
+		// the filter matches the facts
+		m.reloadList()
+		if m.showConfig {
+			m.setConfig()
+		}
+		cmd := AddLog("collected facts of %d hosts", len(msg.collected))
+		if msg.err != nil {
+			return m, tea.Batch(cmd, AddError(msg.err))
+		}
+		return m, cmd
+	case mastersMsg:
+		return m, m.updateMasters(msg)
+	case livenessResultMsg:
+		m.pinging = false
+		for _, res := range msg.results {
+			m.alive[res.Host] = res
+		}
+		return m, tea.Batch(
+			m.refreshItems(),
+			nextLivenessCheck(m.pingInterval, m.pingGen),
+		)
 	case ExitOnConnMsg:
 		m.ExitOnCmd = true
 		return m, AddLog("exit true")
@@ -121,125 +324,106 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.li.SetFilterText(msg.Arg)
 		m.li.SetFilteringEnabled(true)
 		return m, AddLog("filter true")
+	case StartFilterMsg:
+		if a, ok := findAction("filter"); ok {
+			return a.Run(m)
+		}
+		return m, nil
+	case editDoneMsg:
+		return m.editDone(msg)
 	case ReloadConfigMsg:
 		err := m.config.ParsePath(m.config.GetPath())
 		if err != nil {
 			return m, AddError(err)
 		}
-		m.li = listFrom(m.config, m.theme)
-		m.li.NewStatusMessage(fmt.Sprintf("[%s]", m.Cmd))
+		m.defaults, m.defaultsErr = nil, nil
+		m.reloadList()
 		return m, AddLog("reloading config")
 	case ShowConfigMsg:
 		m.showConfig = true
 		return m, nil
 	case SetThemeMsg:
-		m.theme = themes[msg.Theme]
-		m.li = listFrom(m.config, m.theme)
+		if _, ok := themes[msg.Theme]; !ok {
+			return m, AddError(fmt.Errorf("unknown theme %q, available: %s",
+				msg.Theme, strings.Join(ThemeNames(), ", ")))
+		}
+		m.themeName = msg.Theme
+		m.applyTheme()
+		return m, nil
+	case secretPromptMsg:
+		return SecretPromptModel(m, msg.host), textinput.Blink
+	case preHookMsg:
+		if msg.err != nil {
+			return m, AddError(hookError(msg.err))
+		}
+		logCmd := hookLog("pre-connect", msg.dials[0].host.host.Name, msg.out)
+		if msg.tile {
+			return m, tea.Batch(logCmd, m.tile(msg.dials))
+		}
+		return m, tea.Batch(logCmd, m.open(msg.dials[0]))
+	case ConnectHostMsg:
+		for _, it := range m.li.Items() {
+			host := it.(item)
+			if host.host.Name != msg.Name {
+				continue
+			}
+			if msg.Connector != "" {
+				m.Cmd = SysCmd(msg.Connector)
+				m.cmdChosen = true
+				m.li.NewStatusMessage(m.status())
+			}
+			if am := m.agentCheck(host); am != nil {
+				return am, nil
+			}
+			return m, m.connectTo(host)
+		}
+		return m, AddError(fmt.Errorf("host %s not found in %s", msg.Name, m.config.GetPath()))
+	case SetSortMsg:
+		m.sort = msg.Mode
+		m.reloadList()
 		return m, nil
 
 	case tea.KeyPressMsg:
+		if m.viewSearching {
+			return m, m.updateViewSearch(msg)
+		}
+		filtering := m.li.FilterState() == list.Filtering
 		switch msg.Code {
-		case tea.KeyTab:
-			if m.Cmd == sshCmd {
-				m.Cmd = moshCmd
-				m.li.NewStatusMessage(fmt.Sprintf("[%s]", m.Cmd))
-			} else {
-				m.Cmd = sshCmd
-				m.li.NewStatusMessage(fmt.Sprintf("[%s]", m.Cmd))
-			}
 		case tea.KeyEnter:
-			if m.li.FilterState() == list.Filtering {
-				if m.li.FilterValue() == "" {
-					m.li.ResetFilter()
-				}
-				break
+			if filtering && m.li.FilterValue() == "" {
+				m.li.ResetFilter()
 			}
-			conncmd := m.connect()
-			return m, tea.Batch(
-				conncmd,
-				AddError(fmt.Errorf("%s", m.errbuf.String())),
-			)
 		case tea.KeyBackspace:
 			if m.li.FilteringEnabled() {
 				m.li.ResetFilter()
 				return m, nil
 			}
-		case 'q':
-			if m.li.FilterState() != 1 {
-				return m, tea.Quit
+		}
+		if msg.String() == "ctrl+c" {
+			if filtering || m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
 			}
+			return m, tea.Quit
 		}
-		switch msg.Mod {
-		// we're only interested in ctrl+<key>
-		case tea.ModCtrl:
-			switch msg.Code {
-			case 'c':
-				if m.li.FilterState() == list.Filtering ||
-					m.li.IsFiltered() {
-					m.li.ResetFilter()
-					return m, nil
-				}
-				return m, tea.Quit
-
-			// emacs keybinds
-			case 'p':
-				m.li.CursorUp()
-			case 'n':
-				m.li.CursorDown()
-			case 'b':
-				m.li.PrevPage()
-			case 'f':
-				m.li.NextPage()
-
-			case 'e':
-				confFile := m.config.GetPath()
-				editorPath := os.Getenv("EDITOR")
-				knownEditors := [...]string{
-					editorPath,
-					"vim",
-					"vi",
-					"nano",
-					"ed",
+		// while typing a filter only ctrl+<key> runs actions,
+		// esc clears an applied filter before anything else
+		ctrl := msg.Mod&tea.ModCtrl != 0
+		typing := filtering && !ctrl
+		clearing := m.li.IsFiltered() && key.Matches(msg, m.li.KeyMap.ClearFilter)
+		if !typing && !clearing {
+			if next, cmd, ok := m.runKey(msg.String()); ok {
+				if m.showConfig {
+					// the cursor may have moved
+					m.setConfig()
 				}
-				for _, cmd := range knownEditors {
-					path, err := exec.LookPath(cmd)
-					if err != nil {
-						continue
-					}
-					editorPath = path
-					break
-				}
-				if editorPath == "" {
-					return m, AddError(fmt.Errorf("env EDITOR not set, nor any %v found in PATH", knownEditors[1:]))
-				}
-				cmd := exec.Command(editorPath, confFile)
-				cmd.Dir = filepath.Dir(confFile)
-				cmd.Stderr = &m.errbuf
-				execCmd := tea.ExecProcess(cmd, func(err error) tea.Msg {
-					logCmd := AddLog("%v", err)
-					var errCmd tea.Cmd
-					if err != nil {
-						errCmd = AddError(err)
-					}
-					return tea.Batch(logCmd, errCmd)
-				})
-				return m, tea.Sequence(
-					execCmd,
-					func() tea.Msg {
-						return ReloadConfigMsg{}
-					},
-				)
-			case 'r':
-				return RunCmdModel(m), nil
-			case 's':
-				return m, AddError(fmt.Errorf("sftp: not yet implemented"))
-			case 'v':
-				m.showConfig = !m.showConfig
-				m.setConfig()
-			default:
-				return m, AddError(fmt.Errorf("that's an interesting key combo! %s", msg))
+				return next, cmd
 			}
-		default:
+			if ctrl {
+				return m, AddError(fmt.Errorf("%s is not bound: %s lists every command", msg, m.boundKey("palette")))
+			}
+		}
+		if msg.Mod == 0 {
 			cmds = append(cmds, ClearError())
 		}
 	}
@@ -270,62 +454,187 @@ func (m *Model) connect() tea.Cmd {
 	if !ok {
 		return AddError(fmt.Errorf("unable to find selected item: open bug report"))
 	}
+	return m.connectTo(host)
+}
+
+func (m *Model) connectTo(host item) tea.Cmd {
+	conn, err := m.connectorFor(host.host)
+	if err != nil {
+		return AddError(err)
+	}
+	argv, err := conn.Argv(connector.DataOf(host.host, m.config.GetPath()))
+	if err != nil {
+		return AddError(err)
+	}
+
+	env, secretCmd := m.secretEnv(host)
+	if secretCmd != nil {
+		return secretCmd
+	}
+	if env != nil && m.launcher.Target != launch.Replace {
+		return AddError(errSecretOutside(host.host.Name))
+	}
+
+	d := dial{
+		host:      host,
+		connector: conn.Name,
+		argv:      argv,
+		env:       env,
+		hookEnv:   hook.Env(host.host, conn.Name),
+	}
+	hooks := m.hooks.For(host.host)
+	d.post = hooks.Post
+	if len(hooks.Pre) > 0 {
+		return tea.Batch(
+			AddLog("running %d pre-connect hooks of %s", len(hooks.Pre), host.host.Name),
+			m.runPreHooks([]dial{d}, [][]string{hooks.Pre}, false),
+		)
+	}
+	return m.open(d)
+}
+
+// open connects to the host of d once its pre-connect hooks ran.
+func (m *Model) open(d dial) tea.Cmd {
+	host, argv, env := d.host, d.argv, d.env
+	m.state.Record(host.host.Name, time.Now())
+	saveCmd := saveState(m.state)
+	rec := m.recordPath(host.host)
+	if m.outside() {
+		// the session runs outside of ssm
+		var err error
+		if rec != "" {
+			if argv, err = recordArgv(rec, host.host.Name, argv); err != nil {
+				return AddError(err)
+			}
+		}
+		d.argv = argv
+		if argv, err = m.hookArgv(d); err != nil {
+			return AddError(err)
+		}
+	}
+	if m.launcher.Target != launch.Replace {
+		launchCmd := m.launchOut(host.host.Name, argv)
+		if m.ExitOnCmd {
+			return tea.Sequence(saveCmd, launchCmd, tea.Quit)
+		}
+		return tea.Batch(saveCmd, launchCmd)
+	}
 	if m.ExitOnCmd {
-		m.ExitHost = strings.TrimSpace(host.title)
-		return tea.Quit
+		m.ExitHost = strings.TrimSpace(host.host.Name)
+		m.ExitArgv = argv
+		m.ExitEnv = env
+		return tea.Sequence(saveCmd, tea.Quit)
 	}
 
-	cmdPath, err := exec.LookPath(m.Cmd.String())
+	cmdPath, err := exec.LookPath(argv[0])
 	if err != nil {
-		return AddError(fmt.Errorf("can't find `%s` cmd in your path: %v", m.Cmd, err))
+		return AddError(fmt.Errorf("can't find `%s` cmd in your path: %v", argv[0], err))
 	}
 
-	var cmd *exec.Cmd
-	cmd = exec.Command(cmdPath, host.title, "-F", m.config.GetPath())
-	if m.Cmd == moshCmd {
-		sshFlag := fmt.Sprintf("--ssh='ssh -F %s'", m.config.GetPath())
-		cmd = exec.Command(
-			cmdPath,
-			host.title,
-			sshFlag,
-		)
+	cmd := exec.Command(cmdPath, argv[1:]...)
+	// nil inherits the ssm environment
+	cmd.Env = env
+	tail := history.NewTail(history.TailLines)
+	if rec == "" {
+		// a pty fills nil streams only: recorded sessions keep it
+		cmd.Stderr = io.MultiWriter(&m.errbuf, tail)
 	}
-	if host.title == "create free research root server" {
-		host.desc = strings.TrimSpace(host.desc)
-		_cmdPath, err := exec.LookPath("sshpass")
+	entry := history.Entry{
+		Host:      host.host.Name,
+		Connector: d.connector,
+		Start:     time.Now(),
+	}
+	done := func(err error) tea.Msg {
+		entry.Duration = time.Since(entry.Start).Round(time.Second)
+		entry.ExitCode = history.ExitCode(err)
+		entry.Stderr = tail.Lines()
 		if err != nil {
-			_cmdPath, err = exec.LookPath("ssh")
-			if err != nil {
-				return AddError(fmt.Errorf("can't find `%s` cmd in your path: %v", m.Cmd, err))
+			entry.Error = err.Error()
+		}
+		var histCmd tea.Cmd
+		if err := m.history.Append(entry); err != nil {
+			histCmd = AddLog("history: %v", err)
+		}
+		postCmd := m.runPostHooks(d, entry.ExitCode, entry.Duration)
+		// a BatchMsg runs its commands, a Cmd sent as Msg is dropped
+		if strings.Contains(m.errbuf.String(), hostKeyChanged) {
+			m.errbuf.Reset()
+			return tea.BatchMsg{
+				AddError(fmt.Errorf("host key of %s changed: press %s to review and repair", host.host.Name, m.boundKey("known-hosts"))),
+				histCmd,
+				postCmd,
 			}
-			cmd = exec.Command(_cmdPath, host.title, "-F", m.config.GetPath())
-		} else {
-			cmd = exec.Command(_cmdPath, "-p", "segfault", "ssh", host.desc)
 		}
-	}
-	cmd.Stderr = &m.errbuf
-	execmd := tea.ExecProcess(cmd, func(err error) tea.Msg {
-		return tea.Batch(
+		return tea.BatchMsg{
 			AddError(
-				fmt.Errorf("connection closed: %v, err: %v", host.title, err),
+				fmt.Errorf("connection closed: %v, err: %v", host.host.Name, err),
 			),
 			AddError(fmt.Errorf("%s", m.errbuf.String())),
-		)
-	})
-	return execmd
+			histCmd,
+			postCmd,
+		}
+	}
+	if rec != "" {
+		// stderr is part of the recorded terminal output,
+		// the history keeps its last lines
+		session := &recording.Session{Cmd: cmd, Path: rec, Title: host.host.Name, Output: tail}
+		return tea.Batch(saveCmd, AddLog("recording %s to %s", host.host.Name, rec), tea.Exec(session, done))
+	}
+	return tea.Batch(saveCmd, tea.ExecProcess(cmd, done))
+}
+
+// connectorFor returns the connector chosen in this session,
+// or the one pinned by host, or the default one.
+func (m *Model) connectorFor(host sshconf.Host) (connector.Connector, error) {
+	if !m.cmdChosen {
+		return connector.For(m.connectors, host, m.Cmd.String())
+	}
+	c, ok := connector.Find(m.connectors, m.Cmd.String())
+	if !ok {
+		return c, fmt.Errorf("unknown connector %q, available: %s",
+			m.Cmd, strings.Join(connector.Names(m.connectors), ", "))
+	}
+	return c, nil
+}
+
+// saveState persists st reporting failures in the log area.
+func saveState(st *state.State) tea.Cmd {
+	return func() tea.Msg {
+		if err := st.Save(); err != nil {
+			return ErrorMsg{Err: fmt.Errorf("state: %w", err)}
+		}
+		return nil
+	}
 }
 
 func (m *Model) setConfig() {
-	i := m.li.GlobalIndex()
-	host := m.config.Hosts[i]
-	var out string
-	keyStyle := lg.NewStyle().
-		Foreground(lg.Color("#4682b4"))
-	for i, k := range host.Options.Keys() {
-		k = keyStyle.Render(k)
-		out += fmt.Sprintf("%s %s\n", k, host.Options.Values()[i])
+	selected, ok := m.li.SelectedItem().(item)
+	if !ok {
+		m.vp.SetContent("")
+		return
+	}
+	host := selected.host
+	m.vp.SetContent(m.hostFacts(host.Name) + m.jumpChain(host.Name) + m.effectiveConfig(host))
+}
+
+// jumpChain renders the route to host for the side view,
+// flagging cycles and jump hosts missing from the config.
+func (m *Model) jumpChain(host string) string {
+	if m.jumps == nil {
+		return ""
+	}
+	c := m.jumps.Chain(host)
+	if len(c.Hops) < 2 && !c.Cycle {
+		return ""
+	}
+	out := fmt.Sprintf("%s %s\n", m.theme.key().Render("jump"), c)
+	if c.Cycle {
+		out += m.theme.bad().Render("jump cycle: "+host+" can't be reached") + "\n"
+	}
+	if len(c.Missing) > 0 {
+		out += m.theme.warn().Render("not in config: "+strings.Join(c.Missing, ", ")) + "\n"
 	}
-	m.vp.SetContent(out)
+	return out + "\n"
 }
 
 func (m *Model) View() string {
@@ -0,0 +1,213 @@
+package tui_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/hook"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"github.com/lfaoro/ssm/pkg/state"
+	"github.com/lfaoro/ssm/pkg/tui"
+)
+
+// fakeSSH puts an ssh running script in PATH.
+func fakeSSH(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("SSH_AUTH_SOCK", "")
+}
+
+// failingSSH puts an ssh in PATH printing stderr and exiting with 3,
+// `ssh -G` prints nothing.
+func failingSSH(t *testing.T, stderr string) {
+	t.Helper()
+	fakeSSH(t, "case \"$1\" in -G) exit 0;; esac\nprintf '%s\\n' \"$FAKE_STDERR\" >&2\nexit 3\n")
+	t.Setenv("FAKE_STDERR", stderr)
+}
+
+// syncBuffer is written by the program while the test reads it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// connect runs a program connecting to web in place until done
+// reports true, it returns the log and error lines.
+func connect(t *testing.T, done func(log string) bool, opts ...tui.ModelOption) string {
+	t.Helper()
+	config := sshconftest.Parse(t, "Host web\n  HostName 127.0.0.1\n")
+	log := &syncBuffer{}
+	m := tui.NewModel(config, false, append(opts, tui.WithDebugLog(log))...)
+	// no keys, a file: reads are cancelled while ssh runs
+	input, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	p := tea.NewProgram(m, tea.WithInput(input), tea.WithOutput(io.Discard), tea.WithoutSignals())
+	result := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		result <- err
+	}()
+	p.Send(tui.ConnectHostMsg{Name: "web"})
+	deadline := time.Now().Add(5 * time.Second)
+	for !done(log.String()) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	// let the batched commands land
+	time.Sleep(200 * time.Millisecond)
+	p.Quit()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if !done(log.String()) {
+		t.Fatalf("session not done, log:\n%s", log.String())
+	}
+	return log.String()
+}
+
+func TestPostConnectHook(t *testing.T) {
+	failingSSH(t, "")
+	out := filepath.Join(t.TempDir(), "post")
+	// a quiet hook: no output to log
+	hooks := hook.Config{Hooks: hook.Hooks{Post: []string{"echo $SSM_EXIT_CODE > " + out}}}
+	log := connect(t, func(string) bool {
+		b, _ := os.ReadFile(out)
+		return len(b) > 0
+	}, tui.WithHooks(hooks))
+
+	b, _ := os.ReadFile(out)
+	if strings.TrimSpace(string(b)) != "3" {
+		t.Errorf("post hook exit code: %q", b)
+	}
+	if !strings.Contains(log, "connection closed: web") {
+		t.Errorf("log:\n%s", log)
+	}
+}
+
+func TestHostKeyChanged(t *testing.T) {
+	failingSSH(t, "@@@ WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED! @@@")
+	log := connect(t, func(log string) bool {
+		return strings.Contains(log, "host key of web changed")
+	})
+	if !strings.Contains(log, "press shift+k") {
+		t.Errorf("log:\n%s", log)
+	}
+}
+
+func TestExitRecordsHistory(t *testing.T) {
+	config := sshconftest.Parse(t, "Host web\n  HostName 127.0.0.1\n")
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	m := tui.NewModel(config, false, tui.WithHistory(history.OpenPath(path)))
+	m.Update(tui.ExitOnConnMsg{})
+	m.Update(tui.ConnectHostMsg{Name: "web"})
+
+	// ssm hook --spec {...} -- ssh ...
+	argv := m.ExitArgv
+	if len(argv) < 5 || argv[1] != "hook" || argv[4] != "--" {
+		t.Fatalf("argv: %q", argv)
+	}
+	var spec hook.Deferred
+	if err := json.Unmarshal([]byte(argv[3]), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.History != path || spec.Host != "web" || spec.Connector != "ssh" {
+		t.Errorf("spec: %+v", spec)
+	}
+}
+
+func TestSortPersists(t *testing.T) {
+	config := sshconftest.Parse(t, "Host zeta\n  HostName 127.0.0.1\nHost alpha\n  HostName 127.0.0.2\n")
+	path := filepath.Join(t.TempDir(), "state.json")
+	m := tui.NewModel(config, false, tui.WithState(state.New(path)))
+	// config to alpha
+	_, cmd := m.Update(tea.KeyPressMsg{Code: 'o', Mod: tea.ModCtrl})
+	for _, cmd := range cmd().(tea.BatchMsg) {
+		cmd()
+	}
+
+	st, err := state.LoadPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, _ := tui.NewModel(config, false, tui.WithState(st)).Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	v := view.(tea.ViewModel).View()
+	if a, z := strings.Index(v, "alpha"), strings.Index(v, "zeta"); a < 0 || z < a {
+		t.Errorf("not sorted by name:\n%s", v)
+	}
+}
+
+func TestUnboundChord(t *testing.T) {
+	keys, err := tui.DefaultKeymap().Merge(keymap.Keymap{"palette": {"ctrl+p"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := sshconftest.Parse(t, "Host web\n")
+	_, cmd := tui.NewModel(config, false, tui.WithKeymap(keys)).Update(tea.KeyPressMsg{Code: 'x', Mod: tea.ModCtrl})
+	var errs []string
+	var collect func(tea.Cmd)
+	collect = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				collect(cmd)
+			}
+		case tui.ErrorMsg:
+			errs = append(errs, msg.Err.Error())
+		}
+	}
+	collect(cmd)
+	want := "ctrl+x is not bound: ctrl+p lists every command"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
+
+func TestDefaultsFailureCached(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeSSH(t, "echo -G >> "+calls+"\nexit 255\n")
+	config := sshconftest.Parse(t, "Host web\n")
+	m, _ := tui.NewModel(config, false).Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModShift})
+	for range 3 {
+		m, _ = m.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	}
+	b, _ := os.ReadFile(calls)
+	if n := strings.Count(string(b), "-G"); n != 1 {
+		t.Errorf("ssh -G ran %d times", n)
+	}
+}
@@ -1,13 +1,19 @@
 package tui
 
+import "github.com/lfaoro/ssm/pkg/state"
+
 type (
 	ShowConfigMsg    struct{}
 	ReloadConfigMsg  struct{}
 	LivenessCheckMsg struct{}
+	CollectFactsMsg  struct{}
 	ExitOnConnMsg    struct{}
 	SetThemeMsg      struct {
 		Theme string
 	}
+	SetSortMsg struct {
+		Mode state.SortMode
+	}
 	tickMsg struct{}
 	AppMsg  struct {
 		Text string
@@ -15,4 +21,10 @@ type (
 	FilterTagMsg struct {
 		Arg string
 	}
+	// StartFilterMsg focuses the filter input.
+	StartFilterMsg struct{}
+	ConnectHostMsg struct {
+		Name      string
+		Connector string
+	}
 )
@@ -0,0 +1,364 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/mux"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "masters", Title: "ControlMaster connections: start, stop, stop all", Keys: []string{"shift+x"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			return MastersModel(m), m.checkMasters(false)
+		},
+	})
+	RegisterAction(Action{
+		ID: "warm-up", Title: "warm up the masters of selected or listed hosts", Keys: []string{"shift+w"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			var hosts []string
+			for _, it := range m.li.VisibleItems() {
+				if h := it.(item).host; m.selected[h.Name] {
+					hosts = append(hosts, h.Name)
+				}
+			}
+			if len(hosts) == 0 {
+				for _, it := range m.li.VisibleItems() {
+					hosts = append(hosts, it.(item).host.Name)
+				}
+			}
+			return m, m.startMasters(hosts)
+		},
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "masters.start", Title: "start", Keys: []string{"s", "enter"}},
+		ScreenKey{ID: "masters.stop", Title: "stop", Keys: []string{"x"}},
+		ScreenKey{ID: "masters.stop-all", Title: "stop all", Keys: []string{"shift+x"}},
+		ScreenKey{ID: "masters.refresh", Title: "refresh", Keys: []string{"r", "ctrl+t"}},
+		ScreenKey{ID: "masters.up", Title: "move up", Keys: []string{"up", "k"}},
+		ScreenKey{ID: "masters.down", Title: "move down", Keys: []string{"down", "j"}},
+		ScreenKey{ID: "masters.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+// mastersMsg carries the statuses of masters checked, started
+// or stopped, log describes what was done.
+type mastersMsg struct {
+	statuses []mux.Status
+	log      string
+	err      error
+}
+
+// updateMasters stores the statuses of msg and marks the live masters.
+func (m *Model) updateMasters(msg mastersMsg) tea.Cmd {
+	for _, s := range msg.statuses {
+		m.masters[s.Host] = s
+	}
+	cmds := []tea.Cmd{m.refreshItems()}
+	if msg.log != "" {
+		cmds = append(cmds, AddLog("%s", msg.log))
+	}
+	if msg.err != nil {
+		cmds = append(cmds, AddError(msg.err))
+	}
+	return tea.Batch(cmds...)
+}
+
+// checkMasters checks the masters of hosts off the UI loop, every
+// host or, in the background, the ones with a ControlPath in effect:
+// `ssh -G` runs for each and evaluates `Match exec`.
+func (m *Model) checkMasters(background bool) tea.Cmd {
+	hosts := make([]string, 0, len(m.config.Hosts))
+	for _, h := range m.config.Hosts {
+		if background && h.ControlPath() == "" {
+			continue
+		}
+		hosts = append(hosts, h.Name)
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	config := m.config.GetPath()
+	return func() tea.Msg {
+		return mastersMsg{statuses: mux.CheckAll(context.Background(), config, hosts)}
+	}
+}
+
+// startMasters opens the masters of hosts in the background,
+// hosts without a ControlPath are skipped.
+func (m *Model) startMasters(hosts []string) tea.Cmd {
+	if len(hosts) == 0 {
+		return nil
+	}
+	config := m.config.GetPath()
+	return tea.Batch(
+		AddLog("warming up the masters of %d hosts", len(hosts)),
+		func() tea.Msg {
+			statuses, err := mux.StartAll(context.Background(), config, hosts, mux.DefaultTimeout)
+			var alive, skipped int
+			for _, s := range statuses {
+				switch {
+				case s.Alive:
+					alive++
+				case !s.Multiplexed() && s.Err == nil:
+					skipped++
+				}
+			}
+			log := fmt.Sprintf("%d masters alive", alive)
+			if skipped > 0 {
+				log += fmt.Sprintf(", %d hosts without ControlPath skipped", skipped)
+			}
+			return mastersMsg{statuses: statuses, log: log, err: err}
+		},
+	)
+}
+
+// stopMasters closes the masters of hosts and checks them again.
+func (m *Model) stopMasters(hosts ...string) tea.Cmd {
+	config := m.config.GetPath()
+	return func() tea.Msg {
+		ctx := context.Background()
+		err := mux.StopAll(ctx, config, hosts)
+		return mastersMsg{
+			statuses: mux.CheckAll(ctx, config, hosts),
+			log:      fmt.Sprintf("stopped the masters of %d hosts", len(hosts)),
+			err:      err,
+		}
+	}
+}
+
+// masterMarker suffixes the title of hosts with a live master.
+func (m *Model) masterMarker(host string) string {
+	if m.masters[host].Alive {
+		return " ⇄"
+	}
+	return ""
+}
+
+// MastersModel lists the multiplexed hosts and their masters.
+func MastersModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width())
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	m := &mastersModel{
+		previousModel: previousModel,
+		vp:            vp,
+		checking:      true,
+	}
+	m.render()
+	return m
+}
+
+type mastersModel struct {
+	previousModel *Model
+	vp            viewport.Model
+	// rows are the multiplexed hosts in config order.
+	rows    []string
+	cursor  int
+	current string
+	// checking is set until the statuses come back.
+	checking bool
+	// stopping waits for the confirmation of stopping all masters.
+	stopping bool
+}
+
+func (m *mastersModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *mastersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.vp.SetWidth(msg.Width)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case mastersMsg:
+		m.checking = false
+		cmd := m.previousModel.updateMasters(msg)
+		m.render()
+		return m, cmd
+	case tea.KeyPressMsg:
+		pm := m.previousModel
+		action := pm.screenKey("masters", msg)
+		if action != "stop-all" {
+			m.stopping = false
+		}
+		switch action {
+		case "back":
+			return pm, nil
+		case "up":
+			if len(m.rows) > 0 {
+				m.current = m.rows[max(0, m.cursor-1)]
+			}
+		case "down":
+			if len(m.rows) > 0 {
+				m.current = m.rows[min(len(m.rows)-1, m.cursor+1)]
+			}
+		case "refresh":
+			m.checking = true
+			m.render()
+			return m, pm.checkMasters(false)
+		case "start":
+			if m.current == "" {
+				return m, nil
+			}
+			m.checking = true
+			m.render()
+			return m, pm.startMasters([]string{m.current})
+		case "stop":
+			if !pm.masters[m.current].Alive {
+				return m, nil
+			}
+			m.checking = true
+			m.render()
+			return m, pm.stopMasters(m.current)
+		case "stop-all":
+			alive := m.alive()
+			if len(alive) == 0 {
+				return m, nil
+			}
+			if !m.stopping {
+				m.stopping = true
+				m.render()
+				return m, nil
+			}
+			m.stopping = false
+			m.checking = true
+			m.render()
+			return m, pm.stopMasters(alive...)
+		default:
+			var cmd tea.Cmd
+			m.vp, cmd = m.vp.Update(msg)
+			return m, cmd
+		}
+		m.render()
+		return m, nil
+	}
+	return m, nil
+}
+
+// alive returns the hosts with a live master.
+func (m *mastersModel) alive() []string {
+	var hosts []string
+	for _, h := range m.rows {
+		if m.previousModel.masters[h].Alive {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+func (m *mastersModel) render() {
+	pm := m.previousModel
+	theme := pm.theme
+	m.rows, m.cursor = m.rows[:0], 0
+	for _, h := range pm.config.Hosts {
+		if s := pm.masters[h.Name]; s.Multiplexed() || s.Err != nil {
+			if h.Name == m.current {
+				m.cursor = len(m.rows)
+			}
+			m.rows = append(m.rows, h.Name)
+		}
+	}
+	if len(m.rows) > 0 {
+		m.current = m.rows[m.cursor]
+	}
+
+	cols := []string{"host", "master", "pid", "persist", "control path"}
+	widths := make([]int, len(cols))
+	for c, name := range cols {
+		widths[c] = lg.Width(name)
+	}
+	cells := make([][]string, len(m.rows))
+	for i, h := range m.rows {
+		s := pm.masters[h]
+		state, pid := "off", "-"
+		switch {
+		case s.Err != nil:
+			state = "error"
+		case s.Alive:
+			state = "alive"
+			if s.PID > 0 {
+				pid = strconv.Itoa(s.PID)
+			}
+		}
+		persist := s.Persist
+		if persist == "" {
+			persist = "-"
+		}
+		cells[i] = []string{h, state, pid, persist, tildePath(s.Path)}
+		for c, text := range cells[i] {
+			widths[c] = max(widths[c], lg.Width(text))
+		}
+	}
+
+	var b strings.Builder
+	title := fmt.Sprintf("ControlMaster (%d multiplexed hosts, %d alive)", len(m.rows), len(m.alive()))
+	if m.checking {
+		title += " checking"
+	}
+	b.WriteString(pm.li.Styles.Title.Render(title) + "\n\n")
+	b.WriteString("  ")
+	for c, name := range cols {
+		b.WriteString(theme.key().Render(pad(name, widths[c], false)) + "  ")
+	}
+	b.WriteString("\n")
+	for i, row := range cells {
+		prefix := "  "
+		if i == m.cursor {
+			prefix = theme.key().Render("› ")
+		}
+		b.WriteString(prefix)
+		style := theme.dim()
+		switch row[1] {
+		case "alive":
+			style = theme.good()
+		case "error":
+			style = theme.bad()
+		}
+		for c, text := range row {
+			cell := pad(text, widths[c], false)
+			if c == 1 {
+				cell = style.Render(cell)
+			}
+			b.WriteString(cell + "  ")
+		}
+		b.WriteString("\n")
+	}
+	if len(m.rows) == 0 && !m.checking {
+		b.WriteString(theme.dim().Render("no host sets a ControlPath") + "\n")
+	}
+	if s, ok := pm.masters[m.current]; ok && s.Err != nil {
+		b.WriteString("\n" + theme.bad().Render(s.Err.Error()) + "\n")
+	}
+	if m.stopping {
+		b.WriteString("\n" + theme.warn().Render(
+			fmt.Sprintf("press %s again to stop %d masters", pm.boundKey("masters.stop-all"), len(m.alive()))) + "\n")
+	}
+	b.WriteString("\n" + theme.dim().Render(pm.screenHint("masters", "start", "stop", "stop-all", "refresh", "back")))
+	m.vp.SetContent(b.String())
+	// keep the cursor in view below the title and header
+	if line := m.cursor + 3; line < m.vp.YOffset {
+		m.vp.SetYOffset(line)
+	} else if line >= m.vp.YOffset+m.vp.Height()-2 {
+		m.vp.SetYOffset(line - m.vp.Height() + 3)
+	}
+}
+
+func (m *mastersModel) View() string {
+	return m.vp.View()
+}
@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+)
+
+type paletteItem struct {
+	action Action
+	host   string
+	// keys in effect for the action
+	keys []string
+}
+
+func (i paletteItem) Title() string {
+	if i.action.Host && i.host != "" {
+		return i.action.Title + " · " + i.host
+	}
+	return i.action.Title
+}
+
+func (i paletteItem) Description() string {
+	if len(i.keys) == 0 {
+		return "unbound"
+	}
+	return keyHelp(i.keys)
+}
+
+func (i paletteItem) FilterValue() string {
+	return i.action.Title
+}
+
+type paletteModel struct {
+	previousModel *Model
+	li            list.Model
+}
+
+// PaletteModel fuzzy finds and runs the registered actions,
+// host actions name the selected host.
+func PaletteModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	selected, hasHost := previousModel.li.SelectedItem().(item)
+	var items []list.Item
+	for _, a := range Actions() {
+		if a.Host && !hasHost || a.ID == "palette" {
+			continue
+		}
+		items = append(items, paletteItem{
+			action: a,
+			host:   selected.host.Name,
+			keys:   previousModel.keys[a.ID],
+		})
+	}
+
+	li := list.New(items, d, previousModel.li.Width(), previousModel.li.Height())
+	li.Title = "Commands"
+	li.Styles.Title = previousModel.li.Styles.Title
+	li.SetStatusBarItemName("command", "commands")
+	li.DisableQuitKeybindings()
+	// start typing right away
+	li, _ = li.Update(tea.KeyPressMsg{Code: '/', Text: "/"})
+	return &paletteModel{
+		previousModel: previousModel,
+		li:            li,
+	}
+}
+
+func (m *paletteModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *paletteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width, msg.Height)
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		switch msg.Code {
+		case tea.KeyEsc:
+			if m.li.FilterState() == list.Filtering && m.li.FilterValue() != "" {
+				break
+			}
+			return m.previousModel, nil
+		case tea.KeyEnter:
+			selected, ok := m.li.SelectedItem().(paletteItem)
+			if !ok {
+				return m, nil
+			}
+			return selected.action.Run(m.previousModel)
+		}
+		if msg.String() == "ctrl+c" {
+			return m.previousModel, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	return m, cmd
+}
+
+func (m *paletteModel) View() string {
+	return m.li.View()
+}
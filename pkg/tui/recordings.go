@@ -0,0 +1,288 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "recordings", Title: "session recordings", Keys: []string{"shift+r"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return RecordingsModel(m), nil },
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "recordings.replay", Title: "replay", Keys: []string{"enter"}},
+		ScreenKey{ID: "recordings.delete", Title: "delete", Keys: []string{"x"}},
+		ScreenKey{ID: "recordings.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+// replayIdle shortens the pauses of replays in the TUI.
+const replayIdle = 2 * time.Second
+
+// WithRecording records the sessions of the hosts picked
+// by policy to dir, an empty dir records nothing.
+func WithRecording(dir string, policy recording.Policy) ModelOption {
+	return func(m *Model) {
+		m.recordDir = dir
+		m.recordPolicy = policy
+	}
+}
+
+// recordPath returns the recording file of a session to host,
+// empty when it isn't recorded.
+func (m *Model) recordPath(host sshconf.Host) string {
+	if m.recordDir == "" || !m.recordPolicy.Records(host) {
+		return ""
+	}
+	return recording.NewPath(m.recordDir, host.Name, time.Now())
+}
+
+// recordArgv wraps argv with `ssm record` for the sessions
+// running outside of ssm: tmux, terminals and --exit.
+func recordArgv(path, host string, argv []string) ([]string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("recording %s: %w", host, err)
+	}
+	return append([]string{self, "record", "--output", path, "--title", host, "--"}, argv...), nil
+}
+
+type recordingItem struct {
+	info recording.Info
+}
+
+func (i recordingItem) Title() string {
+	return fmt.Sprintf("%s · %s", i.info.Host, i.info.Start.Format("2006-01-02 15:04"))
+}
+
+func (i recordingItem) Description() string {
+	return fmt.Sprintf("%s  %s", i.info.Duration.Round(time.Second), formatSize(i.info.Size))
+}
+
+func (i recordingItem) FilterValue() string {
+	return i.info.Host + " " + i.info.Start.Format("2006-01-02")
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// RecordingsModel browses the recorded sessions,
+// enter replays the selected one.
+func RecordingsModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	li := list.New([]list.Item{}, d, previousModel.li.Width()/2, previousModel.li.Height())
+	li.Title = fmt.Sprintf("Recordings (%s)", tildePath(previousModel.recordDir))
+	li.Styles.Title = previousModel.li.Styles.Title
+	li.SetStatusBarItemName("recording", "recordings")
+	li.DisableQuitKeybindings()
+	li.AdditionalFullHelpKeys = func() []key.Binding {
+		return previousModel.screenHelp("recordings", "replay", "delete", "back")
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width() / 2)
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	m := &recordingsModel{
+		previousModel: previousModel,
+		li:            li,
+		vp:            vp,
+	}
+	m.load()
+	return m
+}
+
+type recordingsModel struct {
+	previousModel *Model
+	li            list.Model
+	vp            viewport.Model
+	err           error
+	// deleting is the recording waiting for its delete confirmation.
+	deleting string
+}
+
+func (m *recordingsModel) load() {
+	m.err = nil
+	if m.previousModel.recordDir == "" {
+		m.err = fmt.Errorf("recordings directory not set")
+		m.setDetails()
+		return
+	}
+	infos, err := recording.List(m.previousModel.recordDir)
+	if err != nil {
+		m.err = err
+	}
+	items := make([]list.Item, 0, len(infos))
+	for _, info := range infos {
+		items = append(items, recordingItem{info: info})
+	}
+	m.li.SetItems(items)
+	m.setDetails()
+}
+
+func (m *recordingsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *recordingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width/2, msg.Height-1)
+		m.vp.SetWidth(msg.Width - msg.Width/2)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case replayDoneMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		m.setDetails()
+		return m, nil
+	case tea.KeyPressMsg:
+		if m.li.FilterState() == list.Filtering {
+			break
+		}
+		selected, ok := m.li.SelectedItem().(recordingItem)
+		action := m.previousModel.screenKey("recordings", msg)
+		if action != "delete" {
+			m.deleting = ""
+		}
+		switch action {
+		case "back":
+			if m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
+			}
+			return m.previousModel, nil
+		case "replay":
+			if !ok {
+				return m, nil
+			}
+			return m, tea.Exec(&replayCmd{path: selected.info.Path}, func(err error) tea.Msg {
+				return replayDoneMsg{err: err}
+			})
+		case "delete":
+			if !ok {
+				return m, nil
+			}
+			if m.deleting != selected.info.Path {
+				m.deleting = selected.info.Path
+				m.setDetails()
+				return m, nil
+			}
+			m.deleting = ""
+			if err := os.Remove(selected.info.Path); err != nil {
+				m.err = err
+			}
+			m.load()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	m.setDetails()
+	return m, cmd
+}
+
+type replayDoneMsg struct {
+	err error
+}
+
+// setDetails shows the selected recording in the viewport.
+func (m *recordingsModel) setDetails() {
+	if m.err != nil {
+		m.vp.SetContent(m.previousModel.log.ErrStyle.Render(m.err.Error()))
+		return
+	}
+	selected, ok := m.li.SelectedItem().(recordingItem)
+	if !ok {
+		m.vp.SetContent("(no sessions recorded)\n\n" + m.previousModel.theme.dim().Render(
+			"record hosts with a `#record: yes` comment or [record] in config.toml"))
+		return
+	}
+	info := selected.info
+	keyStyle := m.previousModel.theme.key()
+	var b strings.Builder
+	row := func(k, v string) {
+		fmt.Fprintf(&b, "%s %s\n", keyStyle.Render(fmt.Sprintf("%-10s", k)), v)
+	}
+	row("host", info.Host)
+	row("start", info.Start.Format("2006-01-02 15:04:05"))
+	row("duration", info.Duration.Round(time.Second).String())
+	row("size", formatSize(info.Size))
+	row("file", tildePath(info.Path))
+	if m.deleting == info.Path {
+		b.WriteString("\n" + m.previousModel.theme.warn().Render(
+			fmt.Sprintf("press %s again to delete %s",
+				m.previousModel.boundKey("recordings.delete"), filepath.Base(info.Path))))
+	}
+	m.vp.SetContent(b.String())
+}
+
+func (m *recordingsModel) View() string {
+	return lg.JoinHorizontal(lg.Top, m.li.View(), m.vp.View())
+}
+
+// replayCmd plays a recording on the terminal released by the TUI.
+type replayCmd struct {
+	path   string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (c *replayCmd) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *replayCmd) SetStdout(w io.Writer) { c.stdout = w }
+func (c *replayCmd) SetStderr(io.Writer)   {}
+
+func (c *replayCmd) Run() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// clear the screen
+	fmt.Fprint(c.stdout, "\x1b[H\x1b[2J")
+	if err := recording.Play(f, c.stdout, 1, replayIdle); err != nil {
+		return err
+	}
+	fmt.Fprint(c.stdout, "\r\n\x1b[0m[replay ended, press enter]")
+	_, err = bufio.NewReader(c.stdin).ReadString('\n')
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
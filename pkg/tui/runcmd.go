@@ -1,19 +1,45 @@
 package tui
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
 	"github.com/charmbracelet/bubbles/v2/spinner"
 	"github.com/charmbracelet/bubbles/v2/textinput"
 	"github.com/charmbracelet/bubbles/v2/viewport"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/history"
+	"github.com/lfaoro/ssm/pkg/theme"
 )
 
+func init() {
+	RegisterAction(Action{
+		ID: "run", Title: "run command on host", Keys: []string{"ctrl+r"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return RunCmdModel(m), nil },
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "run.run", Title: "run the command", Keys: []string{"enter"}},
+		ScreenKey{ID: "run.cancel", Title: "cancel the running command", Keys: []string{"ctrl+c"}},
+		ScreenKey{ID: "run.clear", Title: "clear the output", Keys: []string{"ctrl+l"}},
+		ScreenKey{ID: "run.snippets", Title: "pick a snippet", Keys: []string{"ctrl+f"}},
+		ScreenKey{ID: "run.history-prev", Title: "previous command", Keys: []string{"up"}},
+		ScreenKey{ID: "run.history-next", Title: "next command", Keys: []string{"down"}},
+		ScreenKey{ID: "run.back", Title: "back", Keys: []string{"esc"}},
+		ScreenKey{ID: "snippets.use", Title: "use snippet", Keys: []string{"enter"}},
+		ScreenKey{ID: "snippets.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
 func RunCmdModel(base tea.Model) tea.Model {
 	previousModel, ok := base.(*Model)
 	if !ok {
@@ -52,13 +78,14 @@ func RunCmdModel(base tea.Model) tea.Model {
 			key.WithKeys("d", "ctrl+d"),
 			key.WithHelp("d", "½ page down"),
 		),
+		// up and down browse the command history
 		Up: key.NewBinding(
-			key.WithKeys("up"),
-			key.WithHelp("↑", "up"),
+			key.WithKeys("shift+up"),
+			key.WithHelp("shift+↑", "up"),
 		),
 		Down: key.NewBinding(
-			key.WithKeys("down"),
-			key.WithHelp("↓", "down"),
+			key.WithKeys("shift+down"),
+			key.WithHelp("shift+↓", "down"),
 		),
 		Left: key.NewBinding(
 			key.WithKeys("left"),
@@ -83,23 +110,63 @@ func RunCmdModel(base tea.Model) tea.Model {
 		running:       false,
 		commands:      []string{},
 		spinner:       s,
+		picker:        newSnippetPicker(previousModel, vp.Width(), vp.Height()),
+		histIdx:       len(previousModel.runHistory),
 	}
 }
 
-type cmdResultMsg struct {
-	output string
-	err    error
+// maxOutputLines bounds the output kept in the viewport,
+// older lines are dropped first.
+const maxOutputLines = 10_000
+
+// maxBatchLines bounds the lines delivered by a single message.
+const maxBatchLines = 256
+
+// maxLineBytes splits longer lines: output without newlines,
+// like progress bars or binaries, is kept in bounded chunks.
+const maxLineBytes = 4096
+
+type outputLine struct {
+	text   string
+	stderr bool
+}
+
+// cmdStream carries the output of a running command.
+type cmdStream struct {
+	lines  chan outputLine
+	done   chan cmdDoneMsg
+	cancel context.CancelFunc
+	start  time.Time
+}
+
+type cmdOutputMsg struct {
+	stream *cmdStream
+	lines  []outputLine
+}
+
+type cmdDoneMsg struct {
+	stream  *cmdStream
+	err     error
+	elapsed time.Duration
 }
 
 type cmdModel struct {
 	commands      []string
+	dropped       int
 	previousModel tea.Model
 	viewport      viewport.Model
 	input         textinput.Model
 	ready         bool
 	running       bool
+	cancelled     bool
 	spinner       spinner.Model
-	currentCmd    *exec.Cmd
+	stream        *cmdStream
+
+	picker  list.Model
+	picking bool
+	// histIdx points in the run history of the base model,
+	// past the end while typing a new command.
+	histIdx int
 }
 
 func (m *cmdModel) Init() tea.Cmd {
@@ -107,6 +174,9 @@ func (m *cmdModel) Init() tea.Cmd {
 }
 
 func (m *cmdModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyPressMsg); ok && m.picking {
+		return m.updatePicker(key)
+	}
 	var cmds []tea.Cmd
 
 	var inputCmd, viewportCmd tea.Cmd
@@ -131,84 +201,164 @@ func (m *cmdModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.handleWindowSize(msg)
 
-	case cmdResultMsg:
-		m.handleCommandResult(msg)
+	case cmdOutputMsg:
+		if msg.stream != m.stream {
+			break
+		}
+		for _, l := range msg.lines {
+			text := l.text
+			if l.stderr {
+				text = m.colors().fg(m.colors().Stderr).Render(text)
+			}
+			m.appendLine(text)
+		}
+		m.render()
+		cmds = append(cmds, waitOutput(msg.stream))
+	case cmdDoneMsg:
+		if msg.stream != m.stream {
+			break
+		}
+		m.handleCommandDone(msg)
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
 func (m *cmdModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.KeyPressMsg:
-		switch msg.Code {
-		case tea.KeyEsc:
-			return m.previousModel, nil
-		case tea.KeyEnter:
-			command := strings.TrimSpace(m.input.Value())
-			if command == "" {
-				return m, nil
-			}
-
-			m.input.SetValue("")
-			m.commands = append(m.commands, "$ "+command)
-			m.viewport.SetContent(strings.Join(m.commands, "\n"))
-			m.viewport.GotoBottom()
-
-			m.input.Blur()
-			m.running = true
+	press, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return m, nil
+	}
+	switch m.screenKey("run", press) {
+	case "back":
+		if m.stream != nil {
+			m.stream.cancel()
+		}
+		return m.previousModel, nil
+	case "run":
+		command := strings.TrimSpace(m.input.Value())
+		if command == "" {
+			return m, nil
+		}
 
-			return m, runCommand(m, command)
+		if m.running {
+			return m, nil
 		}
-		switch msg.Mod {
-		// we're only interested in ctrl+<key>
-		case tea.ModCtrl:
-			switch msg.Code {
-			// clear output
-			case 'l':
-				m.commands = nil
-				m.viewport.SetContent("")
-			case 'c':
-				if m.running && m.currentCmd != nil && m.currentCmd.Process != nil {
-					_ = m.currentCmd.Process.Kill()
-					m.commands = append(m.commands, "[command cancelled]")
-					m.viewport.SetContent(strings.Join(m.commands, "\n"))
-					m.viewport.GotoBottom()
-					m.running = false
-					m.input.Focus()
-					m.currentCmd = nil
-				} else {
-					m.commands = append(m.commands, "[no running command to cancel]")
-					m.viewport.SetContent(strings.Join(m.commands, "\n"))
-					m.viewport.GotoBottom()
-				}
-			}
+
+		m.input.SetValue("")
+		m.remember(command)
+		m.appendLine("$ " + command)
+		m.render()
+
+		m.input.Blur()
+		m.running = true
+		m.cancelled = false
+
+		return m, m.runCommand(command)
+	case "history-prev":
+		m.browseHistory(-1)
+	case "history-next":
+		m.browseHistory(1)
+	case "snippets":
+		m.openPicker()
+	case "clear":
+		m.commands = nil
+		m.dropped = 0
+		m.viewport.SetContent("")
+	case "cancel":
+		if m.running && m.stream != nil && !m.cancelled {
+			// the exit status follows once the process is gone
+			m.stream.cancel()
+			m.cancelled = true
+			m.appendLine("[command cancelled]")
+		} else if !m.running {
+			m.appendLine("[no running command to cancel]")
 		}
+		m.render()
 	}
 	return m, nil
 }
 
+// screenKey returns the action of screen bound to msg.
+func (m *cmdModel) screenKey(screen string, msg tea.KeyPressMsg) string {
+	pm, ok := m.previousModel.(*Model)
+	if !ok {
+		return ""
+	}
+	return pm.screenKey(screen, msg)
+}
+
 func (m *cmdModel) handleWindowSize(msg tea.WindowSizeMsg) {
 	m.input.SetWidth(msg.Width - 3)
 	m.viewport.SetWidth(msg.Width)
 	m.viewport.SetHeight(msg.Height)
+	m.picker.SetSize(msg.Width, msg.Height)
 }
 
-func (m *cmdModel) handleCommandResult(msg cmdResultMsg) {
-	if msg.err != nil {
-		errorMsg := msg.err.Error() + "\n" + msg.output
-		m.commands = append(m.commands, errorMsg)
-		m.viewport.SetContent(strings.Join(m.commands, "\n"))
-		m.viewport.GotoBottom()
-	} else {
-		m.commands = append(m.commands, msg.output)
-		m.viewport.SetContent(strings.Join(m.commands, "\n"))
-		m.viewport.GotoBottom()
+// remember appends command to the run history shared
+// by every run screen, skipping repeats.
+func (m *cmdModel) remember(command string) {
+	prev, ok := m.previousModel.(*Model)
+	if !ok {
+		return
+	}
+	if n := len(prev.runHistory); n == 0 || prev.runHistory[n-1] != command {
+		prev.runHistory = append(prev.runHistory, command)
 	}
+	m.histIdx = len(prev.runHistory)
+}
+
+// browseHistory moves through the run history, moving past
+// the newest command clears the input.
+func (m *cmdModel) browseHistory(delta int) {
+	prev, ok := m.previousModel.(*Model)
+	if !ok || len(prev.runHistory) == 0 {
+		return
+	}
+	m.histIdx = max(0, min(len(prev.runHistory), m.histIdx+delta))
+	if m.histIdx == len(prev.runHistory) {
+		m.input.SetValue("")
+		return
+	}
+	m.input.SetValue(prev.runHistory[m.histIdx])
+	m.input.CursorEnd()
+}
+
+func (m *cmdModel) handleCommandDone(msg cmdDoneMsg) {
+	status := fmt.Sprintf("[exit %d · %s]", history.ExitCode(msg.err), msg.elapsed.Round(time.Millisecond))
+	if msg.err != nil && history.ExitCode(msg.err) == -1 {
+		status = fmt.Sprintf("[%v · %s]", msg.err, msg.elapsed.Round(time.Millisecond))
+	}
+	m.appendLine(m.colors().dim().Render(status))
+	m.render()
+	m.stream = nil
 	m.running = false
 	m.input.Focus()
 }
 
+// appendLine adds a line to the output dropping the oldest
+// ones past maxOutputLines.
+func (m *cmdModel) appendLine(line string) {
+	m.commands = append(m.commands, line)
+	if over := len(m.commands) - maxOutputLines; over > 0 {
+		m.commands = append(m.commands[:0:0], m.commands[over:]...)
+		m.dropped += over
+	}
+}
+
+// render shows the output, following it when scrolled to the bottom.
+func (m *cmdModel) render() {
+	follow := m.viewport.AtBottom()
+	content := strings.Join(m.commands, "\n")
+	if m.dropped > 0 {
+		content = m.colors().dim().Render(fmt.Sprintf("[%d earlier lines dropped]", m.dropped)) + "\n" + content
+	}
+	m.viewport.SetContent(content)
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
 func (m cmdModel) View() string {
 	var builder strings.Builder
 	builder.WriteString(m.Bar() + "\n\n")
@@ -217,6 +367,10 @@ func (m cmdModel) View() string {
 	} else {
 		builder.WriteString(m.input.View() + "\n\n")
 	}
+	if m.picking {
+		builder.WriteString(m.picker.View())
+		return builder.String()
+	}
 	builder.WriteString(m.viewport.View())
 	return builder.String()
 }
@@ -228,67 +382,155 @@ func (m cmdModel) Bar() string {
 	}
 	selectedItem, ok := pm.li.SelectedItem().(item)
 	if !ok {
-		return renderPrimaryBar("No host selected", pm.theme.selectedTitleColor)
+		return pm.theme.primaryBar("No host selected", pm.theme.SelectedTitle)
 	}
 
-	windowName := renderPrimaryBar("Run Command", pm.theme.selectedTitleColor)
-	status := renderPrimaryBar("SSM", pm.theme.selectedTitleColor)
-	viewportScrollPercent := renderPrimaryBar(fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100), pm.theme.mainTitleColor)
+	windowName := pm.theme.primaryBar("Run Command", pm.theme.SelectedTitle)
+	status := pm.theme.primaryBar("SSM", pm.theme.SelectedTitle)
+	viewportScrollPercent := pm.theme.primaryBar(fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100), pm.theme.Title)
 
 	availableWidth := m.viewport.Width() - lipgloss.Width(windowName) - lipgloss.Width(status) - lipgloss.Width(viewportScrollPercent)
-	host := renderSecondaryBar(selectedItem.Description(), availableWidth)
+	host := pm.theme.secondaryBar(selectedItem.Description(), availableWidth)
 
 	return lipgloss.JoinHorizontal(lipgloss.Top, windowName, host, viewportScrollPercent, status)
 }
 
-func renderPrimaryBar(content string, bgColor string) string {
+// colors returns the palette of the host list.
+func (m cmdModel) colors() palette {
+	pm, ok := m.previousModel.(*Model)
+	if !ok {
+		return palette{theme.DefaultDark}
+	}
+	return pm.theme
+}
+
+func (c palette) primaryBar(content string, bgColor string) string {
 	return lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#000000")).
+		Foreground(lipgloss.Color(c.BarText)).
 		Background(lipgloss.Color(bgColor)).
 		Padding(0, 1).
 		Render(content)
 }
 
-func renderSecondaryBar(content string, width int) string {
+func (c palette) secondaryBar(content string, width int) string {
 	return lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFFDF5")).
-		Background(lipgloss.Color("#343433")).
+		Foreground(lipgloss.Color(c.SecondaryBarText)).
+		Background(lipgloss.Color(c.SecondaryBar)).
 		Padding(0, 1).
 		Width(width).
 		Render(content)
 }
 
-func runCommand(m *cmdModel, command string) tea.Cmd {
+// runCommand starts command on the selected host and streams
+// its output line by line.
+func (m *cmdModel) runCommand(command string) tea.Cmd {
+	prev, ok := m.previousModel.(*Model)
+	if !ok {
+		return m.failed(fmt.Errorf("invalid previous model"))
+	}
+
+	selected, ok := prev.li.SelectedItem().(item)
+	if !ok {
+		return m.failed(fmt.Errorf("no selected host"))
+	}
+
+	// ssh command args to force use of keys
+	args := []string{
+		"-T",
+		"-F", prev.config.GetPath(),
+		// "-o", "PreferredAuthentications=publickey",
+		// "-o", "PasswordAuthentication=no",
+		selected.host.Name,
+		command,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	// don't wait forever on processes holding the output open
+	cmd.WaitDelay = time.Second
+
+	stream := &cmdStream{
+		lines:  make(chan outputLine, maxBatchLines),
+		done:   make(chan cmdDoneMsg, 1),
+		cancel: cancel,
+		start:  time.Now(),
+	}
+	outR, outW := io.Pipe()
+	errR, errW := io.Pipe()
+	cmd.Stdout = outW
+	cmd.Stderr = errW
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return m.failed(err)
+	}
+	m.stream = stream
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go stream.read(ctx, &wg, outR, false)
+	go stream.read(ctx, &wg, errR, true)
+	go func() {
+		err := cmd.Wait()
+		outW.Close()
+		errW.Close()
+		wg.Wait()
+		close(stream.lines)
+		stream.done <- cmdDoneMsg{stream: stream, err: err, elapsed: time.Since(stream.start)}
+		cancel()
+	}()
+	return waitOutput(stream)
+}
+
+// failed reports a command that couldn't start.
+func (m *cmdModel) failed(err error) tea.Cmd {
+	stream := &cmdStream{cancel: func() {}}
+	m.stream = stream
 	return func() tea.Msg {
-		prev, ok := m.previousModel.(*Model)
-		if !ok {
-			return cmdResultMsg{output: "", err: fmt.Errorf("invalid previous model")}
+		return cmdDoneMsg{stream: stream, err: err}
+	}
+}
+
+// read forwards the lines of r, split past maxLineBytes,
+// they are discarded once cancelled.
+func (s *cmdStream) read(ctx context.Context, wg *sync.WaitGroup, r io.Reader, stderr bool) {
+	defer wg.Done()
+	br := bufio.NewReaderSize(r, maxLineBytes)
+	for {
+		// a full buffer is returned as a line
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			l := outputLine{text: strings.TrimRight(string(line), "\r\n"), stderr: stderr}
+			select {
+			case s.lines <- l:
+			case <-ctx.Done():
+			}
 		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return
+		}
+	}
+}
 
-		selected, ok := prev.li.SelectedItem().(item)
+// waitOutput delivers the next lines of stream, batched so fast
+// output doesn't re-render on every line, then its exit status.
+func waitOutput(stream *cmdStream) tea.Cmd {
+	return func() tea.Msg {
+		l, ok := <-stream.lines
 		if !ok {
-			return cmdResultMsg{output: "", err: fmt.Errorf("no selected host")}
+			return <-stream.done
 		}
-
-		// ssh command args to force use of keys
-		args := []string{
-			"-T",
-			"-F", prev.config.GetPath(),
-			// "-o", "PreferredAuthentications=publickey",
-			// "-o", "PasswordAuthentication=no",
-			selected.title,
-			command,
+		lines := []outputLine{l}
+		for len(lines) < maxBatchLines {
+			select {
+			case l, ok := <-stream.lines:
+				if !ok {
+					return cmdOutputMsg{stream: stream, lines: lines}
+				}
+				lines = append(lines, l)
+			default:
+				return cmdOutputMsg{stream: stream, lines: lines}
+			}
 		}
-
-		cmd := exec.Command(prev.Cmd.String(), args...)
-
-		m.currentCmd = cmd
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		err := cmd.Run()
-		m.currentCmd = nil
-
-		return cmdResultMsg{output: out.String(), err: err}
+		return cmdOutputMsg{stream: stream, lines: lines}
 	}
 }
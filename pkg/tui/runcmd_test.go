@@ -0,0 +1,159 @@
+package tui_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"github.com/lfaoro/ssm/pkg/tui"
+)
+
+// screen drives a run screen, its ssh runs the command.
+type screen struct {
+	t *testing.T
+	m tea.Model
+	// msgs receives what the commands return.
+	msgs chan tea.Msg
+}
+
+func runScreen(t *testing.T, opts ...tui.ModelOption) *screen {
+	t.Helper()
+	fakeSSH(t, "for last; do :; done\nexec sh -c \"$last\"\n")
+	config := sshconftest.Parse(t, "Host web\n  HostName 127.0.0.1\n")
+	size := tea.WindowSizeMsg{Width: 100, Height: 40}
+	base, _ := tui.NewModel(config, false, opts...).Update(size)
+	m, _ := tui.RunCmdModel(base).Update(size)
+	return &screen{t: t, m: m, msgs: make(chan tea.Msg, 16)}
+}
+
+// send updates the screen with msg and runs the command returned.
+func (s *screen) send(msg tea.Msg) {
+	var cmd tea.Cmd
+	s.m, cmd = s.m.Update(msg)
+	s.run(cmd)
+}
+
+// run delivers what cmd returns, only the messages of tui:
+// spinner and cursor ticks would never stop.
+func (s *screen) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, cmd := range batch {
+				s.run(cmd)
+			}
+			return
+		}
+		if msg != nil && reflect.TypeOf(msg).PkgPath() == reflect.TypeOf(tui.Model{}).PkgPath() {
+			s.msgs <- msg
+		}
+	}()
+}
+
+// wait delivers the messages until the view satisfies done.
+func (s *screen) wait(done func(view string) bool) string {
+	s.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for !done(s.view()) {
+		select {
+		case msg := <-s.msgs:
+			s.send(msg)
+		case <-timeout:
+			s.t.Fatalf("timed out, view:\n%s", s.view())
+		}
+	}
+	return s.view()
+}
+
+func (s *screen) view() string {
+	return s.m.(tea.ViewModel).View()
+}
+
+// exec types command and presses enter.
+func (s *screen) exec(command string) {
+	s.send(tea.PasteMsg(command))
+	s.send(tea.KeyPressMsg{Code: tea.KeyEnter})
+}
+
+func exited(view string) bool {
+	return strings.Contains(view, "[exit ")
+}
+
+func TestRunStreams(t *testing.T) {
+	s := runScreen(t)
+	s.exec("echo out; echo err >&2; exit 2")
+	out := s.wait(exited)
+	for _, want := range []string{"$ echo out", "\nout", "err", "[exit 2 · "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in view:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunSplitsLongLines(t *testing.T) {
+	s := runScreen(t)
+	// no newline: kept as chunks of 4096 bytes, the last one is c
+	s.exec("head -c 4096 /dev/zero | tr '\\0' a; head -c 4096 /dev/zero | tr '\\0' b; printf c")
+	out := s.wait(exited)
+	lines := map[string]bool{}
+	for _, l := range strings.Split(out, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines[l[:1]] = true
+		}
+	}
+	if !lines["a"] || !lines["b"] || !lines["c"] {
+		t.Errorf("view:\n%s", out)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	s := runScreen(t)
+	start := time.Now()
+	s.exec("echo started; sleep 10")
+	s.wait(func(view string) bool {
+		return strings.Contains(view, "\nstarted")
+	})
+	s.send(tea.KeyPressMsg{Code: 'c', Mod: tea.ModCtrl})
+	out := s.wait(func(view string) bool {
+		return strings.Contains(view, " · ")
+	})
+	if !strings.Contains(out, "[command cancelled]") {
+		t.Errorf("view:\n%s", out)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled after %s", elapsed)
+	}
+}
+
+func TestRunReboundCancel(t *testing.T) {
+	keys, err := tui.DefaultKeymap().Merge(keymap.Keymap{"run.cancel": {"ctrl+x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s := runScreen(t, tui.WithKeymap(keys))
+	s.exec("echo started; sleep 10")
+	s.wait(func(view string) bool {
+		return strings.Contains(view, "\nstarted")
+	})
+	s.send(tea.KeyPressMsg{Code: 'c', Mod: tea.ModCtrl})
+	if strings.Contains(s.view(), "[command cancelled]") {
+		t.Fatal("cancelled by the default key")
+	}
+	s.send(tea.KeyPressMsg{Code: 'x', Mod: tea.ModCtrl})
+	out := s.wait(func(view string) bool {
+		return strings.Contains(view, " · ")
+	})
+	if !strings.Contains(out, "[command cancelled]") {
+		t.Errorf("view:\n%s", out)
+	}
+}
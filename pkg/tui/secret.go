@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/secret"
+)
+
+func init() {
+	RegisterScreenKeys(
+		ScreenKey{ID: "passphrase.unlock", Title: "unlock and connect", Keys: []string{"enter"}},
+		ScreenKey{ID: "passphrase.cancel", Title: "cancel", Keys: []string{"esc", "ctrl+c"}},
+	)
+}
+
+// WithSecrets resolves host passwords with the env and cmd
+// providers, and from file when not nil.
+func WithSecrets(file *secret.File) ModelOption {
+	return func(m *Model) {
+		m.secretFile = file
+		m.secrets = secret.NewResolver(file)
+	}
+}
+
+// secretPromptMsg asks the secrets file passphrase
+// before connecting to host.
+type secretPromptMsg struct {
+	host item
+}
+
+// secretEnv returns the connector environment of host,
+// nil for hosts without a `#secret:`.
+func (m *Model) secretEnv(host item) ([]string, tea.Cmd) {
+	spec := secret.Spec(host.host)
+	if spec == "" {
+		return nil, nil
+	}
+	password, err := m.secrets.Resolve(spec)
+	if errors.Is(err, secret.ErrNoPassphrase) && m.secretFile != nil {
+		return nil, func() tea.Msg { return secretPromptMsg{host: host} }
+	}
+	if err != nil {
+		return nil, AddError(fmt.Errorf("%s: %w", host.host.Name, err))
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, AddError(fmt.Errorf("%s: askpass: %w", host.host.Name, err))
+	}
+	return secret.Environ(password, self), nil
+}
+
+// errSecretOutside is returned for password hosts opened outside
+// of ssm: their environment can't carry the password.
+func errSecretOutside(host string) error {
+	return fmt.Errorf("%s has a #secret: it opens in place only, the password would show in tmux or terminal argv", host)
+}
+
+// SecretPromptModel asks the passphrase of the secrets file,
+// then connects to host.
+func SecretPromptModel(base tea.Model, host item) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+	input := textinput.New()
+	input.Prompt = "passphrase: "
+	input.EchoMode = textinput.EchoPassword
+	input.VirtualCursor = true
+	input.Focus()
+	return &secretPromptModel{
+		previousModel: previousModel,
+		host:          host,
+		input:         input,
+	}
+}
+
+type secretPromptModel struct {
+	previousModel *Model
+	host          item
+	input         textinput.Model
+	err           error
+}
+
+func (m *secretPromptModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *secretPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		switch m.previousModel.screenKey("passphrase", msg) {
+		case "cancel":
+			return m.previousModel, nil
+		case "unlock":
+			file := m.previousModel.secretFile
+			file.Passphrase = m.input.Value()
+			if _, err := file.Load(); err != nil {
+				// asked again on the next connection
+				file.Passphrase = ""
+				m.err = err
+				m.input.SetValue("")
+				return m, nil
+			}
+			return m.previousModel, m.previousModel.connectTo(m.host)
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *secretPromptModel) View() string {
+	var b strings.Builder
+	b.WriteString(m.previousModel.theme.warn().Render(
+		fmt.Sprintf("%s reads its password from %s", m.host.host.Name, tildePath(m.previousModel.secretFile.Path))) + "\n\n")
+	b.WriteString(m.input.View() + "\n\n")
+	if m.err != nil {
+		b.WriteString(m.previousModel.log.ErrStyle.Render(m.err.Error()) + "\n")
+	}
+	b.WriteString(m.previousModel.theme.dim().Render(
+		fmt.Sprintf("%s · $%s skips this prompt",
+			m.previousModel.screenHint("passphrase", "unlock", "cancel"), secret.PassphraseEnv)))
+	return b.String()
+}
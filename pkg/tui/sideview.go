@@ -0,0 +1,183 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/connector"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "view-defaults", Title: "show ssh defaults in the side view", Keys: []string{"shift+d"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.showConfig = true
+			m.viewDefaults = !m.viewDefaults
+			m.defaultsErr = nil
+			m.setConfig()
+			return m, nil
+		},
+	})
+	RegisterAction(Action{
+		ID: "view-search", Title: "search the side view", Keys: []string{"shift+f"}, Host: true,
+		Run: func(m *Model) (tea.Model, tea.Cmd) {
+			m.showConfig = true
+			m.viewSearching = true
+			m.viewSearch.Focus()
+			m.setConfig()
+			return m, textinput.Blink
+		},
+	})
+}
+
+func newViewSearch() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "search: "
+	input.VirtualCursor = true
+	return input
+}
+
+// updateViewSearch handles the keys typed in the side view search,
+// enter keeps the query, esc clears it.
+func (m *Model) updateViewSearch(msg tea.KeyPressMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		m.viewSearching = false
+		m.viewSearch.Blur()
+		m.setConfig()
+		return nil
+	case "esc", "ctrl+c":
+		m.viewSearching = false
+		m.viewSearch.Blur()
+		m.viewSearch.SetValue("")
+		m.setConfig()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.viewSearch, cmd = m.viewSearch.Update(msg)
+	m.setConfig()
+	return cmd
+}
+
+// readsSystemConfig reports whether connections with c read the
+// system config: ssh skips it when given a config with -F.
+func readsSystemConfig(c connector.Connector) bool {
+	for _, arg := range c.Command {
+		if arg == "-F" || strings.Contains(arg, " -F ") {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveConfig renders the options in effect for host: explicit
+// ones as keys, inherited ones dimmed with the block setting them.
+func (m *Model) effectiveConfig(host sshconf.Host) string {
+	system := false
+	if conn, err := m.connectorFor(host); err == nil {
+		system = readsSystemConfig(conn)
+	}
+	blocks, err := m.config.Blocks(system)
+	if err != nil {
+		return m.theme.bad().Render(err.Error()) + "\n"
+	}
+	settings := sshconf.Effective(host.Name, blocks)
+	var notes []string
+	if m.viewDefaults {
+		defaults, ok := m.defaults[host.Name]
+		err, failed := m.defaultsErr[host.Name]
+		if !ok && !failed {
+			// ssh -G runs once per host, not on every update
+			defaults, err = sshconf.Defaults(m.config.GetPath(), host.Name, settings)
+			if err != nil {
+				if m.defaultsErr == nil {
+					m.defaultsErr = map[string]error{}
+				}
+				m.defaultsErr[host.Name] = err
+			} else {
+				if m.defaults == nil {
+					m.defaults = map[string][]sshconf.Setting{}
+				}
+				m.defaults[host.Name] = defaults
+			}
+		}
+		if err != nil {
+			notes = append(notes, m.theme.bad().Render(err.Error()))
+		}
+		settings = append(settings, defaults...)
+	}
+	if _, err := os.Stat(sshconf.SystemConfig); err == nil && !system {
+		notes = append(notes, m.theme.dim().Render(sshconf.SystemConfig+" not read: the connector passes -F"))
+	}
+
+	query := strings.ToLower(strings.TrimSpace(m.viewSearch.Value()))
+	var b strings.Builder
+	shown := 0
+	for _, s := range settings {
+		from := ""
+		if s.Source == sshconf.Inherited {
+			from = "← " + s.From()
+		}
+		if query != "" && !strings.Contains(strings.ToLower(s.Key+" "+s.Value+" "+from), query) {
+			continue
+		}
+		shown++
+		keyStyle, valueStyle := m.theme.key(), lg.NewStyle()
+		switch s.Source {
+		case sshconf.Inherited:
+			keyStyle = m.theme.dim().Italic(true)
+			valueStyle = keyStyle
+		case sshconf.Default:
+			keyStyle = m.theme.dim().Faint(true)
+			valueStyle = keyStyle
+		}
+		b.WriteString(highlight(s.Key, query, keyStyle) + " " + highlight(s.Value, query, valueStyle))
+		if from != "" {
+			b.WriteString("  " + highlight(from, query, m.theme.dim()))
+		}
+		b.WriteString("\n")
+	}
+
+	var head string
+	switch {
+	case m.viewSearching:
+		head = m.viewSearch.View()
+	case query != "":
+		head = m.theme.dim().Render("search: " + m.viewSearch.Value())
+	}
+	if head != "" {
+		head += m.theme.dim().Render(fmt.Sprintf("  %d of %d", shown, len(settings))) + "\n\n"
+	}
+	out := head + b.String()
+	if len(notes) > 0 {
+		out += "\n" + strings.Join(notes, "\n") + "\n"
+	}
+	return out
+}
+
+// highlight renders s with style, the matches of query reversed.
+func highlight(s, query string, style lg.Style) string {
+	if query == "" {
+		return style.Render(s)
+	}
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		return style.Render(s)
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			break
+		}
+		b.WriteString(style.Render(s[:i]))
+		b.WriteString(style.Reverse(true).Render(s[i : i+len(query)]))
+		s, lower = s[i+len(query):], lower[i+len(query):]
+	}
+	return b.String() + style.Render(s)
+}
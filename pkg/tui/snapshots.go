@@ -0,0 +1,259 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/snapshot"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "snapshots", Title: "config snapshots", Keys: []string{"shift+s"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return SnapshotsModel(m), nil },
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "snapshots.restore", Title: "restore", Keys: []string{"r"}},
+		ScreenKey{ID: "snapshots.undo", Title: "undo", Keys: []string{"u"}},
+		ScreenKey{ID: "snapshots.diff", Title: "diff", Keys: []string{"d"}},
+		ScreenKey{ID: "snapshots.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+type snapshotItem struct {
+	snap snapshot.Snapshot
+	// diff is what restoring the snapshot changes.
+	diff configDiff
+	same bool
+}
+
+func (i snapshotItem) Title() string {
+	return i.snap.Time.Local().Format("2006-01-02 15:04:05")
+}
+
+func (i snapshotItem) Description() string {
+	if i.same {
+		return "same as the config"
+	}
+	return fmt.Sprintf("%s  %s", sshconf.Summary(i.diff.changes), formatSize(i.snap.Size))
+}
+
+func (i snapshotItem) FilterValue() string {
+	return i.Title()
+}
+
+// SnapshotsModel browses the snapshots of the config taken
+// before edits and restores, restoring one is undoable.
+func SnapshotsModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	li := list.New([]list.Item{}, d, previousModel.li.Width()/2, previousModel.li.Height())
+	li.Title = fmt.Sprintf("Snapshots (%s)", tildePath(previousModel.config.GetPath()))
+	li.Styles.Title = previousModel.li.Styles.Title
+	li.SetStatusBarItemName("snapshot", "snapshots")
+	li.DisableQuitKeybindings()
+	li.AdditionalFullHelpKeys = func() []key.Binding {
+		return previousModel.screenHelp("snapshots", "restore", "undo", "diff", "back")
+	}
+
+	vp := viewport.New()
+	vp.SetWidth(previousModel.li.Width() / 2)
+	vp.SetHeight(previousModel.li.Height())
+	vp.Style = lg.NewStyle().Padding(1, 2)
+
+	m := &snapshotsModel{
+		previousModel: previousModel,
+		li:            li,
+		vp:            vp,
+	}
+	m.load()
+	return m
+}
+
+type snapshotsModel struct {
+	previousModel *Model
+	li            list.Model
+	vp            viewport.Model
+	err           error
+	status        string
+	showUnified   bool
+	// restoring is the snapshot waiting for its restore confirmation.
+	restoring string
+}
+
+func (m *snapshotsModel) load() {
+	m.err = nil
+	if m.previousModel.snapshots == nil {
+		m.err = fmt.Errorf("snapshots directory not set")
+		m.setDetails()
+		return
+	}
+	path := m.previousModel.config.GetPath()
+	current, err := os.ReadFile(path)
+	if err != nil {
+		m.err = err
+		m.setDetails()
+		return
+	}
+	snaps, err := m.previousModel.snapshots.List(path)
+	if err != nil {
+		m.err = err
+	}
+	items := make([]list.Item, 0, len(snaps))
+	for _, snap := range snaps {
+		data, err := snap.Read()
+		if err != nil {
+			continue
+		}
+		item := snapshotItem{snap: snap, same: bytes.Equal(data, current)}
+		item.diff, err = diffConfig(path, current, data)
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	m.li.SetItems(items)
+	m.setDetails()
+}
+
+// restore writes snap back to the config and reloads it.
+func (m *snapshotsModel) restore(snap snapshot.Snapshot) tea.Cmd {
+	if _, err := m.previousModel.snapshots.Restore(snap); err != nil {
+		m.err = err
+		m.setDetails()
+		return nil
+	}
+	_, cmd := m.previousModel.Update(ReloadConfigMsg{})
+	m.status = fmt.Sprintf("restored %s, press %s to undo",
+		snap.Time.Local().Format("15:04:05"), m.previousModel.boundKey("snapshots.undo"))
+	m.load()
+	return cmd
+}
+
+// undo restores the newest snapshot differing from the config:
+// the config before the last edit or restore. Undoing twice redoes.
+func (m *snapshotsModel) undo() tea.Cmd {
+	for _, it := range m.li.Items() {
+		if it := it.(snapshotItem); !it.same {
+			return m.restore(it.snap)
+		}
+	}
+	m.status = "nothing to undo"
+	m.setDetails()
+	return nil
+}
+
+func (m *snapshotsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *snapshotsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width/2, msg.Height-1)
+		m.vp.SetWidth(msg.Width - msg.Width/2)
+		m.vp.SetHeight(msg.Height - 1)
+		// keep the host list in sync for when we go back
+		m.previousModel.Update(msg)
+	case tea.KeyPressMsg:
+		if m.li.FilterState() == list.Filtering {
+			break
+		}
+		selected, ok := m.li.SelectedItem().(snapshotItem)
+		action := m.previousModel.screenKey("snapshots", msg)
+		if action != "restore" {
+			m.restoring = ""
+		}
+		switch action {
+		case "back":
+			if m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
+			}
+			return m.previousModel, nil
+		case "diff":
+			m.showUnified = !m.showUnified
+			m.setDetails()
+			return m, nil
+		case "undo":
+			return m, m.undo()
+		case "restore":
+			if !ok || selected.same {
+				return m, nil
+			}
+			if m.restoring != selected.snap.Path {
+				m.restoring = selected.snap.Path
+				m.setDetails()
+				return m, nil
+			}
+			m.restoring = ""
+			return m, m.restore(selected.snap)
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	m.setDetails()
+	return m, cmd
+}
+
+// setDetails shows what restoring the selected snapshot changes.
+func (m *snapshotsModel) setDetails() {
+	if m.err != nil {
+		m.vp.SetContent(m.previousModel.log.ErrStyle.Render(m.err.Error()))
+		return
+	}
+	theme := m.previousModel.theme
+	var b strings.Builder
+	if m.status != "" {
+		b.WriteString(theme.good().Render(m.status) + "\n\n")
+	}
+	selected, ok := m.li.SelectedItem().(snapshotItem)
+	if !ok {
+		b.WriteString("(no snapshots)\n\n" + theme.dim().Render(
+			"the config is snapshotted before each edit with "+m.previousModel.boundKey("edit-config")))
+		m.vp.SetContent(b.String())
+		return
+	}
+	b.WriteString(theme.key().Render(fmt.Sprintf("%-9s", "file")) + " " + tildePath(selected.snap.Path) + "\n\n")
+	if selected.same {
+		b.WriteString(theme.dim().Render("same as the config") + "\n")
+		m.vp.SetContent(b.String())
+		return
+	}
+	b.WriteString(theme.dim().Render("restoring applies:") + "\n")
+	b.WriteString(renderChanges(selected.diff.changes, theme))
+	if len(selected.diff.changes) == 0 {
+		b.WriteString(theme.dim().Render("only comments and layout") + "\n")
+	}
+	if m.showUnified {
+		b.WriteString("\n" + renderUnified(selected.diff.unified, theme))
+	}
+	if m.restoring == selected.snap.Path {
+		b.WriteString("\n" + theme.warn().Render(fmt.Sprintf(
+			"press %s again to restore this snapshot", m.previousModel.boundKey("snapshots.restore"))))
+	}
+	m.vp.SetContent(b.String())
+}
+
+func (m *snapshotsModel) View() string {
+	return lg.JoinHorizontal(lg.Top, m.li.View(), m.vp.View())
+}
@@ -0,0 +1,27 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/lfaoro/ssm/pkg/keymap"
+	"github.com/lfaoro/ssm/pkg/sshconf/sshconftest"
+	"github.com/lfaoro/ssm/pkg/tui"
+)
+
+func TestSnapshotsReboundBack(t *testing.T) {
+	keys, err := tui.DefaultKeymap().Merge(keymap.Keymap{"snapshots.back": {"x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := sshconftest.Parse(t, "Host web\n")
+	m := tui.SnapshotsModel(tui.NewModel(config, false, tui.WithKeymap(keys)))
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'q', Text: "q"})
+	if _, ok := m.(*tui.Model); ok {
+		t.Fatal("went back with the default key")
+	}
+	m, _ = m.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if _, ok := m.(*tui.Model); !ok {
+		t.Errorf("still on %T", m)
+	}
+}
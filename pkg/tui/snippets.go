@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/snippet"
+)
+
+type snippetItem struct {
+	snippet snippet.Snippet
+	command string
+	err     error
+}
+
+func (i snippetItem) Title() string {
+	if i.snippet.Description != "" {
+		return i.snippet.Name + " · " + i.snippet.Description
+	}
+	return i.snippet.Name
+}
+
+func (i snippetItem) Description() string {
+	if i.err != nil {
+		return i.err.Error()
+	}
+	return i.command
+}
+
+func (i snippetItem) FilterValue() string {
+	return i.snippet.Name + " " + i.snippet.Description
+}
+
+func newSnippetPicker(base *Model, width, height int) list.Model {
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(base.theme.SelectedBorder)).
+		Foreground(lg.Color(base.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(base.theme.SelectedDescription))
+
+	li := list.New([]list.Item{}, d, width, height)
+	li.Title = "Snippets"
+	li.Styles.Title = base.li.Styles.Title
+	li.SetStatusBarItemName("snippet", "snippets")
+	li.DisableQuitKeybindings()
+	li.AdditionalFullHelpKeys = func() []key.Binding {
+		return base.screenHelp("snippets", "use", "back")
+	}
+	return li
+}
+
+// openPicker lists the snippets applying to the selected host
+// rendered for it.
+func (m *cmdModel) openPicker() {
+	prev, ok := m.previousModel.(*Model)
+	if !ok {
+		return
+	}
+	selected, ok := prev.li.SelectedItem().(item)
+	if !ok {
+		return
+	}
+	var items []list.Item
+	for _, s := range snippet.For(prev.snippets, selected.host) {
+		command, err := s.Render(selected.host)
+		items = append(items, snippetItem{snippet: s, command: command, err: err})
+	}
+	m.picker.SetItems(items)
+	m.picker.ResetFilter()
+	if len(items) == 0 {
+		path, _ := snippet.Path()
+		m.appendLine(m.colors().dim().Render("[no snippets for " + selected.host.Name + " in " + path + "]"))
+		m.render()
+		return
+	}
+	m.picking = true
+}
+
+func (m *cmdModel) updatePicker(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	if m.picker.FilterState() != list.Filtering {
+		switch m.screenKey("snippets", msg) {
+		case "back":
+			if m.picker.IsFiltered() {
+				m.picker.ResetFilter()
+				return m, nil
+			}
+			m.picking = false
+			return m, nil
+		case "use":
+			selected, ok := m.picker.SelectedItem().(snippetItem)
+			if !ok || selected.err != nil {
+				return m, nil
+			}
+			m.picking = false
+			m.input.SetValue(selected.command)
+			m.input.CursorEnd()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.picker, cmd = m.picker.Update(msg)
+	return m, cmd
+}
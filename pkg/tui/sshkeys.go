@@ -0,0 +1,294 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/list"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/lfaoro/ssm/pkg/sshkeys"
+)
+
+func init() {
+	RegisterAction(Action{
+		ID: "ssh-keys", Title: "ssh keys", Keys: []string{"shift+i"},
+		Run: func(m *Model) (tea.Model, tea.Cmd) { return KeysModel(m), nil },
+	})
+	RegisterScreenKeys(
+		ScreenKey{ID: "ssh-keys.generate", Title: "generate key", Keys: []string{"g"}},
+		ScreenKey{ID: "ssh-keys.deploy", Title: "deploy to selected host", Keys: []string{"c"}},
+		ScreenKey{ID: "ssh-keys.deploy-all", Title: "deploy to listed hosts", Keys: []string{"a"}},
+		ScreenKey{ID: "ssh-keys.use", Title: "use as IdentityFile", Keys: []string{"u"}},
+		ScreenKey{ID: "ssh-keys.back", Title: "back", Keys: []string{"esc", "q"}},
+	)
+}
+
+type keyItem struct {
+	key sshkeys.Key
+}
+
+func (i keyItem) Title() string {
+	return i.key.Name()
+}
+
+func (i keyItem) Description() string {
+	k := i.key
+	parts := []string{
+		fmt.Sprintf("%s %d", strings.TrimPrefix(k.Type, "ssh-"), k.Bits),
+		k.Fingerprint,
+		sshkeys.FormatAge(k.Age()),
+	}
+	switch {
+	case k.Path == "":
+		parts = append(parts, "public only")
+	case k.Encrypted:
+		parts = append(parts, "passphrase")
+	default:
+		parts = append(parts, "no passphrase")
+	}
+	if len(k.Hosts) == 0 {
+		parts = append(parts, "unused")
+	} else {
+		parts = append(parts, "used by "+strings.Join(k.Hosts, ","))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (i keyItem) FilterValue() string {
+	return i.key.Name() + " " + i.key.Comment + " " + strings.Join(i.key.Hosts, " ")
+}
+
+type (
+	keysReloadMsg struct {
+		status string
+	}
+	keyDeployedMsg struct {
+		host sshconf.Host
+		key  sshkeys.Key
+		err  error
+	}
+)
+
+type keysModel struct {
+	previousModel *Model
+	dir           string
+	li            list.Model
+	input         textinput.Model
+	naming        bool
+	status        []string
+	changed       bool
+}
+
+// KeysModel lists the keys in ~/.ssh, generates new ones
+// and deploys them to hosts.
+func KeysModel(base tea.Model) tea.Model {
+	previousModel, ok := base.(*Model)
+	if !ok {
+		panic("failed to cast tea.Model to Model")
+	}
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
+		BorderForeground(lg.Color(previousModel.theme.SelectedBorder)).
+		Foreground(lg.Color(previousModel.theme.SelectedTitle))
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.
+		Foreground(lg.Color(previousModel.theme.SelectedDescription))
+
+	m := &keysModel{
+		previousModel: previousModel,
+		dir:           sshkeys.DefaultDir(),
+	}
+	m.li = list.New([]list.Item{}, d, previousModel.li.Width(), previousModel.li.Height()-3)
+	m.li.Title = fmt.Sprintf("SSH keys (%s)", m.dir)
+	m.li.Styles.Title = previousModel.li.Styles.Title
+	m.li.SetStatusBarItemName("key", "keys")
+	m.li.DisableQuitKeybindings()
+	m.li.AdditionalFullHelpKeys = func() []key.Binding {
+		return previousModel.screenHelp("ssh-keys", "generate", "deploy", "deploy-all", "use", "back")
+	}
+	m.input = textinput.New()
+	m.input.Prompt = "new key file: "
+	m.input.Placeholder = "id_ed25519"
+	m.input.VirtualCursor = true
+	m.reload()
+	return m
+}
+
+func (m *keysModel) reload() {
+	keys, err := sshkeys.List(m.dir, m.previousModel.config)
+	if err != nil {
+		m.status = append(m.status, err.Error())
+	}
+	items := make([]list.Item, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, keyItem{key: k})
+	}
+	m.li.SetItems(items)
+}
+
+func (m *keysModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *keysModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.li.SetSize(msg.Width, msg.Height-3)
+		m.previousModel.Update(msg)
+	case keysReloadMsg:
+		if msg.status != "" {
+			m.status = append(m.status, msg.status)
+		}
+		m.reload()
+		return m, nil
+	case keyDeployedMsg:
+		if msg.err != nil {
+			m.status = append(m.status, fmt.Sprintf("%s: deploy failed: %v", msg.host.Name, msg.err))
+			return m, nil
+		}
+		err := sshconf.SetOption(msg.host.File, msg.host.Name, "IdentityFile", tildePath(identityPath(msg.key)))
+		if err != nil {
+			m.status = append(m.status, fmt.Sprintf("%s: deployed, IdentityFile not updated: %v", msg.host.Name, err))
+			return m, nil
+		}
+		m.changed = true
+		m.status = append(m.status, fmt.Sprintf("%s: using %s", msg.host.Name, msg.key.Name()))
+		m.reloadConfig()
+		return m, nil
+	case tea.KeyPressMsg:
+		if m.naming {
+			return m.updateNaming(msg)
+		}
+		if m.li.FilterState() == list.Filtering {
+			break
+		}
+		selected, _ := m.li.SelectedItem().(keyItem)
+		switch m.previousModel.screenKey("ssh-keys", msg) {
+		case "back":
+			if m.li.IsFiltered() {
+				m.li.ResetFilter()
+				return m, nil
+			}
+			if m.changed {
+				return m.previousModel, func() tea.Msg { return ReloadConfigMsg{} }
+			}
+			return m.previousModel, nil
+		case "generate":
+			m.naming = true
+			m.input.SetValue("")
+			return m, m.input.Focus()
+		case "deploy":
+			host, ok := m.previousModel.li.SelectedItem().(item)
+			if !ok || selected.key.Pub == "" {
+				return m, nil
+			}
+			return m, m.deploy(selected.key, []sshconf.Host{host.host})
+		case "deploy-all":
+			if selected.key.Pub == "" {
+				return m, nil
+			}
+			var hosts []sshconf.Host
+			for _, it := range m.previousModel.li.VisibleItems() {
+				hosts = append(hosts, it.(item).host)
+			}
+			return m, m.deploy(selected.key, hosts)
+		case "use":
+			host, ok := m.previousModel.li.SelectedItem().(item)
+			if !ok || selected.key.Pub == "" {
+				return m, nil
+			}
+			return m.Update(keyDeployedMsg{host: host.host, key: selected.key})
+		}
+	}
+	var cmd tea.Cmd
+	m.li, cmd = m.li.Update(msg)
+	return m, cmd
+}
+
+func (m *keysModel) updateNaming(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.Code {
+	case tea.KeyEsc:
+		m.naming = false
+		m.input.Blur()
+		return m, nil
+	case tea.KeyEnter:
+		m.naming = false
+		m.input.Blur()
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" {
+			name = m.input.Placeholder
+		}
+		path := filepath.Join(m.dir, filepath.Base(name))
+		if _, err := os.Stat(path); err == nil {
+			m.status = append(m.status, fmt.Sprintf("%s already exists", path))
+			return m, nil
+		}
+		hostname, _ := os.Hostname()
+		comment := fmt.Sprintf("%s@%s", os.Getenv("USER"), hostname)
+		cmd := sshkeys.GenerateCmd(path, "ed25519", comment)
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			if err != nil {
+				return keysReloadMsg{status: fmt.Sprintf("ssh-keygen: %v", err)}
+			}
+			return keysReloadMsg{status: fmt.Sprintf("generated %s", path)}
+		})
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// deploy installs k on every host one after the other,
+// each may prompt for a password.
+func (m *keysModel) deploy(k sshkeys.Key, hosts []sshconf.Host) tea.Cmd {
+	cmds := make([]tea.Cmd, 0, len(hosts))
+	for _, h := range hosts {
+		cmd := sshkeys.DeployCmd(m.previousModel.config.GetPath(), h.Name, k.Pub)
+		cmds = append(cmds, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return keyDeployedMsg{host: h, key: k, err: err}
+		}))
+	}
+	return tea.Sequence(cmds...)
+}
+
+// reloadConfig re-reads the ssh config so key users are current.
+func (m *keysModel) reloadConfig() {
+	if err := m.previousModel.config.ParsePath(m.previousModel.config.GetPath()); err != nil {
+		m.status = append(m.status, err.Error())
+	}
+	m.reload()
+}
+
+func identityPath(k sshkeys.Key) string {
+	if k.Path != "" {
+		return k.Path
+	}
+	return k.PubPath
+}
+
+// tildePath shortens paths inside the home directory.
+func tildePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || !strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return path
+	}
+	return "~" + strings.TrimPrefix(path, home)
+}
+
+func (m *keysModel) View() string {
+	var b strings.Builder
+	b.WriteString(m.li.View() + "\n")
+	if m.naming {
+		b.WriteString(m.input.View() + "\n")
+	}
+	if n := len(m.status); n > 0 {
+		b.WriteString(m.previousModel.theme.dim().Render(m.status[n-1]))
+	}
+	return b.String()
+}
@@ -1,12 +1,12 @@
 package tui
 
+import "github.com/lfaoro/ssm/pkg/connector"
+
+// SysCmd is the name of a connector.
 type SysCmd string
 
 func (s SysCmd) String() string {
 	return string(s)
 }
 
-const (
-	sshCmd  SysCmd = "ssh"
-	moshCmd SysCmd = "mosh"
-)
+const sshCmd SysCmd = connector.Default
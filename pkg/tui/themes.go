@@ -1,34 +1,71 @@
 package tui
 
-// theme provides the colors for the properties.
-// you can use ANSI, ANSI256 or Hex colors.
-// https://html-color.code
-type theme struct {
-	mainTitleColor           string
-	selectedBorderColor      string
-	selectedTitleColor       string
-	selectedDescriptionColor string
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	lg "github.com/charmbracelet/lipgloss/v2"
+	"github.com/lfaoro/ssm/pkg/theme"
+)
+
+const defaultTheme = "matrix"
+
+// themes holds the available themes by name.
+var themes = map[string]theme.Theme{}
+
+func init() {
+	RegisterThemes(theme.Builtin()...)
 }
 
-var themes = map[string]theme{
-	"matrix": matrixTheme(),
-	"sky":    skyTheme(),
+// RegisterThemes makes ts available to SetThemeMsg and the
+// command palette, registering an existing name replaces it.
+func RegisterThemes(ts ...theme.Theme) {
+	for _, t := range ts {
+		themes[t.Name] = t
+		RegisterAction(Action{
+			ID: "theme-" + t.Name, Title: "theme: " + t.Name,
+			Run: func(m *Model) (tea.Model, tea.Cmd) {
+				return m, func() tea.Msg { return SetThemeMsg{Theme: t.Name} }
+			},
+		})
+	}
 }
 
-func matrixTheme() theme {
-	return theme{
-		mainTitleColor:           "#648c11",
-		selectedTitleColor:       "#9efd38",
-		selectedBorderColor:      "#9efd38",
-		selectedDescriptionColor: "#648c11",
+// ThemeNames returns the names of the registered themes sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
 	}
+	slices.Sort(names)
+	return names
 }
 
-func skyTheme() theme {
-	return theme{
-		mainTitleColor:           "#4682b4",
-		selectedTitleColor:       "#00bfff",
-		selectedBorderColor:      "#00bfff",
-		selectedDescriptionColor: "#4682b4",
+// palette is the variant of the theme in use,
+// with shortcuts for the common styles.
+type palette struct {
+	theme.Palette
+}
+
+func (p palette) fg(color string) lg.Style {
+	return lg.NewStyle().Foreground(lg.Color(color))
+}
+
+func (p palette) key() lg.Style  { return p.fg(p.Key) }
+func (p palette) dim() lg.Style  { return p.fg(p.Dim) }
+func (p palette) bad() lg.Style  { return p.fg(p.Error) }
+func (p palette) good() lg.Style { return p.fg(p.OK) }
+func (p palette) warn() lg.Style { return p.fg(p.Warn) }
+
+// applyTheme switches to the variant of the current theme
+// for the terminal background.
+func (m *Model) applyTheme() {
+	t, ok := themes[m.themeName]
+	if !ok {
+		t = themes[defaultTheme]
 	}
+	m.theme = palette{t.Variant(m.isDark)}
+	m.log.ErrStyle = m.theme.bad()
+	m.log.DebugStyle = m.theme.dim()
+	m.reloadList()
 }
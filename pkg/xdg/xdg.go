@@ -0,0 +1,46 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package xdg resolves the ssm directories following the
+// XDG Base Directory specification.
+// ref: https://specifications.freedesktop.org/basedir-spec/latest/
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "ssm"
+
+// StateDir returns $XDG_STATE_HOME/ssm, defaults to ~/.local/state/ssm.
+func StateDir() (string, error) {
+	return dir("XDG_STATE_HOME", ".local", "state")
+}
+
+// DataDir returns $XDG_DATA_HOME/ssm, defaults to ~/.local/share/ssm.
+func DataDir() (string, error) {
+	return dir("XDG_DATA_HOME", ".local", "share")
+}
+
+// CacheDir returns $XDG_CACHE_HOME/ssm, defaults to ~/.cache/ssm.
+func CacheDir() (string, error) {
+	return dir("XDG_CACHE_HOME", ".cache")
+}
+
+// ConfigDir returns $XDG_CONFIG_HOME/ssm, defaults to ~/.config/ssm.
+func ConfigDir() (string, error) {
+	return dir("XDG_CONFIG_HOME", ".config")
+}
+
+func dir(env string, fallback ...string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(append([]string{home}, fallback...)...)
+	}
+	return filepath.Join(base, appName), nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/recording"
+	"github.com/urfave/cli/v3"
+)
+
+var replayCmd = &cli.Command{
+	Name:  "replay",
+	Usage: "replay a recorded session",
+	UsageText: "ssm replay [file|host]\n" +
+		"example: ssm replay\n" +
+		"example: ssm replay db1 --speed 2\n" +
+		"example: ssm replay ~/.local/share/ssm/recordings/db1-20250801-101500.cast",
+	Description: "replay plays an asciicast v2 recording, for a host name its latest one. " +
+		"Without arguments it lists the recordings of $XDG_DATA_HOME/ssm/recordings. " +
+		"Sessions are recorded for hosts with a `#record: yes` comment or listed in " +
+		"the [record] table of config.toml.",
+	Action: replayAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "recording",
+			UsageText: "recording file or host name",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.FloatFlag{
+			Name:  "speed",
+			Usage: "playback speed multiplier",
+			Value: 1,
+		},
+		&cli.DurationFlag{
+			Name:  "idle-limit",
+			Usage: "shorten pauses to this duration, 0 keeps them",
+			Value: 2 * time.Second,
+		},
+	},
+}
+
+var replayAction = func(_ context.Context, cmd *cli.Command) error {
+	dir, err := recording.Dir()
+	if err != nil {
+		return err
+	}
+	arg := cmd.StringArg("recording")
+	if arg == "" {
+		list, err := recording.List(dir)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("no recordings in %s", dir)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tSTART\tDURATION\tFILE")
+		for _, info := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Host,
+				info.Start.Format("2006-01-02 15:04"), info.Duration.Round(time.Second), info.Path)
+		}
+		return w.Flush()
+	}
+
+	path := arg
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		info, err := recording.Latest(dir, arg)
+		if err != nil {
+			return err
+		}
+		path = info.Path
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return recording.Play(f, os.Stdout, cmd.Float("speed"), cmd.Duration("idle-limit"))
+}
+
+// recordCmd wraps connections running outside of the TUI:
+// tmux windows and panes, terminals and --exit.
+var recordCmd = &cli.Command{
+	Name:      "record",
+	Usage:     "run a command recording its terminal output",
+	UsageText: "ssm record --output file [--title host] -- command [args]",
+	Hidden:    true,
+	Action:    recordAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "asciicast file to write",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "title",
+			Usage: "host of the session",
+		},
+	},
+}
+
+var recordAction = func(_ context.Context, cmd *cli.Command) error {
+	argv := cmd.Args().Slice()
+	if len(argv) == 0 {
+		return fmt.Errorf("record: missing command")
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	s := &recording.Session{
+		Cmd:   exec.Command(path, argv[1:]...),
+		Path:  cmd.String("output"),
+		Title: cmd.String("title"),
+	}
+	err = s.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		// keep the exit status of the connection
+		os.Exit(exit.ExitCode())
+	}
+	return err
+}
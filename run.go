@@ -0,0 +1,226 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lfaoro/ssm/pkg/snippet"
+	"github.com/lfaoro/ssm/pkg/sshconf"
+	"github.com/urfave/cli/v3"
+)
+
+var runCmd = &cli.Command{
+	Name:  "run",
+	Usage: "run a snippet or a command on hosts",
+	UsageText: "ssm run [query] --snippet name | --command cmd\n" +
+		"example: ssm run --snippet restart-nginx --tag web\n" +
+		"example: ssm run 'user:root' --command 'uptime'\n" +
+		"example: ssm run --list",
+	Description: "run executes the command over `ssh -T` with BatchMode on every matching host " +
+		"in parallel, output lines are prefixed with the host name. Commands are Go templates " +
+		"over the host fields: {{.Name}} {{.HostName}} {{.User}} {{.Port}}, {{.Meta.key}} reads #ssm-key: comments. " +
+		"Snippets are read from $XDG_CONFIG_HOME/ssm/snippets.toml. " +
+		"It exits with status 1 when the command fails on any host.",
+	Action: runAction,
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "query",
+			UsageText: "filter query, same syntax as the [tag] argument",
+		},
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "snippet",
+			Usage: "name of the snippet to run",
+		},
+		&cli.StringFlag{
+			Name:  "command",
+			Usage: "command template to run",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "only run on hosts with any of these tags",
+		},
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "print the available snippets",
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "hosts run in parallel",
+			Value: 8,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "timeout of the command on every host, 0 waits forever",
+		},
+	},
+}
+
+var runAction = func(ctx context.Context, cmd *cli.Command) error {
+	snippets, err := snippet.Load()
+	if err != nil {
+		return err
+	}
+	if cmd.Bool("list") {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTAGS\tDESCRIPTION\tCOMMAND")
+		for _, s := range snippets {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				s.Name, strings.Join(s.Tags, ","), s.Description, s.Command)
+		}
+		return w.Flush()
+	}
+
+	var sn snippet.Snippet
+	switch name, command := cmd.String("snippet"), cmd.String("command"); {
+	case name != "" && command != "":
+		return fmt.Errorf("use either --snippet or --command")
+	case name != "":
+		var ok bool
+		if sn, ok = snippet.Find(snippets, name); !ok {
+			path, _ := snippet.Path()
+			return fmt.Errorf("snippet %q not found in %s", name, path)
+		}
+	case command != "":
+		sn = snippet.Snippet{Name: "command", Command: command}
+	default:
+		return fmt.Errorf("nothing to run: set --snippet or --command")
+	}
+
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	var hosts []sshconf.Host
+	for _, h := range filterHosts(config, cmd.StringArg("query")) {
+		if hasAnyTag(h, cmd.StringSlice("tag")) && sn.Applies(h) {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts match %q for snippet %s", cmd.StringArg("query"), sn.Name)
+	}
+
+	width := 0
+	for _, h := range hosts {
+		width = max(width, len(h.Name))
+	}
+	out := &prefixWriter{}
+	workers := max(1, int(cmd.Int("workers")))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	for _, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			prefix := fmt.Sprintf("%-*s | ", width, h.Name)
+			err := runOn(ctx, config.GetPath(), h, sn, cmd.Duration("timeout"), out, prefix)
+			if err != nil {
+				out.line(os.Stderr, prefix+err.Error())
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hosts failed", failed, len(hosts))
+	}
+	return nil
+}
+
+func hasAnyTag(h sshconf.Host, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range h.Tags() {
+		if slices.Contains(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// runOn renders sn for h and runs it, the same way the run screen does
+// but without prompts.
+func runOn(ctx context.Context, configPath string, h sshconf.Host, sn snippet.Snippet, timeout time.Duration, out *prefixWriter, prefix string) error {
+	command, err := sn.Render(h)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "ssh",
+		"-T",
+		"-F", configPath,
+		"-o", "BatchMode=yes",
+		h.Name,
+		command,
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go out.copy(&wg, os.Stdout, stdout, prefix)
+	go out.copy(&wg, os.Stderr, stderr, prefix)
+	wg.Wait()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s: %w", sn.Name, err)
+	}
+	return nil
+}
+
+// prefixWriter writes whole lines so parallel hosts don't interleave.
+type prefixWriter struct {
+	mu sync.Mutex
+}
+
+func (p *prefixWriter) line(w io.Writer, s string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintln(w, s)
+}
+
+func (p *prefixWriter) copy(wg *sync.WaitGroup, w io.Writer, r io.Reader, prefix string) {
+	defer wg.Done()
+	br := bufio.NewReader(r)
+	for {
+		s, err := br.ReadString('\n')
+		if s != "" {
+			p.line(w, prefix+strings.TrimRight(s, "\r\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
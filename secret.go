@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Leonardo Faoro & authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lfaoro/ssm/pkg/secret"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+var secretCmd = &cli.Command{
+	Name:  "secret",
+	Usage: "manage the encrypted secrets file of password hosts",
+	UsageText: "ssm secret list|set|rm [name]\n" +
+		"example: ssm secret set prod/db\n" +
+		"example: pass show prod/db | ssm secret set prod/db",
+	Description: "hosts read their password with a `#secret: provider:ref` comment, " +
+		"providers are env:VAR, cmd:command and file:name from " +
+		"$XDG_DATA_HOME/ssm/secrets.enc. The file passphrase is read from " +
+		"$" + secret.PassphraseEnv + " or asked on the terminal.",
+	Commands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "print the names in the secrets file",
+			Action: secretListAction,
+		},
+		{
+			Name:      "set",
+			Usage:     "store a secret, read from the terminal or stdin",
+			ArgsUsage: "name",
+			Action:    secretSetAction,
+			Arguments: []cli.Argument{&cli.StringArg{Name: "name"}},
+		},
+		{
+			Name:      "rm",
+			Usage:     "remove a secret",
+			ArgsUsage: "name",
+			Action:    secretRmAction,
+			Arguments: []cli.Argument{&cli.StringArg{Name: "name"}},
+		},
+	},
+}
+
+// secretFile returns the secrets file, asking its passphrase
+// on the terminal when the environment doesn't hold it.
+func secretFile() (*secret.File, error) {
+	path, err := secret.FilePath()
+	if err != nil {
+		return nil, err
+	}
+	f := &secret.File{Path: path}
+	if os.Getenv(secret.PassphraseEnv) != "" {
+		return f, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, secret.ErrNoPassphrase
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "passphrase of %s: ", path)
+	b, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, err
+	}
+	f.Passphrase = string(b)
+	return f, nil
+}
+
+var secretListAction = func(_ context.Context, _ *cli.Command) error {
+	f, err := secretFile()
+	if err != nil {
+		return err
+	}
+	names, err := f.Names()
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		fmt.Println(n)
+	}
+	return nil
+}
+
+var secretSetAction = func(_ context.Context, cmd *cli.Command) error {
+	name := cmd.StringArg("name")
+	if name == "" {
+		return fmt.Errorf("secret set: missing name")
+	}
+	f, err := secretFile()
+	if err != nil {
+		return err
+	}
+	var value string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "secret %s: ", name)
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+		value = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("secret set: reading stdin: %w", err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+	if value == "" {
+		return fmt.Errorf("secret set: empty secret")
+	}
+	if err := f.Set(name, value); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "stored %s, use it with `#secret: file:%s`\n", name, name)
+	return nil
+}
+
+var secretRmAction = func(_ context.Context, cmd *cli.Command) error {
+	name := cmd.StringArg("name")
+	if name == "" {
+		return fmt.Errorf("secret rm: missing name")
+	}
+	f, err := secretFile()
+	if err != nil {
+		return err
+	}
+	return f.Delete(name)
+}